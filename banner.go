@@ -0,0 +1,68 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+)
+
+// bannerWritten is set once the startup banner was written, since that only
+// happens once per process. Protected by initMutex.
+var bannerWritten bool
+
+// programVersion returns the version of the main module, as recorded in the
+// executable, or "unknown".
+func programVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "unknown"
+}
+
+// writeBanner writes the startup banner, which describes the program and the
+// configuration of rlog, regardless of the log level. The caller needs to
+// hold the write lock on initMutex.
+func writeBanner() {
+	host, _ := os.Hostname()
+	source := "environment"
+	if lastGoodConfLines != nil {
+		source += ", " + settingConfFile
+	}
+	program := filepath.Base(os.Args[0])
+	traceLevel := traceFilterSpec.String(true)
+	if traceLevel == "" {
+		traceLevel = "off"
+	}
+	writeRecord(&logRecord{
+		time:            currentTime(),
+		level:           levelInfo,
+		levelDecoration: levelStrings[levelInfo],
+		timeFormat:      settingDateTimeFormat,
+		msg:             "rlog: Started " + program + "\n",
+		fields: []field{
+			{key: "program", kind: fieldString, str: program},
+			{key: "version", kind: fieldString, str: programVersion()},
+			intField("pid", int64(os.Getpid())),
+			{key: "host", kind: fieldString, str: host},
+			{key: "log_level", kind: fieldString, str: logFilterSpec.String(false)},
+			{key: "trace_level", kind: fieldString, str: traceLevel},
+			{key: "config", kind: fieldString, str: source},
+		},
+	})
+	bannerWritten = true
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBanner checks that the startup banner is written once, if requested,
+// regardless of the log level.
+func TestBanner(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer func() { bannerWritten = false }()
+	bannerWritten = false
+	conf.banner = "yes"
+	conf.logLevel = "ERROR"
+	initialize(conf, true)
+	initialize(conf, true)
+	Error("Test Error 1")
+
+	host, _ := os.Hostname()
+	program := filepath.Base(os.Args[0])
+	checkLines := []string{
+		fmt.Sprintf("INFO     : rlog: Started %s program=%s version=%s pid=%d host=%s log_level=ERROR trace_level=off config=environment",
+			program, program, programVersion(), os.Getpid(), host),
+		"ERROR    : Test Error 1",
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package benchmarks measures the cost of rlog's hot paths: Log calls that
+// are disabled or filtered out, which should be as cheap as possible, and
+// entries in text and JSON output, with and without caller info. Run them
+// with:
+//
+//     go test -bench . -benchmem github.com/romana/rlog/benchmarks
+//
+// The constants below are the targets for each benchmark. The number of
+// allocations per operation is checked by the tests of this package, so that
+// changes which add allocations to a hot path are noticed. The time per
+// operation depends on the machine. The targets were set on a typical server
+// CPU and are only checked if RLOG_BENCH_TIMING is set in the environment.
+package benchmarks
+
+// Targets for messages that are not logged. They don't allocate at all.
+const (
+	DisabledDebugNs            = 700
+	DisabledDebugAllocs        = 0
+	DisabledTraceNs            = 50
+	DisabledTraceAllocs        = 0
+	FilteredTraceNs            = 800
+	FilteredTraceAllocs        = 0
+	FilteredDebugPerFileNs     = 900
+	FilteredDebugPerFileAllocs = 0
+)
+
+// Targets for messages that are logged.
+const (
+	TextNs           = 2500
+	TextAllocs       = 12
+	JSONNs           = 4000
+	JSONAllocs       = 11
+	CallerInfoNs     = 3500
+	CallerInfoAllocs = 17
+)
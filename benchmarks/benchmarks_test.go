@@ -0,0 +1,144 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package benchmarks
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/romana/rlog"
+)
+
+// benchmarkCase is one of the measured log calls, with the configuration it
+// is measured in and its targets.
+type benchmarkCase struct {
+	settings map[string]string // rlog settings on top of the defaults
+	call     func(i int)       // the log call
+	ns       float64           // target for the time per call
+	allocs   float64           // target for the allocations per call
+}
+
+var errNotFound = errors.New("not found")
+
+// cases are the benchmarks by name.
+var cases = map[string]benchmarkCase{
+	"DisabledDebug": {
+		call:   func(i int) { rlog.Debug("Disabled message") },
+		ns:     DisabledDebugNs,
+		allocs: DisabledDebugAllocs,
+	},
+	"DisabledTrace": {
+		call:   func(i int) { rlog.Trace(1, "Disabled trace") },
+		ns:     DisabledTraceNs,
+		allocs: DisabledTraceAllocs,
+	},
+	"FilteredTrace": {
+		settings: map[string]string{"RLOG_TRACE_LEVEL": "1"},
+		call:     func(i int) { rlog.Trace(2, "Filtered trace") },
+		ns:       FilteredTraceNs,
+		allocs:   FilteredTraceAllocs,
+	},
+	"FilteredDebugPerFile": {
+		settings: map[string]string{"RLOG_LOG_LEVEL": "client.go=DEBUG,ip*=WARN,INFO"},
+		call:     func(i int) { rlog.Debug("Filtered message") },
+		ns:       FilteredDebugPerFileNs,
+		allocs:   FilteredDebugPerFileAllocs,
+	},
+	"Text": {
+		call:   func(i int) { rlog.Infof("Message %d", i) },
+		ns:     TextNs,
+		allocs: TextAllocs,
+	},
+	"JSON": {
+		settings: map[string]string{"RLOG_LOG_FORMAT": "json"},
+		call: func(i int) {
+			rlog.Ev().Str("user", "alice").Int("n", i).Err(errNotFound).Msg("Lookup failed")
+		},
+		ns:     JSONNs,
+		allocs: JSONAllocs,
+	},
+	"CallerInfo": {
+		settings: map[string]string{"RLOG_CALLER_INFO": "yes"},
+		call:     func(i int) { rlog.Info("Message with caller info") },
+		ns:       CallerInfoNs,
+		allocs:   CallerInfoAllocs,
+	},
+}
+
+// configure applies the given rlog settings, on top of a configuration that
+// discards all output, so that only the cost of rlog itself is measured.
+func configure(settings map[string]string) {
+	for _, name := range []string{"RLOG_LOG_LEVEL", "RLOG_TRACE_LEVEL",
+		"RLOG_LOG_FILE", "RLOG_LOG_FORMAT", "RLOG_CALLER_INFO"} {
+		os.Unsetenv(name)
+	}
+	for name, value := range settings {
+		os.Setenv(name, value)
+	}
+	rlog.DisableConfFile()
+	rlog.SetStreamOutput(ioutil.Discard)
+	rlog.UpdateEnv()
+}
+
+// run runs the benchmark with the given name.
+func run(b *testing.B, name string) {
+	c := cases[name]
+	configure(c.settings)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.call(i)
+	}
+}
+
+func BenchmarkDisabledDebug(b *testing.B)        { run(b, "DisabledDebug") }
+func BenchmarkDisabledTrace(b *testing.B)        { run(b, "DisabledTrace") }
+func BenchmarkFilteredTrace(b *testing.B)        { run(b, "FilteredTrace") }
+func BenchmarkFilteredDebugPerFile(b *testing.B) { run(b, "FilteredDebugPerFile") }
+func BenchmarkText(b *testing.B)                 { run(b, "Text") }
+func BenchmarkJSON(b *testing.B)                 { run(b, "JSON") }
+func BenchmarkCallerInfo(b *testing.B)           { run(b, "CallerInfo") }
+
+// TestTargets checks that the log calls don't allocate more than their
+// targets allow and, if RLOG_BENCH_TIMING is set, that they aren't slower.
+// The race detector adds allocations and time, so nothing is checked then.
+func TestTargets(t *testing.T) {
+	if raceEnabled {
+		t.Skip("Targets don't apply with the race detector")
+	}
+	timing := os.Getenv("RLOG_BENCH_TIMING") != ""
+	for name, c := range cases {
+		configure(c.settings)
+		i := 0
+		allocs := testing.AllocsPerRun(1000, func() {
+			c.call(i)
+			i++
+		})
+		if allocs > c.allocs {
+			t.Errorf("%s: %.0f allocs/op, target is %.0f", name, allocs, c.allocs)
+		}
+		if !timing {
+			continue
+		}
+		result := testing.Benchmark(func(b *testing.B) { run(b, name) })
+		if ns := float64(result.NsPerOp()); ns > c.ns {
+			t.Errorf("%s: %.0f ns/op, target is %.0f", name, ns, c.ns)
+		}
+	}
+	configure(nil)
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"unicode/utf8"
+)
+
+// The file name under which body dumps are matched against the trace level
+// filters. This allows enabling them with a per-file trace level.
+const bodyDumpFileName = "rlog/bodydump.go"
+
+// defaultBodyDumpBytes is the number of bytes of a body that are dumped, if
+// the middleware doesn't ask for a different number.
+const defaultBodyDumpBytes = 4096
+
+// BodyDumpMiddleware wraps an HTTP handler, so that the bodies of requests and
+// responses are logged as trace messages of the given trace level, together
+// with the method, path and response status. This replaces ad-hoc calls of
+// httputil.DumpRequest. Bodies are only captured while the trace level is
+// enabled for the file name "rlog/bodydump.go", for example with
+// RLOG_TRACE_LEVEL=bodydump.go=5, so that the middleware costs next to
+// nothing otherwise. Only the first maxBytes bytes of a body are logged, or
+// 4096 if maxBytes isn't positive. Bodies that aren't text are logged as
+// their length only. The messages are logged via the Logger of the request's
+// context, so together with RequestIDMiddleware they carry the request ID:
+//
+//     handler = rlog.RequestIDMiddleware(rlog.BodyDumpMiddleware(5, 1024, handler))
+//
+// The request body is read up to the limit before the handler is called,
+// which means that the handler only starts once that much has arrived.
+func BodyDumpMiddleware(traceLevel int, maxBytes int, next http.Handler) http.Handler {
+	if maxBytes <= 0 {
+		maxBytes = defaultBodyDumpBytes
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !bodyDumpEnabled(traceLevel) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var reqBody []byte
+		if r.Body != nil && r.Body != http.NoBody {
+			reqBody, _ = ioutil.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+		}
+		l := FromContext(r.Context())
+		logBodyDump(l, traceLevel, fmt.Sprintf("Request body of %s %s: %s",
+			r.Method, r.URL.Path, formatBodyDump(reqBody, maxBytes)))
+
+		dw := &bodyDumpWriter{ResponseWriter: w, max: maxBytes, status: http.StatusOK}
+		next.ServeHTTP(dw, r)
+		logBodyDump(l, traceLevel, fmt.Sprintf("Response body of %s %s (status %d): %s",
+			r.Method, r.URL.Path, dw.status, formatBodyDump(dw.body, maxBytes)))
+	})
+}
+
+// bodyDumpEnabled returns true if trace messages of the given level are
+// logged for body dumps.
+func bodyDumpEnabled(traceLevel int) bool {
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	return traceFilterSpec.matchfilters(bodyDumpFileName, traceLevel)
+}
+
+// logBodyDump logs a body dump as trace message, which is matched against the
+// filters under the file name for body dumps.
+func logBodyDump(l *Logger, traceLevel int, msg string) {
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	caller := callerData{
+		funcName:          "github.com/romana/rlog.BodyDumpMiddleware",
+		moduleAndFileName: bodyDumpFileName,
+	}
+	logEntry(currentTime(), l, levelTrace, traceLevel, caller, "%s\n",
+		tracePrefix(traceLevel), msg)
+}
+
+// formatBodyDump formats a captured body for the log. Text is quoted, so that
+// the message stays on one line, and cut off after max bytes. Anything else
+// is only described by its length.
+func formatBodyDump(body []byte, max int) string {
+	if len(body) == 0 {
+		return "(empty)"
+	}
+	truncated := len(body) > max
+	if truncated {
+		body = body[:max]
+		// Don't cut a multi-byte character in half.
+		for i := 0; i < utf8.UTFMax && len(body) > 0 && !utf8.Valid(body); i++ {
+			body = body[:len(body)-1]
+		}
+	}
+	if !isText(body) {
+		if truncated {
+			return fmt.Sprintf("(more than %d bytes of binary data)", max)
+		}
+		return fmt.Sprintf("(%d bytes of binary data)", len(body))
+	}
+	if truncated {
+		return fmt.Sprintf("%q (truncated after %d bytes)", body, max)
+	}
+	return fmt.Sprintf("%q", body)
+}
+
+// isText returns true if the data is valid UTF-8 without control characters,
+// other than white space.
+func isText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, b := range data {
+		if (b < ' ' && b != '\t' && b != '\n' && b != '\r') || b == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// bodyDumpWriter captures the status and the beginning of the body of a
+// response, while passing them on.
+type bodyDumpWriter struct {
+	http.ResponseWriter
+	max    int
+	status int
+	body   []byte
+}
+
+// WriteHeader records the status and passes it on.
+func (w *bodyDumpWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write captures the data up to one byte more than the limit, so that
+// truncation can be noted, and passes it on.
+func (w *bodyDumpWriter) Write(p []byte) (int, error) {
+	if n := w.max + 1 - len(w.body); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+		w.body = append(w.body, p[:n]...)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush passes the flush on, if the wrapped ResponseWriter supports it.
+func (w *bodyDumpWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack passes the hijacking on, if the wrapped ResponseWriter supports it.
+func (w *bodyDumpWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("rlog: the ResponseWriter doesn't support hijacking")
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestFormatBodyDump checks the formatting of text, binary and truncated
+// bodies.
+func TestFormatBodyDump(t *testing.T) {
+	tests := []struct {
+		body string
+		dump string
+	}{
+		{"", "(empty)"},
+		{"{\"a\": 1}\n", "\"{\\\"a\\\": 1}\\n\""},
+		{"0123456789ab", "\"0123456789\" (truncated after 10 bytes)"},
+		{"123456789\xc3\xa4", "\"123456789\" (truncated after 10 bytes)"},
+		{"\x00\x01\x02", "(3 bytes of binary data)"},
+		{"\x00\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a", "(more than 10 bytes of binary data)"},
+	}
+	for _, test := range tests {
+		if dump := formatBodyDump([]byte(test.body), 10); dump != test.dump {
+			t.Errorf("Incorrect dump of %q: %s", test.body, dump)
+		}
+	}
+}
+
+// TestBodyDumpMiddleware checks that request and response bodies are logged
+// if the trace level is enabled, while the handler sees the complete request.
+func TestBodyDumpMiddleware(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	var received []string
+	handler := BodyDumpMiddleware(2, 8, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		received = append(received, string(b))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	}))
+	serve := func() {
+		r := httptest.NewRequest("POST", "/items", strings.NewReader("name=a long item"))
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	serve()
+	conf.traceLevel = "bodydump.go=2"
+	initialize(conf, true)
+	serve()
+
+	if len(received) != 2 || received[0] != "name=a long item" || received[1] != received[0] {
+		t.Fatalf("Incorrect request bodies: %q", received)
+	}
+	checkLines := []string{
+		"TRACE(2) : Request body of POST /items: \"name=a l\" (truncated after 8 bytes)",
+		"TRACE(2) : Response body of POST /items (status 201): \"done\"",
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"sync"
+	"time"
+)
+
+// EventBuilder assembles a log entry with typed fields, for example:
+//
+//     rlog.Ev().Str("user", u).Int("n", 3).Dur("took", d).Msg("imported")
+//
+// The field values are stored and formatted without reflection and builders
+// are reused, so this is well suited for hot code paths. An EventBuilder must
+// not be used anymore after Msg or Msgf was called.
+type EventBuilder struct {
+	level  int
+	fields []field
+}
+
+// builderPool holds EventBuilders for reuse.
+var builderPool = sync.Pool{
+	New: func() interface{} {
+		return &EventBuilder{fields: make([]field, 0, 8)}
+	},
+}
+
+// Ev starts a new log entry at INFO level. Finish it with Msg or Msgf.
+func Ev() *EventBuilder {
+	b := builderPool.Get().(*EventBuilder)
+	b.level = levelInfo
+	b.fields = b.fields[:0]
+	return b
+}
+
+// Level sets the level at which the entry is logged.
+func (b *EventBuilder) Level(level Level) *EventBuilder {
+	b.level = int(level)
+	return b
+}
+
+// Str adds a string field.
+func (b *EventBuilder) Str(key string, value string) *EventBuilder {
+	b.fields = append(b.fields, field{key: key, kind: fieldString, str: value})
+	return b
+}
+
+// Int adds an integer field.
+func (b *EventBuilder) Int(key string, value int) *EventBuilder {
+	b.fields = append(b.fields, intField(key, int64(value)))
+	return b
+}
+
+// Int64 adds a 64 bit integer field.
+func (b *EventBuilder) Int64(key string, value int64) *EventBuilder {
+	b.fields = append(b.fields, intField(key, value))
+	return b
+}
+
+// Uint64 adds an unsigned 64 bit integer field.
+func (b *EventBuilder) Uint64(key string, value uint64) *EventBuilder {
+	b.fields = append(b.fields, field{key: key, kind: fieldUint, num: int64(value)})
+	return b
+}
+
+// Float64 adds a floating point field.
+func (b *EventBuilder) Float64(key string, value float64) *EventBuilder {
+	b.fields = append(b.fields, field{key: key, kind: fieldFloat, fl: value})
+	return b
+}
+
+// Bool adds a boolean field.
+func (b *EventBuilder) Bool(key string, value bool) *EventBuilder {
+	f := field{key: key, kind: fieldBool}
+	if value {
+		f.num = 1
+	}
+	b.fields = append(b.fields, f)
+	return b
+}
+
+// Dur adds a duration field.
+func (b *EventBuilder) Dur(key string, value time.Duration) *EventBuilder {
+	b.fields = append(b.fields, field{key: key, kind: fieldDuration, num: int64(value)})
+	return b
+}
+
+// Time adds a time field, formatted according to RFC3339 with nanoseconds.
+func (b *EventBuilder) Time(key string, value time.Time) *EventBuilder {
+	b.fields = append(b.fields, field{key: key, kind: fieldString,
+		str: value.Format(time.RFC3339Nano)})
+	return b
+}
+
+// Err adds the error message as field 'error'. Nothing is added for a nil
+// error.
+func (b *EventBuilder) Err(err error) *EventBuilder {
+	if err != nil {
+		b.fields = append(b.fields, field{key: "error", kind: fieldString, str: err.Error()})
+	}
+	return b
+}
+
+// Any adds a field with a value of arbitrary type. This is formatted with
+// the help of reflection, so the typed methods should be preferred.
+func (b *EventBuilder) Any(key string, value interface{}) *EventBuilder {
+	b.fields = append(b.fields, anyField(key, value))
+	return b
+}
+
+// Msg logs the entry with the given message.
+func (b *EventBuilder) Msg(msg string) {
+	l := Logger{fields: b.fields}
+	basicLog(&l, b.level, notATrace, false, "%s\n", "", msg)
+	builderPool.Put(b)
+}
+
+// Msgf logs the entry with a formatted message.
+func (b *EventBuilder) Msgf(format string, a ...interface{}) {
+	l := Logger{fields: b.fields}
+	basicLog(&l, b.level, notATrace, false, format, "", a...)
+	builderPool.Put(b)
+}
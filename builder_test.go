@@ -0,0 +1,56 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestEventBuilder checks the output of typed fields in text and JSON format.
+func TestEventBuilder(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	Ev().Str("user", "joe").Int("n", 3).Dur("took", 1500*time.Millisecond).Msg("imported")
+	Ev().Level(LevelWarn).Str("path", "a b").Bool("ok", false).Float64("f", 0.5).
+		Err(errors.New("failed")).Msgf("problem %d", 1)
+	Ev().Level(LevelDebug).Msg("not shown")
+
+	conf.logFormat = "json"
+	initialize(conf, true)
+	Ev().Str("user", "\"joe\"").Uint64("n", 3).Dur("took", time.Second).Msg("imported")
+
+	checkLines := []string{
+		"INFO     : imported user=joe n=3 took=1.5s",
+		`WARN     : problem 1 path="a b" ok=false f=0.5 error=failed`,
+		`{"level":"INFO","msg":"imported","user":"\"joe\"","n":3,"took":"1s"}`,
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// BenchmarkEventBuilder measures the cost of logging with typed fields.
+func BenchmarkEventBuilder(b *testing.B) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	for i := 0; i < b.N; i++ {
+		Ev().Str("user", "joe").Int("n", i).Dur("took", time.Second).Msg("imported")
+	}
+}
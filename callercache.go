@@ -0,0 +1,52 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"runtime"
+	"sync"
+)
+
+// maxCachedCallers limits the number of call sites whose caller info is
+// cached, so that programs with generated code can't exhaust the memory.
+const maxCachedCallers = 10000
+
+// callerCache holds the caller info of the call sites of log functions by
+// program counter, since resolving it is much more expensive than the lookup.
+// The information for a program counter never changes.
+var callerCache = struct {
+	sync.RWMutex
+	m map[uintptr]callerData
+}{m: make(map[uintptr]callerData)}
+
+// cachedCaller returns the caller info for the given program counter, as
+// returned by runtime.Callers.
+func cachedCaller(pc uintptr) callerData {
+	callerCache.RLock()
+	caller, ok := callerCache.m[pc]
+	callerCache.RUnlock()
+	if ok {
+		return caller
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	caller = newCallerData(frame.Function, frame.File, frame.Line)
+	callerCache.Lock()
+	if len(callerCache.m) < maxCachedCallers {
+		callerCache.m[pc] = caller
+	}
+	callerCache.Unlock()
+	return caller
+}
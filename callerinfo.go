@@ -0,0 +1,80 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strings"
+)
+
+// callerInfoRule decides whether messages from matching files show caller
+// info.
+type callerInfoRule struct {
+	pattern string // shell glob to match caller file name
+	show    bool   // whether caller info is shown
+}
+
+// settingCallerInfoRules holds the per file caller info settings.
+var settingCallerInfoRules []callerInfoRule
+
+// parseCallerInfo interprets the value of RLOG_CALLER_INFO. Besides a single
+// flag for all files, this may be a list of flags for the files matching a
+// pattern, like the log level filters.
+//
+// Format "<setting>,<setting>,[<setting>]..."
+//     setting:
+//       <pattern=flag> | <flag>
+//     pattern:
+//       shell glob to match caller file name
+//     flag:
+//       "yes", "1" or another value that evaluates to 'true' to show caller
+//       info, anything else to not show it
+//
+//     Example:
+//     - "RLOG_CALLER_INFO=server.go=yes,no"
+//       Caller info is shown for messages from server.go only.
+func parseCallerInfo(s string) (show bool, rules []callerInfoRule) {
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.Index(item, "=")
+		if i < 0 {
+			show = isTrueBoolString(item)
+			continue
+		}
+		pattern := strings.TrimSpace(item[:i])
+		if pattern == "" {
+			rlogIssue("Malformed caller info setting: '%s'", item)
+			continue
+		}
+		rules = append(rules, callerInfoRule{pattern, isTrueBoolString(strings.TrimSpace(item[i+1:]))})
+	}
+	return show, rules
+}
+
+// showCallerInfoFor returns whether messages from the given file show caller
+// info, according to the first matching per file setting. If none matches
+// then the setting for all files applies. The caller needs to hold at least
+// the read lock on initMutex.
+func showCallerInfoFor(filename string) bool {
+	for _, r := range settingCallerInfoRules {
+		if matchFilePattern(r.pattern, filename) {
+			return r.show
+		}
+	}
+	return settingShowCallerInfo
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// TestParseCallerInfo checks the interpretation of RLOG_CALLER_INFO.
+func TestParseCallerInfo(t *testing.T) {
+	tests := []struct {
+		spec  string
+		show  bool
+		rules []callerInfoRule
+	}{
+		{"", false, nil},
+		{"yes", true, nil},
+		{"server.go=yes,no", false, []callerInfoRule{{"server.go", true}}},
+		{"db/*=0, 1", true, []callerInfoRule{{"db/*", false}}},
+		{"=yes", false, nil},
+	}
+	for _, test := range tests {
+		show, rules := parseCallerInfo(test.spec)
+		if show != test.show || !reflect.DeepEqual(rules, test.rules) {
+			t.Errorf("Incorrect caller info settings for '%s': %v %v", test.spec, show, rules)
+		}
+	}
+}
+
+// TestCallerInfoPerFile checks that caller info is only shown for messages
+// from matching files.
+func TestCallerInfoPerFile(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.showCallerInfo = "callerinfo_test.go=yes,no"
+	initialize(conf, true)
+	Info("Test Info 1")
+	_, file, line, _ := runtime.Caller(0)
+	line--
+	fileName := newCallerData("", file, 0).moduleAndFileName
+
+	conf.showCallerInfo = "other.go=yes,no"
+	initialize(conf, true)
+	Info("Test Info 2")
+
+	checkLines := []string{
+		fmt.Sprintf("INFO     : [%d %s:%d (github.com/romana/rlog.TestCallerInfoPerFile)] Test Info 1",
+			os.Getpid(), fileName, line),
+		"INFO     : Test Info 2",
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"runtime"
+	"strings"
+)
+
+// maxSkippedFrames is the maximum number of stack frames that are examined
+// when looking for the caller outside of the skipped packages.
+const maxSkippedFrames = 32
+
+// settingCallerSkipPrefixes lists the prefixes of functions, such as those of
+// logging helper packages, which are never reported as the caller of a log
+// function.
+var settingCallerSkipPrefixes []string
+
+// parseSkipPrefixes interprets the value of RLOG_CALLER_SKIP_PREFIXES, which is
+// a comma separated list of package paths or function name prefixes.
+func parseSkipPrefixes(s string) []string {
+	var prefixes []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
+
+// skippedFunc returns true if the function belongs to one of the packages
+// that are skipped when looking for the caller.
+func skippedFunc(funcName string) bool {
+	for _, p := range settingCallerSkipPrefixes {
+		if strings.HasPrefix(funcName, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// getCallerSkipping works like getCaller, but moves further up the stack as
+// long as the caller is in one of the skipped packages. If all of them are,
+// the original caller is returned. The skip parameter is relative to the
+// caller of getCaller. The caller needs to hold at least the read lock on
+// initMutex.
+func getCallerSkipping(skip int) callerData {
+	pcs := make([]uintptr, maxSkippedFrames)
+	n := runtime.Callers(skip+2, pcs)
+	if n == 0 {
+		return callerData{}
+	}
+	frames := runtime.CallersFrames(pcs[:n])
+	first, more := frames.Next()
+	frame := first
+	for skippedFunc(frame.Function) && more {
+		frame, more = frames.Next()
+	}
+	if skippedFunc(frame.Function) {
+		frame = first
+	}
+	return newCallerData(frame.Function, frame.File, frame.Line)
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// logHelper stands in for a logging helper in another package, which is
+// skipped when looking for the caller.
+func logHelper(msg string) {
+	Info(msg)
+}
+
+// TestCallerSkipPrefixes checks that functions with a skipped prefix are not
+// shown as the caller of a log function.
+func TestCallerSkipPrefixes(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.showCallerInfo = "true"
+	conf.callerSkip = " github.com/romana/rlog.logHelper, "
+	initialize(conf, true)
+
+	logHelper("Test Info 1")
+	_, file, line, _ := runtime.Caller(0)
+	line--
+	fileName := newCallerData("", file, 0).moduleAndFileName
+
+	// If everything is skipped, the direct caller is shown
+	conf.callerSkip = "github.com/romana/rlog,runtime,testing"
+	initialize(conf, true)
+	Info("Test Info 2")
+
+	checkLines := []string{
+		fmt.Sprintf("INFO     : [%d %s:%d (github.com/romana/rlog.TestCallerSkipPrefixes)] Test Info 1",
+			os.Getpid(), fileName, line),
+		fmt.Sprintf("INFO     : [%d %s:%d (github.com/romana/rlog.TestCallerSkipPrefixes)] Test Info 2",
+			os.Getpid(), fileName, line+8),
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strconv"
+	"strings"
+)
+
+var (
+	settingCallerWidth      int  // width of the caller info column, 0 if not fixed
+	settingCallerAlignRight bool // whether caller info is aligned to the right
+)
+
+// parseCallerWidth interprets the value of RLOG_CALLER_WIDTH, which is the
+// width of the caller info column, optionally followed by ":left" or
+// ":right" for the alignment, for example "60:right".
+func parseCallerWidth(spec string) (width int, alignRight bool) {
+	if spec == "" {
+		return 0, false
+	}
+	tokens := strings.SplitN(spec, ":", 2)
+	width, err := strconv.Atoi(strings.TrimSpace(tokens[0]))
+	if err == nil && len(tokens) == 2 {
+		switch strings.ToLower(strings.TrimSpace(tokens[1])) {
+		case "left":
+		case "right":
+			alignRight = true
+		default:
+			err = strconv.ErrSyntax
+		}
+	}
+	if err != nil || width < 0 {
+		rlogIssue("Cannot parse caller width value '%s'. Ignored.", spec)
+		return 0, false
+	}
+	return width, alignRight
+}
+
+// fitCallerInfo pads or truncates the caller info of a text line, such as
+// "[1234 app/server.go:42 (main.serve)]", to the width of the caller info
+// column. Function names are shortened first, by removing the package path,
+// before the end is cut off and replaced with "...". Empty caller info is
+// padded as well, so that the messages line up.
+func fitCallerInfo(callerInfo string) string {
+	width := settingCallerWidth
+	if len(callerInfo) > width && strings.HasSuffix(callerInfo, ")]") {
+		if i := strings.LastIndex(callerInfo, " ("); i >= 0 {
+			if j := strings.LastIndex(callerInfo, "/"); j > i {
+				callerInfo = callerInfo[:i+2] + callerInfo[j+1:]
+			}
+		}
+	}
+	if len(callerInfo) > width {
+		if width <= len("...]") {
+			return callerInfo[:width]
+		}
+		return callerInfo[:width-len("...]")] + "...]"
+	}
+	padding := strings.Repeat(" ", width-len(callerInfo))
+	if settingCallerAlignRight {
+		return padding + callerInfo
+	}
+	return callerInfo + padding
+}
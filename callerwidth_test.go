@@ -0,0 +1,90 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseCallerWidth checks the interpretation of RLOG_CALLER_WIDTH.
+func TestParseCallerWidth(t *testing.T) {
+	tests := []struct {
+		spec       string
+		width      int
+		alignRight bool
+	}{
+		{"", 0, false},
+		{"40", 40, false},
+		{"40:left", 40, false},
+		{" 50 : Right", 50, true},
+		{"40:center", 0, false},
+		{"-3", 0, false},
+		{"wide", 0, false},
+	}
+	for _, test := range tests {
+		width, alignRight := parseCallerWidth(test.spec)
+		if width != test.width || alignRight != test.alignRight {
+			t.Errorf("Incorrect caller width for %q: %d, %t", test.spec, width, alignRight)
+		}
+	}
+}
+
+// TestFitCallerInfo checks that caller info is padded and truncated to the
+// width of the column.
+func TestFitCallerInfo(t *testing.T) {
+	defer func() { settingCallerWidth, settingCallerAlignRight = 0, false }()
+	tests := []struct {
+		width      int
+		alignRight bool
+		callerInfo string
+		fitted     string
+	}{
+		{12, false, "[1 a.go:1]", "[1 a.go:1]  "},
+		{12, true, "[1 a.go:1]", "  [1 a.go:1]"},
+		{4, false, "", "    "},
+		{30, false, "[1 app/a.go:1 (github.com/x/app.run)]", "[1 app/a.go:1 (app.run)]      "},
+		{20, false, "[1 app/a.go:1 (github.com/x/app.run)]", "[1 app/a.go:1 (a...]"},
+		{3, false, "[1 a.go:1]", "[1 "},
+	}
+	for _, test := range tests {
+		settingCallerWidth, settingCallerAlignRight = test.width, test.alignRight
+		if fitted := fitCallerInfo(test.callerInfo); fitted != test.fitted {
+			t.Errorf("Incorrect caller info for %q with width %d: %q",
+				test.callerInfo, test.width, fitted)
+		}
+	}
+}
+
+// TestCallerWidth checks that messages line up with a fixed caller width,
+// also if only some of them have caller info.
+func TestCallerWidth(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.callerWidth = "46"
+	initialize(conf, true)
+	SetTestMode(true)
+	defer SetTestMode(false)
+
+	Info("Test Info 1")
+	WithCaller().Info("Test Info 2")
+
+	checkLines := []string{
+		"INFO     : " + strings.Repeat(" ", 46) + " Test Info 1",
+		"INFO     : [callerwidth_test.go (rlog.TestCallerWidth)]   Test Info 2",
+	}
+	fileMatch(t, checkLines, "")
+}
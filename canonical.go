@@ -0,0 +1,132 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// settingCanonicalTimes determines whether times and durations in messages
+// and fields are formatted according to RFC3339 and ISO 8601, rather than
+// the way Go formats them by default.
+var settingCanonicalTimes bool
+
+// isoDuration formats a duration according to ISO 8601, for example
+// "PT1H30M" or "PT0.25S". Only hours, minutes and seconds are used, since
+// days don't always have the same length.
+func isoDuration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	// The absolute value of the smallest duration can't be represented, so
+	// we work with unsigned values.
+	u := uint64(d)
+	if d < 0 {
+		b.WriteByte('-')
+		u = -u
+	}
+	b.WriteString("PT")
+	h, u := u/uint64(time.Hour), u%uint64(time.Hour)
+	m, u := u/uint64(time.Minute), u%uint64(time.Minute)
+	if h > 0 {
+		b.WriteString(strconv.FormatUint(h, 10) + "H")
+	}
+	if m > 0 {
+		b.WriteString(strconv.FormatUint(m, 10) + "M")
+	}
+	if u > 0 {
+		s := strconv.FormatUint(u/uint64(time.Second), 10)
+		if ns := u % uint64(time.Second); ns > 0 {
+			s += strings.TrimRight(fmt.Sprintf(".%09d", ns), "0")
+		}
+		b.WriteString(s + "S")
+	}
+	return b.String()
+}
+
+// canonicalValue returns the canonical form of a time or duration value. The
+// second return value is false for values of other types.
+func canonicalValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	case time.Duration:
+		return isoDuration(v), true
+	}
+	return "", false
+}
+
+// canonicalArg wraps a time or duration argument of a log function, so that
+// it is formatted in canonical form with the verbs %v and %s, while all other
+// verbs are applied to the original value.
+type canonicalArg struct {
+	value interface{}
+	str   string
+}
+
+// Format implements fmt.Formatter.
+func (c canonicalArg) Format(f fmt.State, verb rune) {
+	if verb == 'v' || verb == 's' {
+		fmt.Fprintf(f, formatDirective(f, 's'), c.str)
+		return
+	}
+	fmt.Fprintf(f, formatDirective(f, verb), c.value)
+}
+
+// formatDirective reconstructs the directive, with flags, width and
+// precision, for which Format was called, but with the given verb.
+func formatDirective(f fmt.State, verb rune) string {
+	b := []byte{'%'}
+	for _, flag := range "+-# 0" {
+		if f.Flag(int(flag)) {
+			b = append(b, byte(flag))
+		}
+	}
+	if w, ok := f.Width(); ok {
+		b = strconv.AppendInt(b, int64(w), 10)
+	}
+	if p, ok := f.Precision(); ok {
+		b = append(b, '.')
+		b = strconv.AppendInt(b, int64(p), 10)
+	}
+	return string(append(b, string(verb)...))
+}
+
+// canonicalArgs returns the arguments of a log function with the time and
+// duration values wrapped, so that they are formatted in canonical form. The
+// arguments are only copied if there are such values.
+func canonicalArgs(a []interface{}) []interface{} {
+	var wrapped []interface{}
+	for i, v := range a {
+		s, ok := canonicalValue(v)
+		if !ok {
+			continue
+		}
+		if wrapped == nil {
+			wrapped = make([]interface{}, len(a))
+			copy(wrapped, a)
+		}
+		wrapped[i] = canonicalArg{value: v, str: s}
+	}
+	if wrapped == nil {
+		return a
+	}
+	return wrapped
+}
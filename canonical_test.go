@@ -0,0 +1,59 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+	"time"
+)
+
+// TestISODuration checks the formatting of durations according to ISO 8601.
+func TestISODuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                                   "PT0S",
+		time.Second:                         "PT1S",
+		90 * time.Minute:                    "PT1H30M",
+		250 * time.Millisecond:              "PT0.25S",
+		-(26*time.Hour + 3*time.Second):     "-PT26H3S",
+		time.Minute + 1500*time.Microsecond: "PT1M0.0015S",
+	}
+	for d, should := range tests {
+		if is := isoDuration(d); is != should {
+			t.Errorf("isoDuration(%v) = %s, should be %s", d, is, should)
+		}
+	}
+}
+
+// TestCanonicalTimes checks that times and durations in messages and fields
+// are formatted canonically, if requested.
+func TestCanonicalTimes(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.canonicalTimes = "yes"
+	initialize(conf, true)
+
+	ts := time.Date(2024, 6, 13, 10, 30, 0, 0, time.UTC)
+	Infof("Started at %v, took %s, %d ns", ts, 2*time.Second, time.Second)
+	Info("Took", 1500*time.Millisecond)
+	Ev().Dur("took", time.Minute).Any("at", ts).Msg("Test Info 1")
+
+	checkLines := []string{
+		"INFO     : Started at 2024-06-13T10:30:00Z, took PT2S, 1000000000 ns",
+		"INFO     : Took PT1.5S",
+		"INFO     : Test Info 1 took=PT1M at=2024-06-13T10:30:00Z",
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// CaptureCmd wires the stdout and stderr of a command into rlog, so that the
+// output of external programs shows up in the log as well, with time stamps
+// and caller info. Every line of output becomes a separate log message, which
+// is prefixed with the name of the program. Output on stdout is logged at the
+// specified level, output on stderr at WARN, or at the specified level, if that
+// is more severe. CaptureCmd needs to be called before the command is started.
+//
+// The messages are filtered and decorated as if they were logged at the
+// location from which CaptureCmd was called.
+func CaptureCmd(cmd *exec.Cmd, level Level) {
+	ensureInitialized()
+	initMutex.RLock()
+	caller := getCaller(2)
+	initMutex.RUnlock()
+	prefix := filepath.Base(cmd.Path) + ": "
+	stderrLevel := int(level)
+	if stderrLevel > levelWarn {
+		stderrLevel = levelWarn
+	}
+	cmd.Stdout = &lineLogger{level: int(level), caller: caller, prefix: prefix}
+	cmd.Stderr = &lineLogger{level: stderrLevel, caller: caller, prefix: prefix}
+}
+
+// lineLogger is an io.Writer, which logs every line written to it as a
+// separate log message.
+type lineLogger struct {
+	level  int        // log level of the messages
+	caller callerData // caller info used for the messages
+	prefix string     // prepended to every message
+	buf    []byte     // incomplete line, waiting for the rest
+}
+
+// Write logs all complete lines and holds back the rest.
+func (ll *lineLogger) Write(p []byte) (int, error) {
+	ll.buf = append(ll.buf, p...)
+	for {
+		i := bytes.IndexByte(ll.buf, '\n')
+		if i < 0 {
+			break
+		}
+		ll.logLine(string(ll.buf[:i]))
+		ll.buf = ll.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// ReadFrom logs all lines read from the reader. In contrast to Write, a last
+// line without trailing newline is logged as well. Since io.Copy uses this
+// method, this ensures that such a line isn't lost when the command exits.
+func (ll *lineLogger) ReadFrom(r io.Reader) (int64, error) {
+	var n int64
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		n += int64(len(line))
+		if len(line) > 0 {
+			if _, werr := ll.Write([]byte(line)); werr != nil {
+				return n, werr
+			}
+		}
+		if err == io.EOF {
+			if len(ll.buf) > 0 {
+				ll.logLine(string(ll.buf))
+				ll.buf = nil
+			}
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// logLine logs a single line of output.
+func (ll *lineLogger) logLine(line string) {
+	now := currentTime()
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	logEntry(now, nil, ll.level, notATrace, ll.caller, "%s%s\n", "",
+		ll.prefix, strings.TrimRight(line, "\r"))
+}
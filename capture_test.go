@@ -0,0 +1,43 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"os/exec"
+	"testing"
+)
+
+// TestCaptureCmd checks that the output of a command is logged line by line,
+// with stderr output logged at WARN level.
+func TestCaptureCmd(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	cmd := exec.Command("sh", "-c", "echo line 1; echo line 2; sleep 0.1; echo problem >&2; sleep 0.1; printf 'no newline'")
+	CaptureCmd(cmd, LevelInfo)
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkLines := []string{
+		"INFO     : sh: line 1",
+		"INFO     : sh: line 2",
+		"WARN     : sh: problem",
+		"INFO     : sh: no newline",
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// clock holds the function that provides the time stamps for log entries, if
+// it was replaced with SetClock.
+var clock atomic.Value
+
+// SetClock replaces the function that provides the time stamps for log
+// entries, which is time.Now by default. In tests this allows log output with
+// predictable time stamps, for example for comparison with golden files. The
+// clock also drives sampling and rate limiting. Setting nil restores the
+// default.
+func SetClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	clock.Store(now)
+}
+
+// currentTime returns the time stamp for a new log entry.
+func currentTime() time.Time {
+	if now, ok := clock.Load().(func() time.Time); ok {
+		return now()
+	}
+	return time.Now()
+}
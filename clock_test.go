@@ -0,0 +1,46 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSetClock checks that log entries get their time stamps from the clock.
+func TestSetClock(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer SetClock(nil)
+	conf.logNoTime = "false"
+	conf.logTimeFormat = "RFC3339"
+	conf.timeUTC = "yes"
+	initialize(conf, true)
+
+	tm := time.Date(2020, 2, 29, 12, 30, 0, 0, time.UTC)
+	SetClock(func() time.Time {
+		tm = tm.Add(time.Second)
+		return tm
+	})
+	Info("Test Info 1")
+	Info("Test Info 2")
+
+	checkLines := []string{
+		"2020-02-29T12:30:01Z INFO     : Test Info 1",
+		"2020-02-29T12:30:02Z INFO     : Test Info 2",
+	}
+	fileMatch(t, checkLines, "")
+}
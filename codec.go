@@ -0,0 +1,94 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+	"sync"
+)
+
+// CompressWriter compresses the data written to it. Flush writes all pending
+// compressed data to the underlying writer and Close terminates the
+// compressed stream, without closing the underlying writer. The writers of
+// compress/gzip, as well as those of the common zstd and lz4 packages, already
+// provide these methods.
+type CompressWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// Codec creates the compressor for a logfile.
+type Codec interface {
+	// NewWriter returns a CompressWriter that writes the compressed data to
+	// the logfile w. Every time the logfile is opened a new compressed stream
+	// is appended to it, so the format needs to allow for concatenated
+	// streams.
+	NewWriter(w io.Writer) (CompressWriter, error)
+}
+
+// CodecFunc adapts a function to the Codec interface.
+type CodecFunc func(w io.Writer) (CompressWriter, error)
+
+// NewWriter calls f(w).
+func (f CodecFunc) NewWriter(w io.Writer) (CompressWriter, error) {
+	return f(w)
+}
+
+var (
+	// codecs maps the extensions of logfile names to the codec used for
+	// compressing them. Protected by codecMutex.
+	codecs = map[string]Codec{
+		".gz": CodecFunc(func(w io.Writer) (CompressWriter, error) {
+			return gzip.NewWriter(w), nil
+		}),
+	}
+	codecMutex sync.Mutex
+)
+
+// RegisterCodec makes rlog compress logfiles whose name ends in the given
+// extension with the codec, for example ".zst" or ".lz4". This way
+// compression formats can be added without rlog depending on the packages
+// that implement them. gzip is built in, for the extension ".gz". Registering
+// a nil codec removes the codec for the extension. Logfiles that are already
+// open are not affected, so codecs should be registered before the logging
+// configuration is applied, or the configuration should be updated
+// afterwards.
+func RegisterCodec(ext string, c Codec) {
+	codecMutex.Lock()
+	defer codecMutex.Unlock()
+	if c == nil {
+		delete(codecs, ext)
+		return
+	}
+	codecs[ext] = c
+}
+
+// codecFor returns the codec for a logfile, or nil if the logfile should not
+// be compressed. If several extensions match then the longest one wins.
+func codecFor(fileName string) Codec {
+	codecMutex.Lock()
+	defer codecMutex.Unlock()
+	var codec Codec
+	var longest string
+	for ext, c := range codecs {
+		if strings.HasSuffix(fileName, ext) && len(ext) > len(longest) {
+			codec, longest = c, ext
+		}
+	}
+	return codec
+}
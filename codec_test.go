@@ -0,0 +1,77 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestRegisterCodec checks that a registered codec is used for logfiles with
+// its extension.
+func TestRegisterCodec(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	RegisterCodec(".z", CodecFunc(func(w io.Writer) (CompressWriter, error) {
+		return zlib.NewWriter(w), nil
+	}))
+	defer RegisterCodec(".z", nil)
+
+	logfile += ".z"
+	conf.logFile = logfile
+	initialize(conf, true)
+	Info("Test Info 1")
+	conf.logFile = ""
+	initialize(conf, true)
+
+	f, err := os.Open(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := zlib.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	should := "INFO     : Test Info 1\n"
+	if string(content) != should {
+		t.Fatalf("Incorrect compressed output.\nSHOULD: %s\nIS:     %s\n", should, content)
+	}
+}
+
+// TestCodecFor checks the selection of the codec by the extension.
+func TestCodecFor(t *testing.T) {
+	if codecFor("app.log") != nil {
+		t.Error("Uncompressed logfile should not have a codec")
+	}
+	gz := codecFor("app.log.gz")
+	if gz == nil {
+		t.Error("gzip should be built in")
+	}
+	RegisterCodec(".gz", nil)
+	defer RegisterCodec(".gz", gz)
+	if codecFor("app.log.gz") != nil {
+		t.Error("Codec was not removed")
+	}
+}
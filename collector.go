@@ -0,0 +1,191 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Log entries sent to a collector are terminated by this byte, rather than by
+// a newline, since a single log entry may span several lines.
+const collectorEntryEnd = 0
+
+// collectorSender forwards log entries to the collector of a parent process.
+type collectorSender struct {
+	mutex      sync.Mutex
+	socketPath string   // the socket we are (supposed to be) connected to
+	conn       net.Conn // nil if not connected
+}
+
+// collectorClient is used to forward the log entries of this process, if
+// RLOG_COLLECTOR_SOCKET is set.
+var collectorClient = &collectorSender{}
+
+// connect establishes the connection to the collector at the given socket
+// path. Nothing is done if we are already connected to that socket. An empty
+// path closes any existing connection.
+func (cs *collectorSender) connect(socketPath string) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	if socketPath == cs.socketPath && cs.conn != nil {
+		return
+	}
+	if cs.conn != nil {
+		cs.conn.Close()
+		cs.conn = nil
+	}
+	cs.socketPath = socketPath
+	if socketPath == "" {
+		return
+	}
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		rlogIssue("Unable to connect to log collector: %s", err)
+		return
+	}
+	cs.conn = conn
+}
+
+// send forwards a log entry to the collector, if we are connected to one. If
+// the collector has gone away then the connection is dropped. We try to
+// connect again the next time the configuration is re-read.
+func (cs *collectorSender) send(logLine string) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	if cs.conn == nil {
+		return
+	}
+	if !strings.HasSuffix(logLine, "\n") {
+		logLine += "\n"
+	}
+	if _, err := cs.conn.Write(append([]byte(logLine), collectorEntryEnd)); err != nil {
+		rlogIssue("Lost connection to log collector: %s", err)
+		cs.conn.Close()
+		cs.conn = nil
+	}
+}
+
+// status describes the connection to the collector, for the debug page.
+func (cs *collectorSender) status() string {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	switch {
+	case cs.socketPath == "":
+		return "off"
+	case cs.conn == nil:
+		return cs.socketPath + " (not connected)"
+	default:
+		return cs.socketPath
+	}
+}
+
+var (
+	collectorListener net.Listener          // the running collector, if any
+	collectorPath     string                // the socket path of the collector
+	collectorConns    map[net.Conn]struct{} // the connections of child processes
+	collectorMutex    sync.Mutex            // protects the collector variables
+)
+
+// StartCollector starts a log collector, which listens on a unix socket with
+// the given path. Child processes that use rlog and have the
+// RLOG_COLLECTOR_SOCKET environment variable set to that path forward all
+// their log entries to the collector, which writes them to the outputs of
+// this process. This produces a single, merged log for a whole tree of
+// processes. Any existing file at the socket path is removed.
+func StartCollector(socketPath string) error {
+	collectorMutex.Lock()
+	defer collectorMutex.Unlock()
+	stopCollector()
+	os.Remove(socketPath)
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	collectorListener = l
+	collectorPath = socketPath
+	collectorConns = make(map[net.Conn]struct{})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			collectorMutex.Lock()
+			if collectorListener != l {
+				// The collector was stopped in the meantime.
+				collectorMutex.Unlock()
+				conn.Close()
+				return
+			}
+			collectorConns[conn] = struct{}{}
+			collectorMutex.Unlock()
+			go collectEntries(conn)
+		}
+	}()
+	return nil
+}
+
+// StopCollector stops a log collector that was started with StartCollector.
+// The connections of the child processes are closed and the socket is
+// removed, so that a collector can be started again with the same path.
+func StopCollector() {
+	collectorMutex.Lock()
+	defer collectorMutex.Unlock()
+	stopCollector()
+}
+
+// stopCollector stops the running collector, if any. The caller needs to hold
+// the lock on collectorMutex.
+func stopCollector() {
+	if collectorListener == nil {
+		return
+	}
+	collectorListener.Close()
+	for conn := range collectorConns {
+		conn.Close()
+	}
+	os.Remove(collectorPath)
+	collectorListener = nil
+	collectorPath = ""
+	collectorConns = nil
+}
+
+// collectEntries reads the log entries sent by a child process and writes
+// them to our own outputs, until the child closes the connection.
+func collectEntries(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		collectorMutex.Lock()
+		delete(collectorConns, conn)
+		collectorMutex.Unlock()
+	}()
+	ensureInitialized()
+	r := bufio.NewReader(conn)
+	for {
+		entry, err := r.ReadString(collectorEntryEnd)
+		if err != nil {
+			return
+		}
+		initMutex.RLock()
+		entry = entry[:len(entry)-1]
+		writeOutputs(sameLines(entry), 0)
+		initMutex.RUnlock()
+	}
+}
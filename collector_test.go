@@ -0,0 +1,108 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCollector checks that log entries forwarded by a (simulated) child
+// process end up in the outputs of the process running the collector.
+func TestCollector(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	dir, err := ioutil.TempDir("", "rlog-collector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "collector.sock")
+
+	if err := StartCollector(socketPath); err != nil {
+		t.Fatal(err)
+	}
+	defer StopCollector()
+
+	child := &collectorSender{}
+	child.connect(socketPath)
+	child.send("INFO     : Entry from child\nwith a second line")
+	child.connect("")
+
+	should := "INFO     : Entry from child\nwith a second line\n"
+	var content []byte
+	for i := 0; i < 100; i++ {
+		content, _ = ioutil.ReadFile(logfile)
+		if len(content) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if string(content) != should {
+		t.Fatalf("Incorrect collected output.\nSHOULD: %s\nIS:     %s\n", should, content)
+	}
+}
+
+// TestStopCollector checks that stopping the collector closes the connections
+// of the child processes and removes the socket, so that the collector can be
+// started again with the same path.
+func TestStopCollector(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	dir, err := ioutil.TempDir("", "rlog-collector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "collector.sock")
+
+	if err := StartCollector(socketPath); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("INFO     : Entry from child\n\x00"))
+	for i := 0; i < 100; i++ {
+		if content, _ := ioutil.ReadFile(logfile); len(content) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	StopCollector()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil || os.IsTimeout(err) {
+		t.Errorf("Connection of the child not closed: %v", err)
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("Socket not removed: %v", err)
+	}
+	if err := StartCollector(socketPath); err != nil {
+		t.Fatalf("Unable to start the collector again: %s", err)
+	}
+	StopCollector()
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// confFileError describes an error in a line of the config file.
+type confFileError struct {
+	line int
+	msg  string
+}
+
+var (
+	// lastGoodConfFile and lastGoodConfLines are the name and content of the
+	// config file that was last applied without errors. Protected by
+	// initMutex.
+	lastGoodConfFile  string
+	lastGoodConfLines []string
+	// lastConfNotice is the last notice about errors in the config file that
+	// was logged, so that it is logged only once. Protected by initMutex.
+	lastConfNotice string
+)
+
+// handleConfigErrors decides, depending on RLOG_CONF_ERRORS, whether the
+// config from a config file with errors is used, or the config from the last
+// version without errors. With "warn" and "strict" a notice about the errors
+// is returned, which should be logged. By default the errors are reported on
+// stderr and the erroneous lines are skipped.
+func handleConfigErrors(config *rlogConfig, fileConfig rlogConfig, errs []confFileError) string {
+	switch strings.ToUpper(config.confErrors) {
+	case "STRICT":
+		if lastGoodConfFile == settingConfFile {
+			applyConfigLines(config, lastGoodConfLines)
+		}
+		return fmt.Sprintf("rlog: Config file %s not applied, using previous configuration: %s\n",
+			settingConfFile, joinConfFileErrors(errs))
+	case "WARN":
+		*config = fileConfig
+		return fmt.Sprintf("rlog: Errors in config file %s ignored: %s\n",
+			settingConfFile, joinConfFileErrors(errs))
+	case "IGNORE":
+		*config = fileConfig
+		return ""
+	default:
+		if config.confErrors != "" && !strings.EqualFold(config.confErrors, "REPORT") {
+			rlogIssue("Unknown config file error handling '%s'. Using report.", config.confErrors)
+		}
+		for _, e := range errs {
+			rlogIssue("%s in config file %s:%d. Ignored.", e.msg, settingConfFile, e.line)
+		}
+		*config = fileConfig
+		return ""
+	}
+}
+
+// joinConfFileErrors describes the errors in the config file in one line.
+func joinConfFileErrors(errs []confFileError) string {
+	var lines []string
+	for _, e := range errs {
+		lines = append(lines, fmt.Sprintf("line %d: %s", e.line, e.msg))
+	}
+	return strings.Join(lines, "; ")
+}
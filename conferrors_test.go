@@ -0,0 +1,67 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestConfErrors checks the handling of errors in the config file in the
+// different modes.
+func TestConfErrors(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer func() { lastGoodConfFile, lastGoodConfLines = "", nil }()
+	conf.confFile = writeLogfile([]string{"RLOG_LOG_LEVEL=WARN"})
+	defer os.Remove(conf.confFile)
+	conf.confErrors = "strict"
+	initialize(conf, true)
+	Info("Test Info 1")
+
+	// The previous configuration is kept in strict mode
+	writeConfFile(t, conf.confFile, "RLOG_LOG_LEVEL=DEBUG\nRLOG_LOG_LEVL=DEBUG\n")
+	initialize(conf, true)
+	initialize(conf, true)
+	Info("Test Info 2")
+
+	// The valid lines are used in warn mode
+	conf.confErrors = "warn"
+	initialize(conf, true)
+	Info("Test Info 3")
+
+	// Nothing is reported in ignore mode
+	writeConfFile(t, conf.confFile, "RLOG_LOG_LEVEL=DEBUG\n=\n")
+	conf.confErrors = "ignore"
+	initialize(conf, true)
+	Info("Test Info 4")
+
+	checkLines := []string{
+		"WARN     : rlog: Config file " + conf.confFile + " not applied, using previous configuration: line 2: Unknown or illegal setting name",
+		"WARN     : rlog: Errors in config file " + conf.confFile + " ignored: line 2: Unknown or illegal setting name",
+		"INFO     : Test Info 3",
+		"INFO     : Test Info 4",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// writeConfFile replaces the content of a config file.
+func writeConfFile(t *testing.T, fileName string, content string) {
+	if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
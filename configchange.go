@@ -0,0 +1,72 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+// appliedConfig is the configuration that was applied last, after merging the
+// environment variables, the config file and presets. Protected by initMutex.
+var appliedConfig rlogConfig
+
+// reportedSettings are the settings whose changes are logged when the config
+// file was re-read: Those that determine which messages are logged and where
+// they go.
+var reportedSettings = []struct {
+	name  string
+	value func(c *rlogConfig) string
+}{
+	{"RLOG_LOG_LEVEL", func(c *rlogConfig) string { return c.logLevel }},
+	{"RLOG_TRACE_LEVEL", func(c *rlogConfig) string { return c.traceLevel }},
+	{"RLOG_LEVEL_RULES", func(c *rlogConfig) string { return c.levelRules }},
+	{"RLOG_SAMPLE", func(c *rlogConfig) string { return c.sample }},
+	{"RLOG_LOG_STREAM", func(c *rlogConfig) string { return c.logStream }},
+	{"RLOG_LOG_FILE", func(c *rlogConfig) string { return c.logFile }},
+	{"RLOG_LOG_FORMAT", func(c *rlogConfig) string { return c.logFormat }},
+	{"RLOG_SYSLOG", func(c *rlogConfig) string { return c.syslog }},
+	{"RLOG_COLLECTOR_SOCKET", func(c *rlogConfig) string { return c.collectorSocket }},
+}
+
+// reloadConfig re-reads the config file. If this changed the level filters or
+// the outputs then a notice is written, which lists the changes as fields in
+// the form "old -> new", regardless of the log level. The caller must not hold
+// the lock on initMutex.
+func reloadConfig() {
+	initMutex.RLock()
+	old := appliedConfig
+	initMutex.RUnlock()
+
+	initialize(configFromEnvVars, false)
+
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	var fields []field
+	for _, s := range reportedSettings {
+		oldVal, newVal := s.value(&old), s.value(&appliedConfig)
+		if oldVal != newVal {
+			fields = append(fields, field{key: s.name, kind: fieldString,
+				str: settingValue(oldVal) + " -> " + settingValue(newVal)})
+		}
+	}
+	if len(fields) > 0 {
+		writeNotice(levelInfo, "rlog: Configuration changed\n", fields)
+	}
+}
+
+// settingValue returns the value of a setting for display.
+func settingValue(val string) string {
+	if val == "" {
+		return "(not set)"
+	}
+	return val
+}
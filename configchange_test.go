@@ -0,0 +1,48 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestConfigChangeLogged checks that a message is logged when re-reading the
+// config file changes the configuration, but not when it stays the same.
+func TestConfigChangeLogged(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.confFile = writeLogfile([]string{"RLOG_LOG_LEVEL=WARN"})
+	defer os.Remove(conf.confFile)
+	initialize(conf, true)
+	SetConfCheckInterval(time.Nanosecond)
+	defer SetConfCheckInterval(0)
+
+	Warn("Test Warn 1")
+	writeConfFile(t, conf.confFile, "RLOG_LOG_LEVEL=INFO\nRLOG_TRACE_LEVEL=2\n")
+	time.Sleep(time.Millisecond)
+	Info("Test Info 1")
+	Info("Test Info 2")
+
+	checkLines := []string{
+		"WARN     : Test Warn 1",
+		"INFO     : rlog: Configuration changed RLOG_LOG_LEVEL=\"WARN -> INFO\" RLOG_TRACE_LEVEL=\"(not set) -> 2\"",
+		"INFO     : Test Info 1",
+		"INFO     : Test Info 2",
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// SetConfFileE is like SetConfFile, but reports problems with the config file,
+// so that they can be shown to the user. If the file can't be read, for
+// example because it doesn't exist or because of missing permissions, then
+// the error is returned and the configuration stays unchanged. Errors in the
+// lines of the file are returned as well, but the file is applied
+// nevertheless, as RLOG_CONF_ERRORS demands. An empty name selects the
+// default config file, which doesn't have to exist.
+func SetConfFileE(confFileName string) error {
+	fileName := confFileName
+	if fileName == "" {
+		fileName = defaultConfFile()
+	}
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil && !(confFileName == "" && os.IsNotExist(err)) {
+		return fmt.Errorf("rlog: cannot read config file: %w", err)
+	}
+	SetConfFile(confFileName)
+	if err != nil {
+		return nil
+	}
+
+	var config rlogConfig
+	errs := applyConfigLines(&config, strings.Split(string(content), "\n"))
+	if len(errs) != 0 {
+		return fmt.Errorf("rlog: errors in config file %s: %s", fileName, joinConfFileErrors(errs))
+	}
+	return nil
+}
+
+// SetLogFileE sets the logfile, or a comma separated list of logfiles, as if
+// it was given in RLOG_LOG_FILE. A value for RLOG_LOG_FILE with a '!' in the
+// config file still takes precedence. If one of the files can't be created or
+// opened for writing then the error is returned and the configuration stays
+// unchanged. An empty name switches off the logfile.
+func SetLogFileE(fileNames string) error {
+	for _, fileName := range strings.Split(fileNames, ",") {
+		fileName = strings.TrimSpace(fileName)
+		if fileName == "" {
+			continue
+		}
+		f, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("rlog: cannot open logfile: %w", err)
+		}
+		f.Close()
+	}
+	ensureInitialized()
+	configFromEnvVars.logFile = fileNames
+	initialize(configFromEnvVars, false)
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSetConfFileE checks that problems with the config file are reported.
+func TestSetConfFileE(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer func() { configFromEnvVars.confFile = "" }()
+	initialize(conf, true)
+
+	err := SetConfFileE("/nonexistent/dir/rlog.conf")
+	if err == nil || !os.IsNotExist(errors.Unwrap(err)) {
+		t.Fatalf("Expected error for missing config file, got %v", err)
+	}
+
+	confFile := writeLogfile([]string{"RLOG_LOG_LEVEL=WARN", "RLOG_LOG_LEVL=DEBUG"})
+	defer os.Remove(confFile)
+	err = SetConfFileE(confFile)
+	if err == nil || !strings.Contains(err.Error(), "line 2: Unknown or illegal setting name") {
+		t.Fatalf("Expected error for illegal setting, got %v", err)
+	}
+	Info("Test Info 1")
+	Warn("Test Warning 2")
+
+	writeConfFile(t, confFile, "RLOG_LOG_LEVEL=INFO\n")
+	if err := SetConfFileE(confFile); err != nil {
+		t.Fatal(err)
+	}
+	Info("Test Info 3")
+
+	checkLines := []string{
+		"WARN     : Test Warning 2",
+		"INFO     : Test Info 3",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestSetLogFileE checks that a logfile that can't be opened is reported and
+// not applied.
+func TestSetLogFileE(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	if err := SetLogFileE("/nonexistent/dir/rlog.log"); err == nil {
+		t.Fatal("Should not be able to set unwritable logfile")
+	}
+	Info("Test Info 1")
+
+	otherLogfile := filepath.Join(os.TempDir(), "rlog-test-other.log")
+	defer os.Remove(otherLogfile)
+	if err := SetLogFileE(otherLogfile); err != nil {
+		t.Fatal(err)
+	}
+	Info("Test Info 2")
+	if err := SetLogFileE(logfile); err != nil {
+		t.Fatal(err)
+	}
+	Info("Test Info 3")
+
+	checkLines := []string{
+		"INFO     : Test Info 1",
+		"INFO     : Test Info 3",
+	}
+	fileMatch(t, checkLines, "")
+}
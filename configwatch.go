@@ -0,0 +1,156 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Config describes the effective configuration of rlog, as it is passed to
+// the functions registered with OnConfigChange.
+type Config struct {
+	LogLevel   string // log level filters, as in RLOG_LOG_LEVEL
+	TraceLevel string // trace level filters, as in RLOG_TRACE_LEVEL
+	LogStream  string // name of the log stream, empty for none
+	LogFile    string // name of the logfile, empty for none
+	LogFormat  string // "text", "json", "docker", "glog" or "logfmt"
+}
+
+// configChange is a change of the configuration, which is still to be passed
+// to the functions registered with OnConfigChange.
+type configChange struct {
+	old, new Config
+}
+
+var (
+	// configCallbacks are the functions registered with OnConfigChange.
+	configCallbacks []func(old, new Config)
+	// notifiedConfig is the configuration that was last queued for them.
+	notifiedConfig Config
+	// pendingChanges are the changes that are still to be passed on.
+	pendingChanges []configChange
+	// deliveringChanges is set while a goroutine passes on the changes.
+	deliveringChanges bool
+	// configChangeMutex protects the variables above.
+	configChangeMutex sync.Mutex
+)
+
+// OnConfigChange registers a function, which is called whenever the effective
+// configuration changed, with the previous and the new configuration. This
+// happens when the config file was re-read, the configuration was updated
+// from the environment variables, or it was changed with functions like
+// SetOutput or AddFilter. Subsystems can then adjust their own verbosity in
+// lockstep with rlog. The function is called after the change is complete,
+// so it may log messages.
+func OnConfigChange(fn func(old, new Config)) {
+	ensureInitialized()
+	configChangeMutex.Lock()
+	defer configChangeMutex.Unlock()
+	configCallbacks = append(configCallbacks, fn)
+}
+
+// notifyConfigChange calls the registered functions if the configuration
+// changed since they were last called. The configuration is taken and
+// compared with the last one under the same lock, so that concurrent changes
+// are passed on in the order in which they happened. Only one goroutine at a
+// time calls the functions. Changes that happen meanwhile, for example
+// because a function logs a message, which re-reads the config file, are
+// queued and passed on by that goroutine as well. The caller must not hold
+// the lock on initMutex.
+func notifyConfigChange() {
+	configChangeMutex.Lock()
+	initMutex.RLock()
+	current := currentConfig()
+	initMutex.RUnlock()
+	if current != notifiedConfig {
+		pendingChanges = append(pendingChanges, configChange{old: notifiedConfig, new: current})
+		notifiedConfig = current
+	}
+	if deliveringChanges {
+		configChangeMutex.Unlock()
+		return
+	}
+	deliveringChanges = true
+	for len(pendingChanges) > 0 {
+		change := pendingChanges[0]
+		pendingChanges = pendingChanges[1:]
+		callbacks := configCallbacks
+		configChangeMutex.Unlock()
+		for _, fn := range callbacks {
+			fn(change.old, change.new)
+		}
+		configChangeMutex.Lock()
+	}
+	deliveringChanges = false
+	configChangeMutex.Unlock()
+}
+
+// currentConfig returns the effective configuration. The caller needs to
+// hold at least the read lock on initMutex.
+func currentConfig() Config {
+	c := Config{
+		LogLevel:   logFilterSpec.String(false),
+		TraceLevel: traceFilterSpec.String(true),
+		LogStream:  settingStreamName,
+		LogFile:    currentLogFileName,
+		LogFormat:  "text",
+	}
+	if logWriterStream == nil {
+		c.LogStream = ""
+	}
+	if logWriterFile == nil {
+		c.LogFile = ""
+	}
+	switch settingLogFormat {
+	case formatJSON:
+		c.LogFormat = "json"
+	case formatDocker:
+		c.LogFormat = "docker"
+	case formatGlog:
+		c.LogFormat = "glog"
+	case formatLogfmt:
+		c.LogFormat = "logfmt"
+	}
+	return c
+}
+
+// String returns the filters in the form in which they are configured, for
+// example "client.go=DEBUG,INFO".
+func (spec *filterSpec) String(isTraceLevels bool) string {
+	if spec == nil {
+		return ""
+	}
+	parts := make([]string, len(spec.filters))
+	for i, f := range spec.filters {
+		parts[i] = f.String(isTraceLevels)
+	}
+	return strings.Join(parts, ",")
+}
+
+// String returns the filter in the form in which it is configured, for
+// example "client.go=DEBUG".
+func (f filter) String(isTraceLevel bool) string {
+	level := levelStrings[f.Level]
+	if isTraceLevel {
+		level = strconv.Itoa(f.Level)
+	}
+	if f.Pattern != "" {
+		level = f.Pattern + "=" + level
+	}
+	return level
+}
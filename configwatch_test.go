@@ -0,0 +1,115 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// TestOnConfigChange checks that the registered functions are called when
+// the effective configuration changes, but not otherwise.
+func TestOnConfigChange(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	var changes [][2]Config
+	OnConfigChange(func(old, new Config) {
+		changes = append(changes, [2]Config{old, new})
+		Infof("Level now %s", new.LogLevel)
+	})
+	defer func() { configCallbacks = nil }()
+
+	initialize(conf, true)
+	conf.logLevel = "DEBUG"
+	conf.traceLevel = "2"
+	initialize(conf, true)
+	if err := AddFilter("client.go", LevelWarn); err != nil {
+		t.Fatal(err)
+	}
+	RemoveFilter("client.go")
+
+	if len(changes) != 3 {
+		t.Fatalf("Wrong number of changes: %d", len(changes))
+	}
+	should := Config{LogLevel: "INFO", LogFile: logfile, LogFormat: "text"}
+	if changes[0][0] != should {
+		t.Errorf("Wrong old config: %+v", changes[0][0])
+	}
+	should = Config{LogLevel: "DEBUG", TraceLevel: "2", LogFile: logfile, LogFormat: "text"}
+	if changes[0][1] != should {
+		t.Errorf("Wrong new config: %+v", changes[0][1])
+	}
+	if changes[1][1].LogLevel != "client.go=WARN,DEBUG" {
+		t.Errorf("Wrong log level after AddFilter: %s", changes[1][1].LogLevel)
+	}
+
+	checkLines := []string{
+		"INFO     : Level now DEBUG",
+		"INFO     : Level now client.go=WARN,DEBUG",
+		"INFO     : Level now DEBUG",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestOnConfigChangeOrder checks that concurrent changes, and changes made by
+// a registered function itself, are passed on in order and without gaps.
+func TestOnConfigChangeOrder(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	var changes [][2]Config
+	OnConfigChange(func(old, new Config) {
+		changes = append(changes, [2]Config{old, new})
+		if new.TraceLevel == "1" {
+			// Nested change, which must be passed on after this one.
+			SetOutput(ioutil.Discard)
+		}
+	})
+	defer func() { configCallbacks = nil }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := conf
+			c.traceLevel = strconv.Itoa(i)
+			initialize(c, true)
+		}(i)
+	}
+	wg.Wait()
+
+	if len(changes) == 0 {
+		t.Fatal("No changes passed on")
+	}
+	for i := 1; i < len(changes); i++ {
+		if changes[i][0] != changes[i-1][1] {
+			t.Errorf("Change %d does not follow change %d: %+v, %+v",
+				i, i-1, changes[i][0], changes[i-1][1])
+		}
+	}
+	initMutex.RLock()
+	current := currentConfig()
+	initMutex.RUnlock()
+	if last := changes[len(changes)-1][1]; last != current {
+		t.Errorf("Last change %+v is not the current config %+v", last, current)
+	}
+}
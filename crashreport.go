@@ -0,0 +1,91 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// writeCrashReport writes a crash report file into the configured crash report
+// directory. The report contains the message, build information, the stacks of
+// all goroutines and the most recent log lines. The caller needs to hold at
+// least the read lock on initMutex.
+func writeCrashReport(now time.Time, msg string) {
+	execName := filepath.Base(os.Args[0])
+	fileName := filepath.Join(settingCrashReportDir,
+		fmt.Sprintf("%s-crash-%s-%d.txt", execName,
+			now.Format("20060102T150405.000000000"), os.Getpid()))
+
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		rlogIssue("Unable to create crash report: %s", err)
+		return
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "Crash report for %s (pid %d)\n", execName, os.Getpid())
+	fmt.Fprintf(f, "Time: %s\n\n", now.Format(time.RFC3339Nano))
+	fmt.Fprintf(f, "--- Message ---\n%s\n\n", strings.TrimRight(msg, "\n"))
+
+	fmt.Fprintf(f, "--- Build info ---\n")
+	fmt.Fprintf(f, "Go version: %s\n", runtime.Version())
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(f, "%s\n", bi)
+	}
+
+	fmt.Fprintf(f, "\n--- Goroutines ---\n%s\n", allStacks())
+
+	fmt.Fprintf(f, "--- Recent log entries ---\n")
+	for _, l := range recentEntries.entries() {
+		fmt.Fprintln(f, strings.TrimRight(l, "\n"))
+	}
+}
+
+// panicReportLogger logs the panics that are reported with ReportPanic.
+var panicReportLogger = &Logger{panicReport: true}
+
+// ReportPanic logs a recovered panic as a CRITICAL message, formatted with
+// FormatPanic, and then continues to panic with the same value. If a crash
+// report directory is configured then a crash report is written as well, even
+// if the message is filtered out. It needs to be called directly via defer,
+// for example at the start of main() or of a goroutine:
+//
+//     defer rlog.ReportPanic()
+func ReportPanic() {
+	if r := recover(); r != nil {
+		msg := FormatPanic(r)
+		basicLog(panicReportLogger, levelCrit, notATrace, false, "%s", "", msg)
+		writePanicReport(msg)
+		panic(r)
+	}
+}
+
+// writePanicReport writes the crash report for a panic, if a crash report
+// directory is configured.
+func writePanicReport(msg string) {
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	if settingCrashReportDir != "" {
+		writeCrashReport(currentTime(), msg)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCrashReport checks that a CRITICAL message results in a crash report,
+// which contains the message and the most recent log entries.
+func TestCrashReport(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "rlog-crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf.crashReportDir = dir
+	initialize(conf, true)
+
+	Info("Something before the crash")
+	Critical("Test Critical")
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*-crash-*.txt"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one crash report, found %d", len(files))
+	}
+	content, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(content)
+	for _, s := range []string{
+		"--- Message ---\nTest Critical\n",
+		"INFO     : Something before the crash",
+		"CRITICAL : Test Critical",
+		"TestCrashReport",
+	} {
+		if !strings.Contains(report, s) {
+			t.Fatalf("Crash report does not contain '%s':\n%s", s, report)
+		}
+	}
+}
+
+// TestPanicReport checks that a panic reported with ReportPanic results in
+// exactly one crash report, even if the CRITICAL message is filtered out.
+func TestPanicReport(t *testing.T) {
+	for _, logLevel := range []string{"INFO", "NONE"} {
+		conf := setup()
+		dir, err := ioutil.TempDir("", "rlog-crash")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf.crashReportDir = dir
+		conf.logLevel = logLevel
+		initialize(conf, true)
+
+		func() {
+			defer func() { recover() }()
+			defer ReportPanic()
+			panic("Test Panic")
+		}()
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*-crash-*.txt"))
+		if len(files) != 1 {
+			t.Errorf("Expected one crash report with level %s, found %d", logLevel, len(files))
+		} else if content, _ := ioutil.ReadFile(files[0]); !strings.Contains(string(content), "Test Panic") {
+			t.Errorf("Crash report does not contain the panic:\n%s", content)
+		}
+		os.RemoveAll(dir)
+		cleanup()
+	}
+}
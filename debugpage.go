@@ -0,0 +1,165 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+)
+
+// recentIssuesSize is the number of internal errors that are kept for the
+// debug page.
+const recentIssuesSize = 20
+
+// recentIssues holds the most recent problems reported by rlog itself.
+var recentIssues = &ringBuffer{lines: make([]string, recentIssuesSize)}
+
+// filterCounts holds the number of logged and suppressed messages for a
+// filter.
+type filterCounts struct {
+	logged     uint64
+	suppressed uint64
+}
+
+// DebugHandler returns an HTTP handler for a read-only debug page, which shows
+// the current log and trace levels, the filters with the number of messages
+// they let through and suppressed, the state of the outputs and the most
+// recent errors that rlog encountered itself. It is usually mounted at
+// /debug/rlog:
+//
+//     http.Handle("/debug/rlog", rlog.DebugHandler())
+//
+// Like other debug handlers, it should only be reachable by operators.
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeDebugPage(w)
+	})
+}
+
+// writeDebugPage writes the content of the debug page.
+func writeDebugPage(out io.Writer) {
+	ensureInitialized()
+	initMutex.RLock()
+	c := currentConfig()
+	logFilters := filterRows(logFilterSpec, false)
+	traceFilters := filterRows(traceFilterSpec, true)
+	initMutex.RUnlock()
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "LEVELS\n")
+	fmt.Fprintf(w, "  log level:\t%s\n", orOff(c.LogLevel))
+	fmt.Fprintf(w, "  trace level:\t%s\n", orOff(c.TraceLevel))
+	fmt.Fprintf(w, "  format:\t%s\n", c.LogFormat)
+	fmt.Fprintf(w, "\nLOG FILTERS\tLOGGED\tSUPPRESSED\n")
+	for _, row := range logFilters {
+		fmt.Fprintf(w, "  %s\n", row)
+	}
+	fmt.Fprintf(w, "\nTRACE FILTERS\tLOGGED\tSUPPRESSED\n")
+	for _, row := range traceFilters {
+		fmt.Fprintf(w, "  %s\n", row)
+	}
+
+	fmt.Fprintf(w, "\nOUTPUTS\n")
+	fmt.Fprintf(w, "  stream:\t%s\n", orOff(c.LogStream))
+	fmt.Fprintf(w, "  logfile:\t%s\n", orOff(c.LogFile))
+	fmt.Fprintf(w, "  collector:\t%s\n", collectorClient.status())
+	fmt.Fprintf(w, "  syslog:\t%s\n", syslogClient.status())
+	list, _ := tees.Load().([]*tee)
+	fmt.Fprintf(w, "  tees:\t%d\n", len(list))
+	dropped := DroppedCounts()
+	var parts []string
+	for _, name := range sinkNames {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, dropped[name]))
+	}
+	fmt.Fprintf(w, "  dropped:\t%s\n", strings.Join(parts, " "))
+
+	fmt.Fprintf(w, "\nRECENT INTERNAL ERRORS\n")
+	issues := recentIssues.entries()
+	if len(issues) == 0 {
+		fmt.Fprintf(w, "  none\n")
+	}
+	for _, issue := range issues {
+		fmt.Fprintf(w, "  %s\n", issue)
+	}
+	w.Flush()
+}
+
+// orOff returns the value, or "off" if it is empty.
+func orOff(s string) string {
+	if s == "" {
+		return "off"
+	}
+	return s
+}
+
+// filterRows returns a line for each filter of the spec, with the number of
+// messages that it let through and suppressed, separated by tabs. Every
+// counted file is attributed to the first filter that matches it, since that
+// one decides about its messages. Files that no filter matches are shown in
+// a separate line, since their messages are all suppressed. The caller needs
+// to hold at least the read lock on initMutex.
+func filterRows(spec *filterSpec, isTraceLevels bool) []string {
+	if spec == nil {
+		spec = &filterSpec{}
+	}
+	counts := make([]filterCounts, len(spec.filters)+1)
+	attribute := func(m *sync.Map, suppressed bool) {
+		m.Range(func(k, v interface{}) bool {
+			key := k.(suppressedKey)
+			if (key.traceLevel != notATrace) != isTraceLevels {
+				return true
+			}
+			i := len(spec.filters)
+			for j, f := range spec.filters {
+				if matched, _ := f.match(key.file, 0); matched {
+					i = j
+					break
+				}
+			}
+			n := atomic.LoadUint64(v.(*uint64))
+			if suppressed {
+				counts[i].suppressed += n
+			} else {
+				counts[i].logged += n
+			}
+			return true
+		})
+	}
+	attribute(&loggedCounts, false)
+	attribute(&suppressedCounts, true)
+
+	var rows []string
+	for i, f := range spec.filters {
+		rows = append(rows, fmt.Sprintf("%s\t%d\t%d", f.String(isTraceLevels),
+			counts[i].logged, counts[i].suppressed))
+	}
+	if other := counts[len(spec.filters)]; other.logged+other.suppressed > 0 ||
+		len(spec.filters) == 0 {
+		rows = append(rows, fmt.Sprintf("(no filter)\t%d\t%d", other.logged, other.suppressed))
+	}
+	return rows
+}
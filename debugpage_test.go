@@ -0,0 +1,64 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestDebugHandler checks the content of the debug page.
+func TestDebugHandler(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer func() {
+		loggedCounts = sync.Map{}
+		suppressedCounts = sync.Map{}
+	}()
+	loggedCounts = sync.Map{}
+	suppressedCounts = sync.Map{}
+	conf.logLevel = "debugpage_test.go=WARN,INFO"
+	conf.preset = "nonexistent"
+	initialize(conf, true)
+
+	Info("Test Info 1")
+	Warn("Test Warning 2")
+	Error("Test Error 3")
+
+	rec := httptest.NewRecorder()
+	DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/rlog", nil))
+	page := rec.Body.String()
+	for _, should := range []string{
+		"log level:    debugpage_test.go=WARN,INFO\n",
+		"trace level:  off\n",
+		"debugpage_test.go=WARN  2       1\n",
+		"logfile:    " + logfile + "\n",
+		"Unknown preset 'nonexistent'. Ignored.\n",
+	} {
+		if !strings.Contains(page, should) {
+			t.Errorf("Debug page doesn't contain '%s'", should)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	DebugHandler().ServeHTTP(rec, httptest.NewRequest("POST", "/debug/rlog", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Incorrect status for POST: %d", rec.Code)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// diskCheckInterval is the time between checks of the free space for the
+// logfile.
+const diskCheckInterval = 10 * time.Second
+
+var (
+	// settingMinFreeDisk is the free space, in bytes, below which DEBUG and
+	// TRACE messages are not written to the logfile anymore. 0 for no limit.
+	settingMinFreeDisk uint64
+	// settingLogDir is the directory of the logfile, whose free space is
+	// checked.
+	settingLogDir string
+	// lastDiskCheck is the time of the last check, in Unix nanoseconds.
+	lastDiskCheck int64
+	// diskLow is 1 while the free space is below the limit.
+	diskLow uint32
+)
+
+// parseByteSize interprets a size such as "500MB", "2G" or "4096".
+func parseByteSize(s string) (uint64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	factor := uint64(1)
+	for _, unit := range []struct {
+		suffix string
+		factor uint64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			factor = unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxUint64/factor {
+		return 0, fmt.Errorf("size '%s' is too large", s)
+	}
+	return n * factor, nil
+}
+
+// updateMinFreeDisk applies the value of RLOG_MIN_FREE_DISK. The result of
+// the last check is kept, unless the limit or the directory of the logfile
+// changed, so that the notice about low disk space is not written again with
+// every check of the config file. The caller needs to hold the write lock on
+// initMutex.
+func updateMinFreeDisk(config rlogConfig) {
+	var size uint64
+	var dir string
+	if config.minFreeDisk != "" && config.logFile != "" {
+		var err error
+		size, err = parseByteSize(config.minFreeDisk)
+		if err != nil {
+			rlogIssue("Cannot parse minimum free disk space '%s'. Ignored.", config.minFreeDisk)
+			size = 0
+		} else {
+			dir = filepath.Dir(strings.TrimSpace(strings.Split(config.logFile, ",")[0]))
+		}
+	}
+	if size == settingMinFreeDisk && dir == settingLogDir {
+		return
+	}
+	settingMinFreeDisk = size
+	settingLogDir = dir
+	atomic.StoreInt64(&lastDiskCheck, 0)
+	atomic.StoreUint32(&diskLow, 0)
+}
+
+// skipForDiskSpace returns true if a record is not written to the logfile,
+// because it is a DEBUG or TRACE message and the free space for the logfile
+// is below the limit. The free space is checked at most every
+// diskCheckInterval. Changes are noted in the log. The caller needs to hold
+// at least the read lock on initMutex.
+func skipForDiskSpace(r *logRecord) bool {
+	last := atomic.LoadInt64(&lastDiskCheck)
+	now := time.Now().UnixNano()
+	if now-last >= int64(diskCheckInterval) && atomic.CompareAndSwapInt64(&lastDiskCheck, last, now) {
+		free, ok := freeDiskSpace(settingLogDir)
+		low := ok && free < settingMinFreeDisk
+		if low && atomic.CompareAndSwapUint32(&diskLow, 0, 1) {
+			writeNotice(levelWarn, fmt.Sprintf(
+				"rlog: Only %d MB free for the logfile, DEBUG and TRACE messages are not written to it\n",
+				free>>20), nil)
+		} else if !low && atomic.CompareAndSwapUint32(&diskLow, 1, 0) {
+			writeNotice(levelInfo,
+				"rlog: Enough free space for the logfile again, DEBUG and TRACE messages are written to it\n", nil)
+		}
+	}
+	return r.level >= levelDebug && atomic.LoadUint32(&diskLow) == 1
+}
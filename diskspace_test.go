@@ -0,0 +1,101 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestParseByteSize checks the interpretation of sizes.
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]uint64{
+		"4096":         4096,
+		"10k":          10 << 10,
+		"500MB":        500 << 20,
+		" 2 G ":        2 << 30,
+		"100B":         100,
+		"1.5GB":        0,
+		"99999999999G": 0,
+		"lots":         0,
+	}
+	for s, should := range tests {
+		is, err := parseByteSize(s)
+		if should == 0 && err == nil {
+			t.Errorf("No error for '%s'", s)
+		} else if is != should {
+			t.Errorf("parseByteSize(%q) = %d, should be %d", s, is, should)
+		}
+	}
+}
+
+// TestMinFreeDisk checks that DEBUG messages are not written to the logfile
+// if there is too little free space for it.
+func TestMinFreeDisk(t *testing.T) {
+	if _, ok := freeDiskSpace("/tmp"); !ok {
+		t.Skip("Free disk space can't be determined on this platform")
+	}
+	conf := setup()
+	defer cleanup()
+	conf.logLevel = "DEBUG"
+	conf.minFreeDisk = "1000000000G"
+	initialize(conf, true)
+	Debug("Test Debug 1")
+	Info("Test Info 1")
+
+	conf.minFreeDisk = "1K"
+	initialize(conf, true)
+	Debug("Test Debug 2")
+
+	content, err := ioutil.ReadFile(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 ||
+		!strings.HasPrefix(lines[0], "WARN     : rlog: Only ") ||
+		lines[1] != "INFO     : Test Info 1" ||
+		lines[2] != "DEBUG    : Test Debug 2" {
+		t.Errorf("Wrong logfile content: %q", lines)
+	}
+}
+
+// TestMinFreeDiskNoticeOnce checks that the notice about low disk space is
+// written only once, even if the configuration is applied again.
+func TestMinFreeDiskNoticeOnce(t *testing.T) {
+	if _, ok := freeDiskSpace("/tmp"); !ok {
+		t.Skip("Free disk space can't be determined on this platform")
+	}
+	conf := setup()
+	defer cleanup()
+	conf.logLevel = "DEBUG"
+	conf.minFreeDisk = "1000000000G"
+	initialize(conf, true)
+	Debug("Test Debug 1")
+	initialize(conf, true)
+	atomic.StoreInt64(&lastDiskCheck, 0)
+	Debug("Test Debug 2")
+
+	content, err := ioutil.ReadFile(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(content), "rlog: Only "); n != 1 {
+		t.Errorf("Notice about low disk space written %d times: %q", n, content)
+	}
+}
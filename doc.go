@@ -22,10 +22,13 @@
 // * A new config file can be specified and applied programmatically at any time.
 //
 // * Offers familiar and easy to use log functions for the usual levels: Debug,
-//   Info, Warn, Error and Critical.
+//   Info, Warn, Error and Critical. In addition, Fatal logs at CRITICAL level
+//   and then exits the program.
 //
 // * Offers an additional multi level logging facility with arbitrary depth,
-//   called Trace.
+//   called Trace. With TraceStack, a trace message also shows the stack of the
+//   calling goroutine. TraceAt returns a logger that is bound to a trace level
+//   and, optionally, a topic.
 //
 // * Log and trace levels can be configured separately for the individual files
 //   that make up your executable.
@@ -51,6 +54,20 @@
 //   addition to the output on stderr/stdout. Also, a different output stream
 //   or file can be specified from within your programs at any time.
 //
+// * The stacks of all goroutines can be written to the log when a signal is
+//   received, which helps with diagnosing deadlocks.
+//
+// * Runtime statistics of the process can be logged periodically.
+//
+// * Output can be produced as human readable text, optionally with colored log
+//   levels, or as JSON.
+//
+// * Hooks can be registered to perform custom actions for log messages of a
+//   certain level, such as incrementing a metric.
+//
+// * Log entries can be sent to syslog, with configurable facility and severity
+//   for each log level.
+//
 //
 // DEFAULTS
 //
@@ -98,7 +115,18 @@
 //   that evaluates to 'true' then the message also contains the caller
 //   information, consisting of the process ID, file and line number as well as
 //   function name from which the log message was called. Default: No - meaning
-//   that no caller info is logged.
+//   that no caller info is logged. A comma separated list of
+//   "<file-pattern>=<flag>" settings enables caller info per file, for example
+//   "server.go=yes,no" shows it only for messages from server.go. The first
+//   matching pattern wins and a setting without a pattern applies to all other
+//   files.
+//
+// * RLOG_CALLER_INFO_LEVEL: Messages with this level or a more severe one
+//   always contain the caller info, even if RLOG_CALLER_INFO is not set. For
+//   example, with "ERROR" the locations of all errors are known, without the
+//   cost of collecting caller info for every message. Single messages can
+//   request caller info via rlog.WithCaller(), such as in
+//   rlog.WithCaller().Warn(...). Default: Not set.
 //
 // * RLOG_GOROUTINE_ID: If this variable is set to "1", "yes" or something else
 //   that evaluates to 'true' AND the printing of caller info is requested, then
@@ -106,12 +134,21 @@
 //   ':'. Note that calculation of the goroutine ID has a performance impact, so
 //   please only enable this option if needed.
 //
+// * RLOG_THREAD_ID: If this variable is set to "1", "yes" or something else
+//   that evaluates to 'true' AND the printing of caller info is requested, then
+//   the caller info contains the ID of the OS thread, separated from the process
+//   ID (and goroutine ID, if shown) by a '/'. This helps to correlate messages
+//   with native profilers and debuggers in programs that use cgo. The thread ID
+//   is stable for goroutines that called runtime.LockOSThread(). It is only
+//   available on Linux. Default: No.
+//
 // * RLOG_TIME_FORMAT: Use this variable to customize the date/time format. The
 //   format is specified either by the well known formats listed in
 //   https://golang.org/src/time/format.go, for example "UnixDate" or "RFC3339".
 //   Or as an example date/time output, which is described here:
 //   https://golang.org/pkg/time/#Time.Format Default: Not set - formatted
-//   according to RFC3339.
+//   according to RFC3339. A Logger created with WithTimeFormat() can use its
+//   own format, or no time stamps at all.
 //
 // * RLOG_LOG_NOTIME: If this variable is set to "1", "yes" or something else
 //   that evaluates to 'true' then no date/time stamp is logged with each log
@@ -121,17 +158,371 @@
 //
 // * RLOG_LOG_FILE: Provide a filename here to determine if the logfile should
 //   be written to a file, in addition to the output stream specified in
-//   RLOG_LOG_STREAM. Default: Not set - meaning that output is not written to a
-//   file.
+//   RLOG_LOG_STREAM. If the filename ends in ".gz" then the output is written
+//   gzip compressed. Other formats can be added with RegisterCodec().
+//   Compressed output is flushed to the file at least once per second. Several
+//   files can be given as comma separated list, for example
+//   "/var/log/app.log,/mnt/shared/app.log", to write the output to each of
+//   them. Default: Not set - meaning that output is not written to a file.
 //
 // * RLOG_LOG_STREAM: Use this to direct the log output to a different output
 //   stream, instead of stderr. This accepts three values: "stderr", "stdout" or
 //   "none". If either stderr or stdout is defined here AND a logfile is specified
-//   via RLOG_LOG_FILE then the output is sent to both. Default: Not set -
-//   meaning the output goes to stderr.
-//
-// There are two more settings, related to the configuration file, which can only
-// be set via environment variables.
+//   via RLOG_LOG_FILE then the output is sent to both. The name of a writer
+//   that was registered by the program with rlog.RegisterWriter() may be used
+//   as well. Default: Not set - meaning the output goes to stderr.
+//
+// * RLOG_CRASH_REPORT_DIR: If this variable is set to the name of a directory
+//   then a crash report file is written into this directory every time a
+//   CRITICAL message is logged, or a panic is reported via ReportPanic(). The
+//   report contains the message, build information, the stacks of all goroutines
+//   and the most recent log entries. Default: Not set - meaning that no crash
+//   reports are written.
+//
+// * RLOG_RUNTIME_STATS_INTERVAL: Number of seconds between log messages with
+//   runtime statistics of the process: Number of goroutines, heap usage, garbage
+//   collection counts and pauses and the number of open file descriptors (where
+//   available). This is useful in small deployments without a metrics system.
+//   Default: Not set - meaning that no runtime statistics are logged.
+//
+// * RLOG_RUNTIME_STATS_LEVEL: The log level at which the runtime statistics
+//   are logged. These messages are subject to the normal log level filters and
+//   appear to come from the file 'runtimestats.go', so you can also set a
+//   specific log level for them. Default: INFO.
+//
+// * RLOG_COLLECTOR_SOCKET: The path of the unix socket of a log collector,
+//   which was started by a parent process with StartCollector(). If this is
+//   set then all log entries are also forwarded to that collector. Default: Not
+//   set - meaning that log entries are not forwarded.
+//
+// * LOG_LEVEL and DEBUG: Many platforms and tools set these generic
+//   variables. If neither the environment nor the config file specify
+//   RLOG_LOG_LEVEL then the value of LOG_LEVEL is used as the log level. Common
+//   alternative names, such as "warning" or "fatal", are understood as well. If
+//   LOG_LEVEL isn't set either, but DEBUG is set to "1", "yes" or something else
+//   that evaluates to 'true', then the log level is DEBUG. These can only be set
+//   as environment variables.
+//
+// * RLOG_LOG_FORMAT: Set to "text", "json", "logfmt", "docker" or "glog". With
+//   "json" every log entry is written as a single line JSON object with the
+//   fields "time", "level", "msg" and, if caller info is enabled, "pid",
+//   "goroutine", "caller" and "func". Trace messages have the level "TRACE",
+//   with the trace level in "trace" and, if they were logged with a topic, the
+//   topic in "topic". This is easier to process for log collection systems.
+//   With "logfmt" every entry is a line of key=value pairs, with the same keys
+//   as in JSON output. With "docker" every entry is written like Docker's
+//   json-file logging driver stores the output of a container: As JSON object
+//   with the text line in "log", the name of the log stream in "stream" and the
+//   time stamp in UTC in "time". Tools that parse Docker logs can then consume
+//   rlog's logfiles. With "glog" every entry starts with the line header of
+//   glog: severity letter, date, time with microseconds, process ID, file and
+//   line, followed by "]", for teams that move from glog and whose tools expect
+//   that shape. The file and line are only known with caller info enabled,
+//   which the "glog" preset does. Default: text.
+//
+// * RLOG_COLOR: If this variable is set to "1", "yes" or something else that
+//   evaluates to 'true' then the log levels are shown in color in the text
+//   output on stderr or stdout. Output to the logfile is never colored. Default:
+//   No - meaning that no colors are used.
+//
+// * RLOG_TIME_UTC: If this variable is set to "1", "yes" or something else
+//   that evaluates to 'true' then time stamps are shown in UTC, rather than in
+//   local time. Default: No - meaning that local time is used.
+//
+// * RLOG_PRESET: Set to "dev", "prod" or "glog" to get a sensible bundle of
+//   settings for development or production with a single variable. "dev" means
+//   text output in color with caller info and DEBUG level. "prod" means JSON
+//   output with time stamps in UTC, INFO level and no colors. "glog" means glog
+//   output with caller info, for the migration from glog. A preset only provides
+//   defaults: Any of those settings that are set explicitly take precedence.
+//   Default: Not set - meaning that no preset is used.
+//
+// * RLOG_MAX_LINE_LENGTH: Some transports limit the length of log lines, for
+//   example classic syslog to 1024 bytes, and silently truncate longer lines.
+//   If this is set to a number of bytes then longer log entries are instead
+//   split into several entries, each with a part of the message. The parts are
+//   numbered, for example "[2/3] ". Default: Not set - meaning that long lines
+//   are not split.
+//
+// * RLOG_FATAL_EXIT_CODE: The exit code with which the program is terminated
+//   after a message was logged with Fatal() or Fatalf(). What happens instead of
+//   exiting can be changed programmatically with SetExitFunc(), and
+//   SetFatalPanics() makes them panic instead, which tests can recover.
+//   Default: 1.
+//
+// * RLOG_FATAL_TIMEOUT: Before the program exits, Fatal() and Fatalf() wait
+//   until the message and any output still held in a buffer were written and
+//   the logfile was committed to storage. So that a stuck output, such as a
+//   pipe that nobody reads, can't keep the program from exiting, this waits at
+//   most the given number of seconds. 0 waits without limit. Default: 5.
+//
+// * RLOG_SAMPLE: Throttles noisy programs by sampling and rate limiting log
+//   messages. The format is <level>[:<limit>/s][:1/<n>] and applies to
+//   messages at the given level or of lower severity, including trace messages.
+//   For example, "INFO:100/s:1/10" means that in every second the first 100 of
+//   those messages are logged and after that only one in every 10. With
+//   "DEBUG:1/5" only one in every 5 messages is logged, without rate limit. With
+//   "INFO:50/s" no more than 50 messages per second are logged. Since this can
+//   be set in the config file, a running program can be throttled without a
+//   restart. Default: Not set - meaning that all messages are logged, unless
+//   sampling was enabled with SetSampling().
+//
+// * RLOG_STREAM_BUFFER: Buffers the output to the log stream (stderr or
+//   stdout), which saves a lot of CPU in programs that produce many log lines
+//   and write them to a pipe. "LINE" means that every line is written with a
+//   single write. "SIZE" means that output is only written once 64 KB have
+//   accumulated. A duration, such as "100ms" or "1s", means that output is
+//   written when the buffer is full or at the latest after that time. Buffered
+//   output is written before the program exits via Fatal(), otherwise call
+//   rlog.Flush() before exiting. Default: Not set - meaning that the log
+//   stream is not buffered.
+//
+// * RLOG_K8S_FIELDS: If this variable is set to "1", "yes" or something else
+//   that evaluates to 'true' then the Kubernetes metadata in the environment
+//   variables POD_NAME, POD_NAMESPACE and NODE_NAME is attached to every log
+//   entry as the fields "pod_name", "pod_namespace" and "node_name". Those
+//   variables are conventionally filled via the downward API in the pod spec.
+//   This makes it possible to tell apart the logs of replicated pods after
+//   they were aggregated. Variables that are not set are omitted.
+//   Default: No - meaning that no Kubernetes metadata is attached.
+//
+// * RLOG_SYSLOG: Sends all log entries to syslog as well. Set this to "local"
+//   (or "yes") for the local syslog daemon, or to a network address in the
+//   form "udp:host:port" or "tcp:host:port" for a remote one. The syslog tag is
+//   the name of the executable. Since syslog adds its own time stamps you may
+//   want to set RLOG_LOG_NOTIME as well. Not available on Windows. Default:
+//   Not set - meaning that nothing is sent to syslog.
+//
+// * RLOG_SYSLOG_FACILITY: The syslog facility, for example "DAEMON" or
+//   "LOCAL0" to "LOCAL7". Default: USER.
+//
+// * RLOG_SYSLOG_LEVELS: Different syslog consumers have different
+//   conventions, so the syslog severity for each log level can be changed. For
+//   example, "TRACE=INFO,CRITICAL=ALERT" sends trace messages as INFO and
+//   CRITICAL messages as ALERT. The severities are EMERG, ALERT, CRIT, ERR,
+//   WARNING, NOTICE, INFO and DEBUG. Default: CRITICAL as CRIT, ERROR as ERR,
+//   WARN as WARNING, INFO as INFO, DEBUG and TRACE as DEBUG.
+//
+// * RLOG_FORMAT_STREAM, RLOG_FORMAT_FILE: The output format for only the log
+//   stream or only the logfile, which takes precedence over RLOG_LOG_FORMAT.
+//   Besides "text", "json", "logfmt" and "docker" this may be a template, such
+//   as "{time} {level} {msg} {fields}". The available placeholders are {time},
+//   {level}, {msg}, {fields}, {caller}, {func}, {pid}, {goroutine} and
+//   {thread}. Caller info is only available if RLOG_CALLER_INFO is set. Since
+//   these can be set in the config file, the format of each output can be
+//   changed without a restart. Default: Not set - meaning that the format from
+//   RLOG_LOG_FORMAT is used.
+//
+// * RLOG_QUOTE: Quotes the message in text output, so that messages with
+//   spaces or colons don't confuse column based tools, such as awk or cut. With
+//   "go" the message is quoted like a Go string, which also keeps multi-line
+//   messages on a single line. With "single" the message is put in single
+//   quotes, as a shell would need it. Field values that contain spaces, colons
+//   or quotes are quoted in the same style. Default: Not set - meaning that
+//   messages are not quoted.
+//
+// * RLOG_LEVEL_RULES: Changes the level of messages from matching files,
+//   before the log level filters are applied. This uses the same syntax as the
+//   per file log levels, but with a pair of levels: With
+//   "noisy*.go=ERROR:WARN,db.go=WARN:ERROR", ERROR messages from files whose
+//   names start with "noisy" are logged as WARN, for example because a library
+//   is known to report harmless errors. At the same time, WARN messages from
+//   db.go are logged as ERROR, so that they trigger alerts. A rule without file
+//   pattern applies to all files. Default: Not set - meaning that messages keep
+//   their level.
+//
+// * RLOG_FILE_ORIGIN: If this variable is set to "1", "yes" or something else
+//   that evaluates to 'true' then every entry in the logfile gets additional
+//   fields, which tell where it came from: "pid" with the process ID (unless
+//   caller info already shows it), "stream" with the name of the log stream that
+//   the entry was written to as well, if any, and "seq" with a sequence number.
+//   This keeps the entries attributable when several processes append to the
+//   same logfile. Default: No - meaning that logfile and log stream receive the
+//   same entries.
+//
+// * RLOG_PRINT_LEVELS: Messages logged with Print(), Println() or
+//   Printf(), which exist for compatibility with the standard log package, are
+//   logged at INFO level. If this variable is set to "1" or "yes", then those
+//   that start with a level name followed by a colon or a dash, or enclosed in
+//   brackets, such as "ERROR: ...", "warning - ..." or "[DEBUG] ...", are logged
+//   with that level instead, so that libraries which log through these functions
+//   get the correct levels. Default: No.
+//
+// * RLOG_LOG_FILE_ROTATE: Set to "daily" or "weekly" to start a new logfile
+//   every day or every week, without the need for logrotate. The date, such as
+//   "app-20240613.log", or the ISO week, such as "app-2024-W24.log", is added to
+//   the name given in RLOG_LOG_FILE. That name itself is kept as symlink to
+//   the current logfile, so that tools which tail the log always find it. Days
+//   and weeks start in local time, or in UTC if RLOG_TIME_UTC is set. Default:
+//   Not set - meaning that the logfile is not rotated.
+//
+// * RLOG_STREAM_FALLBACK: If this variable is set to "1", "yes" or something
+//   else that evaluates to 'true' then rlog checks whether the log stream is
+//   closed or redirected to /dev/null, which long-lived daemons sometimes find
+//   themselves with. Then the other one of stdout and stderr is used instead,
+//   or, if that is unusable as well, output only goes to the logfile. A warning
+//   about this is logged once. Default: No.
+//
+// * RLOG_UPTIME_FIELDS: Every CRITICAL entry, including those of Fatal() and
+//   of logged panics, gets the field 'started' with the start time of the
+//   process and 'uptime' with the time it has been running. This shows right
+//   away whether a failure follows a restart or a long runtime. Set this to "0"
+//   or "no" to leave out these fields. Default: Yes.
+//
+// * RLOG_SERVICE_MODE: A Windows service has no console, so anything written
+//   to stderr or stdout is lost. In service mode rlog therefore doesn't use the
+//   log stream and writes to the logfile instead. If no logfile is configured,
+//   a file named after the executable is created in the directory for temporary
+//   files, for example C:\Windows\Temp\myservice.log. Syslog output is not
+//   available on Windows. Set this to "yes" or "no" to switch service mode on or
+//   off explicitly. Default: "auto" - meaning that service mode is used if the
+//   process has no standard error handle, which is only checked on Windows.
+//
+// * RLOG_JOURNAL: If a program is started by systemd and its log stream is
+//   connected to the journal, then the journal records the time of every line
+//   by itself. rlog detects this with the help of the JOURNAL_STREAM
+//   environment variable, which systemd sets, and then leaves out its own time
+//   stamps from the lines written to the stream, so that there is no need to
+//   set RLOG_LOG_NOTIME in the unit file. The logfile keeps its time stamps.
+//   Set this to "yes" or "no" to treat the stream as connected to the journal
+//   or not, regardless of the detection. Default: "auto".
+//
+// * RLOG_PRIORITY_PREFIX: If this variable is set to "1", "yes" or something
+//   else that evaluates to 'true' then every line written to the log stream
+//   starts with the priority of the entry, as defined by the sd-daemon
+//   protocol, for example <3> for ERROR or <6> for INFO. The journal then
+//   records each entry with the right priority, even though the program just
+//   writes to stderr. The logfile is not affected. Default: "auto" - meaning
+//   that the prefixes are used if the stream is connected to the journal (see
+//   RLOG_JOURNAL).
+//
+// * RLOG_MONOTONIC: If this variable is set to "1", "yes" or something else
+//   that evaluates to 'true' then in JSON output every entry also contains
+//   "mono", the number of nanoseconds since the start of the process, measured
+//   with the monotonic clock. Unlike the time stamps, this is not affected by
+//   jumps of the wall clock, for example when NTP steps the time or a virtual
+//   machine was paused, so that durations calculated from the log remain
+//   correct. Default: No.
+//
+// * RLOG_CALLER_SKIP_PREFIXES: A comma separated list of package paths, or
+//   prefixes of function names in general, such as
+//   "github.com/acme/logutil". If a program logs through helper functions
+//   in those packages, then the caller info shows the code that called the
+//   helper, rather than the helper itself, without any changes to the helper.
+//   This also applies to per-file log levels, which match the file of the
+//   code that called the helper. Default: Not set.
+//
+// * RLOG_CANONICAL_TIMES: If this variable is set to "1", "yes" or something
+//   else that evaluates to 'true' then times and durations, which are passed as
+//   arguments to the log functions or as fields, are formatted in canonical
+//   form: Times according to RFC3339, such as "2024-06-13T10:30:00Z", and
+//   durations according to ISO 8601, such as "PT1M30S", instead of Go's
+//   default format. This is easier to process by other tools. In formatted
+//   messages this applies to the verbs %v and %s. Default: No.
+//
+// * RLOG_FIELD_ORDER: Determines the order in which fields are shown in text
+//   output. With "insertion" they are shown in the order in which they were
+//   added to the entry, with "sorted" they are sorted by key, so that lines
+//   with the same fields can be compared easily, for example with golden files
+//   in tests, regardless of how the fields were assembled. JSON output is not
+//   affected. Default: "insertion".
+//
+// * RLOG_IMPLICIT_TRACE: Lets the trace level follow from the global log
+//   level, so that a single setting controls both. The value is a list of log
+//   levels with the trace level that is enabled with them, for example
+//   "DEBUG=2,INFO=0": If the log level is DEBUG then trace messages up to level
+//   2 are shown as well, with INFO only those of level 0. The most verbose of
+//   the enabled log levels applies. This is only used if RLOG_TRACE_LEVEL is
+//   not set. Default: Not set.
+//
+// * RLOG_BANNER: If this variable is set to "1", "yes" or something else that
+//   evaluates to 'true' then rlog writes a banner when it starts logging, once
+//   per process and regardless of the log level. It names the program, its
+//   version, process ID and host, the effective log and trace levels and where
+//   the configuration came from, so that every logfile describes itself:
+//
+//       INFO     : rlog: Started myapp program=myapp version=v1.2.0 pid=4711 host=web1 log_level=INFO trace_level=off config=environment
+//
+//   Default: No.
+//
+// * RLOG_MIN_FREE_DISK: The free space, such as "500MB" or "2GB", below which
+//   DEBUG and TRACE messages are not written to the logfile anymore, so that
+//   verbose logging can't fill up the disk of an appliance. The free space on
+//   the filesystem of the (first) logfile is checked every ten seconds. A
+//   warning is logged when the limit is reached and a notice when there is
+//   enough space again. Other outputs are not affected. This is not supported
+//   on Windows. Default: Not set - meaning that there is no limit.
+//
+// * RLOG_RECENT_ENTRIES: The number of recent log entries that are kept in
+//   memory, so that a program can include them in its own reports, via
+//   RecentEntries(). Crash reports keep at least 100 entries. Default: 0 -
+//   meaning that no entries are kept, unless crash reports are enabled.
+//
+// * RLOG_WRITE_TIMEOUT: The maximum time that writing a log entry to an output
+//   may take, for example "200ms". If a write takes longer then it is abandoned
+//   and the entry is counted as dropped. The timeout can be set for each output
+//   separately, as comma separated list like "stream=100ms,file=2s". The names
+//   of the outputs are "stream", "file", "collector", "syslog" and "tee". A
+//   timeout without name applies to all outputs. Default: Not set - meaning
+//   that writes may take as long as they take.
+//
+// * RLOG_GOROUTINE_CREATOR: A trace level. Trace messages of this level or a
+//   higher one carry the function, file name and line number from which the
+//   logging goroutine was started, in the field 'goroutine_creator'. This helps
+//   to find out where leaked or unknown goroutines come from. The creation site
+//   is taken from the stack of the goroutine, once per goroutine. Default: Not
+//   set - meaning that the creation site is never shown.
+//
+// * RLOG_INTERNAL_OUTPUT: Where rlog writes notices about its own problems,
+//   such as errors in the configuration, lost connections or abandoned writes:
+//   "STDERR", "STDOUT", "NONE" or the name of a file, to which the notices are
+//   appended. This keeps them apart from the messages of the program. Within a
+//   program, the notices can also be sent to any io.Writer with
+//   rlog.SetInternalOutput(), which takes precedence. If either is set then
+//   the notices that rlog otherwise logs along with the messages of the
+//   program go there as well: Errors in the config file, changes of the
+//   configuration, a fallback of the log stream and DEBUG and TRACE messages
+//   that are dropped for lack of disk space. Default: STDERR.
+//
+// * RLOG_INTERNAL_LEVEL: The least severe level of the notices that are
+//   written to the internal output, for example "ERROR". Problems are noted at
+//   WARN level, changes of the configuration at INFO level. Default: Not set -
+//   meaning that all notices are written.
+//
+// * RLOG_MAX_BUFFER_MB: The memory budget in megabytes, such as "4" or "0.5",
+//   for log output that rlog holds in buffers: The buffer of the log stream
+//   (see RLOG_STREAM_BUFFER), the lines that wait to be written to tees and
+//   sinks (see TeeTo and AddSink) and the recent entries that are kept in
+//   memory (see RLOG_RECENT_ENTRIES). The stream buffer gets a quarter of the
+//   budget, but not more than its usual 64 KB, which means that output is
+//   written more often. The queues of all tees and sinks together get another
+//   quarter: A tee or sink for which a line would exceed it is dropped, just
+//   like one that has too many entries waiting. The recent entries get the
+//   rest: If their total size exceeds it, the oldest entries are discarded
+//   earlier than RLOG_RECENT_ENTRIES says, and an entry that is larger than
+//   that by itself is not kept at all. This allows enabling these features in
+//   small containers. Default: Not set - meaning that there is no limit.
+//
+// * RLOG_SAMPLE_REPORT: An interval, such as "10s" or "1m". If messages were
+//   dropped by sampling or rate limiting, then an INFO entry is logged at the
+//   end of every interval for each callsite they came from, with the number of
+//   dropped messages, the callsite and the length of the interval as fields,
+//   for example: Messages dropped by sampling suppressed=1523
+//   callsite=app/poller.go:88 window=10s. This way dashboards can show what
+//   was dropped, rather than it vanishing silently. Default: Not set - meaning
+//   that no such reports are logged.
+//
+// * RLOG_CALLER_WIDTH: The width of the caller info column in text output,
+//   so that the messages line up. Shorter caller info is padded with spaces.
+//   Longer caller info is shortened, first by removing the package path from
+//   the function name, then by cutting off the end, which is marked with
+//   "...". Entries without caller info get an empty column. The width may be
+//   followed by ":left" or ":right" for the alignment, for example "60:right".
+//   Default: Not set - meaning that the caller info takes as much space as it
+//   needs.
+//
+// There are three more settings, related to the configuration file, which can
+// only be set via environment variables.
 //
 // * RLOG_CONF_FILE: If this variable is set then rlog looks for the config
 //   file at the specified location, which needs to be the path of the
@@ -151,6 +542,14 @@
 //   then the configuration from the environment variables is used. Set this value
 //   to 0 in order to switch off the regular config file checking: The config file
 //   will then only be read once at the start.
+// * RLOG_CONF_ERRORS: Determines what happens if the config file contains
+//   malformed lines or unknown settings. By default ("report"), these lines are
+//   skipped and reported on stderr, every time the config file is read. With
+//   "warn" they are skipped as well, but reported once with a WARN message in
+//   the log, including the line numbers, so that a broken config push is
+//   noticed. With "strict" the whole config file is not applied: The previous
+//   version of the file without errors remains in effect and the errors are
+//   reported in the log. With "ignore" the lines are silently skipped.
 //
 // Please note! If these environment variables have incorrect or misspelled
 // values then they will be silently ignored and a default value will be used.
@@ -163,7 +562,7 @@
 // change the logging configuration of a running program from the outside and
 // without having to restart it!
 //
-// When rlog is imported it starts out with the defaults described above. It then
+// When rlog is first used it starts out with the defaults described above. It then
 // takes an initial configuration from environment variables, which may override
 // the default values. Next, it looks for the rlog config file. If it cannot find
 // the config file it will quietly continue without error. If the config file is
@@ -182,6 +581,13 @@
 // SetConfFile() function. An absolute or relative path may be specfied with that
 // function.
 //
+// Programs that embed rlog can also opt out of the config file entirely:
+// DisableConfFile() stops rlog from looking for a config file at all, which is
+// useful in sandboxed environments, where accessing /etc is undesirable. A
+// later call to SetConfFile() enables the config file again. How often the
+// config file is checked for changes can be set with SetConfCheckInterval(),
+// which takes precedence over RLOG_CONF_CHECK_INTERVAL.
+//
 // CONFIG FILE FORMAT
 //
 // The format of the config file is simple. Each setting is referred to by the
@@ -242,6 +648,12 @@
 // You can always just delete the config file to go back to the configuration
 // based solely on environment variables.
 //
+// If re-reading the config file changes the log or trace level, the level rules,
+// sampling or the outputs, rlog logs a single INFO message, regardless of the
+// log level, which lists each changed setting with its old and new value:
+//
+//     INFO     : rlog: Configuration changed RLOG_LOG_LEVEL="WARN -> DEBUG"
+//
 // UPDATING LOGGING CONFIG FROM THE INSIDE: BY MODIFYING YOUR OWN ENVIRONMENT VARIABLES
 //
 // A running program may also change its rlog configuration on its own: The
@@ -254,9 +666,61 @@
 //     os.Setenv("RLOG_LOG_LEVEL", "DEBUG")
 //     rlog.UpdateEnv()
 //
+// rlog.ReinitializeFromEnv() does the same. It is meant for orchestration
+// agents or supervisors that update the environment of the process and then
+// trigger a refresh, for example from a signal handler.
+//
 // Note that this will not change rlog behaviour if the value for this config
 // setting was specified with a '!' in the config file.
 //
+// The configuration is not read when rlog is imported, but only when the first
+// message is logged or rlog is configured otherwise. Therefore, a program (or
+// TestMain in your tests) can set RLOG_* variables with os.Setenv() before
+// using rlog, without having to call rlog.UpdateEnv(). To start over with a
+// clean slate, rlog.Reset() discards all settings that were made with
+// functions like SetOutput() or SetSampling() and reads the environment
+// variables and the config file again.
+//
+// SetOutput() directs all output to a single io.Writer, until the configuration
+// is updated. To redirect only the log stream, for example into a widget of a
+// GUI, use SetStreamOutput(). The configured logfile then keeps working.
+// Likewise, SetFileOutput() only replaces the logfile. Both remain in effect
+// when the configuration is updated.
+//
+// SetConfFile() and the environment variables don't report problems, because
+// rlog just keeps logging as well as it can. To show configuration errors to
+// the user, for example of a config file given on the command line, use
+// SetConfFileE() and SetLogFileE() instead. They return an error if the
+// config file can't be read, if it contains errors, or if the logfile can't be
+// created or opened for writing. Only a config file with errors is applied
+// nevertheless, as RLOG_CONF_ERRORS demands.
+//
+// To show the live log in addition to the configured outputs, for example in
+// the session of an admin tool, rlog.TeeTo() adds a writer, which receives
+// all following entries until the returned function is called:
+//
+//     restore := rlog.TeeTo(conn)
+//     defer restore()
+//
+// The entries are written to it in the background. Once a write to it fails,
+// for example because the connection was closed, or once it falls too far
+// behind, nothing more is written to it.
+//
+// REACTING TO CHANGES OF THE CONFIGURATION
+//
+// Other parts of a program may want to follow changes of the logging
+// configuration, for example to make a metrics sampler more verbose along with
+// rlog. rlog.OnConfigChange() registers a function, which is called with the
+// previous and the new effective configuration whenever it changes, no matter
+// whether the config file was re-read, the environment variables were applied
+// again or a function like rlog.AddFilter() was called:
+//
+//     rlog.OnConfigChange(func(old, new rlog.Config) {
+//         sampler.SetVerbose(new.TraceLevel != "")
+//     })
+//
+// The function is called after the change is complete, so it may log messages.
+//
 //
 // PER FILE LEVEL LOG AND TRACE LEVELS
 //
@@ -311,6 +775,600 @@
 // trace level is specified then -1 (no trace output) is assumed as the global
 // trace level.
 //
+// Filters for single files can also be added and removed by the program at
+// runtime, for example on request of an admin tool, without building a new
+// filter string:
+//
+//     rlog.AddFilter("client.go", rlog.LevelDebug)
+//     ...
+//     rlog.RemoveFilter("client.go")
+//
+// Filters added this way take precedence over the configured ones and are kept
+// when the configuration is re-read.
+//
+// Patterns that contain a '/' are matched against the last directory and the
+// name of the file, rather than just its name, so that files with the same name
+// in different directories can be told apart. This applies to RLOG_LEVEL_RULES
+// as well:
+//
+//     export RLOG_LOG_LEVEL=INFO,agent/*=DEBUG
+//
+// The filters may also select Go packages. Messages logged through the Logger
+// returned by rlog.ForPackage() are attached to the package from which it is
+// called, and a pattern with a '/' applies to that package and all packages
+// below it:
+//
+//     export RLOG_LOG_LEVEL=INFO,github.com/example/app/db=DEBUG
+//
+//     // In package github.com/example/app/db/pool
+//     rlog.ForPackage().Debug("Connection returned to pool")
+//
+// The package's path is also shown in the field 'logger' of those messages.
+// ForPackage() caches the Logger for each call site, so it is cheap to call
+// wherever a message is logged.
+//
+//
+// COUNTING SUPPRESSED MESSAGES
+//
+// To tune the log and trace level filters knowingly, it helps to know how many
+// messages they actually suppress. SuppressedCounts() returns the number of
+// suppressed messages so far, by file and level:
+//
+//     {"rlog/example.go": {"DEBUG": 1200000, "TRACE(3)": 512}}
+//
+// Trace messages are only counted if trace output is enabled for at least one
+// file. The counts can be published as metrics, for example with expvar:
+//
+//     expvar.Publish("rlog_suppressed", expvar.Func(func() interface{} {
+//         return rlog.SuppressedCounts()
+//     }))
+//
+//
+// GOROUTINE STACK DUMPS
+//
+// When a process appears to hang it is often useful to see what all of its
+// goroutines are doing. By default, the Go runtime prints all stacks to stderr
+// and terminates the process when it receives SIGQUIT. With
+// HandleStackDumpSignal() you can instead have the stacks written through rlog,
+// so that they are time stamped and end up in the same outputs as all your other
+// log messages. The process continues to run.
+//
+//     // Write all goroutine stacks to the log whenever SIGQUIT is received
+//     rlog.HandleStackDumpSignal(syscall.SIGQUIT)
+//
+// Each goroutine's stack is written as a separate INFO message. The dump is
+// always written, regardless of the configured log level.
+//
+//
+// CRASH REPORTS
+//
+// If RLOG_CRASH_REPORT_DIR is set then every CRITICAL message results in a
+// crash report file in that directory, named
+// <your-executable-name>-crash-<time>-<pid>.txt. These files are self contained
+// and can easily be attached to support requests.
+//
+// Panics can be included as well. Just defer ReportPanic() at the start of main
+// or of your goroutines. It logs the panic as a CRITICAL message and then
+// continues to panic. The crash report for a panic is written even if the
+// message is filtered out:
+//
+//     func main() {
+//         defer rlog.ReportPanic()
+//         ...
+//     }
+//
+// Panics are formatted with FormatPanic(), which names the kind of panic (runtime
+// error, error or other value) and adds the stack of the panicking goroutine.
+// You can use it in your own recover handlers as well, so that all panics
+// look the same in the log.
+//
+// Crash handlers of your own, or the support bundles of your program, can
+// include the recent log as well. rlog.RecentEntries(n, minLevel) returns the
+// last n entries with the given level or a more severe one as Entry values,
+// oldest first. The number of entries that are kept in memory is set with
+// RLOG_RECENT_ENTRIES:
+//
+//     for _, e := range rlog.RecentEntries(20, rlog.LevelWarn) {
+//         bundle.AddLogEntry(e.Time, e.Level, e.Message)
+//     }
+//
+//
+// MERGING THE LOGS OF SEVERAL PROCESSES
+//
+// When a program starts child processes, for example as a test harness or with a
+// pool of workers, it is often easier to have the log output of all of them in a
+// single place. For this, the parent process can start a log collector, which
+// listens on a unix socket. Child processes that use rlog and have the
+// RLOG_COLLECTOR_SOCKET environment variable set to the socket path forward
+// their log entries to the collector, which writes them to the outputs of the
+// parent, in the order in which they arrive.
+//
+//     if err := rlog.StartCollector("/tmp/myapp-log.sock"); err != nil {
+//         rlog.Error("Cannot start log collector:", err)
+//     }
+//     cmd := exec.Command("worker")
+//     cmd.Env = append(os.Environ(), "RLOG_COLLECTOR_SOCKET=/tmp/myapp-log.sock")
+//
+// Child processes may want to set RLOG_LOG_STREAM=none as well, so that
+// their messages don't appear twice.
+//
+//
+// CAPTURING THE OUTPUT OF EXTERNAL PROGRAMS
+//
+// If your program runs external tools, their output can be made part of your
+// log with CaptureCmd(). Every line the command writes becomes a separate log
+// message, prefixed with the name of the program. Output on stdout is logged at
+// the specified level, output on stderr at WARN (or at the specified level, if
+// that is more severe):
+//
+//     cmd := exec.Command("git", "pull")
+//     rlog.CaptureCmd(cmd, rlog.LevelInfo)
+//     err := cmd.Run()
+//
+// The messages are filtered and show caller info as if they were logged from
+// the place where CaptureCmd() was called.
+//
+//
+// SAMPLING
+//
+// Very chatty log messages can be thinned out with SetSampling(). For
+// example, this only logs one in every 10 messages at INFO level or of lower
+// severity (DEBUG and trace messages):
+//
+//     rlog.SetSampling(rlog.LevelInfo, 10)
+//
+// Randomly dropping individual messages makes it hard to follow what happened
+// to a particular request. Therefore, the sampling decision can instead be made
+// per request: Messages that are logged through a Logger with a sample key,
+// such as a request or trace ID, are either all logged or all dropped, depending
+// on a hash of that key. A rate limit set with RLOG_SAMPLE still applies to
+// them:
+//
+//     log := rlog.WithSampleKey(traceID)
+//     log.Info("Request received")
+//     log.Debugf("Looking up user %s", user)
+//
+// A Logger offers the same log functions as the rlog package itself.
+//
+//
+// VERBOSITY FLAGS IN COMMAND LINE PROGRAMS
+//
+// Command line programs often let the user increase the verbosity with repeated
+// '-v' flags. ApplyVerbosity() translates such a count into rlog settings: 0
+// keeps the default INFO level, 1 enables DEBUG and every additional count
+// enables one more trace level. For example, '-vvv' results in DEBUG with trace
+// level 2:
+//
+//     rlog.ApplyVerbosity(verboseCount)
+//
+// The levels are applied as if they were set via environment variables, so
+// values in the config file that are marked with '!' still take precedence.
+//
+//
+// EVENT CODES
+//
+// Message texts tend to change between releases, which makes them a poor thing
+// to search for in support cases. Instead, messages can be registered in a
+// catalog of events, each with a stable numeric code, a level and a message
+// template (a format string as used by Printf):
+//
+//     rlog.RegisterEvent(1042, rlog.LevelWarn, "Disk %s is %d%% full")
+//
+// Such an event is then logged by its code, with the arguments for the template:
+//
+//     rlog.Event(1042, "/dev/sda", 95)
+//
+// The code is attached to the log entry as the field 'event':
+//
+//     WARN     : Disk /dev/sda is 95% full event=1042
+//
+// In JSON output, fields are added as members of the JSON object.
+//
+//
+// TYPED FIELDS
+//
+// For structured logging in hot code paths, log entries can be assembled with
+// typed fields. The values are formatted without the use of reflection:
+//
+//     rlog.Ev().Str("user", u).Int("n", 3).Dur("took", d).Msg("imported")
+//     rlog.Ev().Level(rlog.LevelWarn).Err(err).Msgf("Import of %s failed", name)
+//
+// Entries are logged at INFO level, unless a different level is chosen with
+// Level(). In text output the fields are appended to the message as
+// key=value pairs, in JSON output they are added as members of the JSON object.
+// Maps, slices and structs passed to Any() are encoded as JSON objects and
+// arrays, so nested data can still be queried. Structs are encoded as by
+// encoding/json. Nesting is limited to 8 levels and maps and slices to 100
+// elements, the rest is left out.
+//
+// Values that are expensive to compute can be given as function, which is only
+// called if the entry is actually logged, and then only once:
+//
+//     rlog.Ev().Level(rlog.LevelDebug).Lazy("state", func() interface{} {
+//         return dumpState()
+//     }).Msg("Cache miss")
+//
+// The same is done for functions of the type func() interface{} that are passed
+// to Any().
+//
+// Message templates let a single call feed both humans and machines. The named
+// placeholders in the template are replaced with the values of the args in the
+// message, and the args are added as fields:
+//
+//     rlog.InfoT("user {user} logged in from {ip}", rlog.Args{"user": u, "ip": ip})
+//
+// This logs "user bob logged in from 10.0.0.1 user=bob ip=10.0.0.1" in text
+// output. There are DebugT(), InfoT(), WarnT(), ErrorT() and CriticalT(), for
+// the package and for a Logger. Placeholders without value are kept as they
+// are, and "{{" and "}}" stand for a literal "{" and "}".
+//
+//
+// ERRORS AS FIELDS
+//
+// When moving from logging with fmt.Errorf()-style messages, errors can keep
+// their structure. If a formatted log function, such as rlog.Errorf(), is given
+// a %w verb, then the error for it is formatted like with %v, and also
+// attached to the entry as field 'error'. If the error wraps other errors, their
+// messages are attached as list in the field 'error_chain'. The same is done for
+// an error that is passed as last argument, without a verb for it in the format
+// string. If the entry already has a field 'error', then the fields 'error_2' and
+// 'error_2_chain' are used instead:
+//
+//     rlog.Errorf("Startup failed: %w", err)
+//     rlog.Errorf("Unable to open %s", fileName, err)
+//
+//
+// REQUEST IDS
+//
+// Programs without a tracing framework can still correlate the messages of a
+// request. rlog.RequestIDMiddleware() wraps an HTTP handler, so that every
+// request gets a request ID: The one from the X-Request-ID header of the
+// request, or a new one from rlog.NewRequestID(). The ID is returned in the
+// X-Request-ID header of the response. The handler finds a Logger in the
+// context of the request, which adds the ID as field 'request_id' to every
+// message:
+//
+//     http.Handle("/", rlog.RequestIDMiddleware(http.HandlerFunc(serve)))
+//
+//     func serve(w http.ResponseWriter, r *http.Request) {
+//         rlog.FromContext(r.Context()).Infof("Serving %s", r.URL.Path)
+//         ...
+//     }
+//
+// To propagate the ID to other services, set the X-Request-ID header of
+// outgoing requests to rlog.RequestID(r.Context()). Any Logger can be passed
+// along in a context with rlog.NewContext().
+//
+// For debugging, rlog.BodyDumpMiddleware() logs the bodies of requests and
+// responses as trace messages, instead of calls of httputil.DumpRequest()
+// behind ad-hoc flags. It is given the trace level of the messages and the
+// number of bytes of each body that are logged:
+//
+//     handler = rlog.RequestIDMiddleware(rlog.BodyDumpMiddleware(5, 1024, handler))
+//
+// Bodies are only captured while that trace level is enabled for the file name
+// "rlog/bodydump.go", for example with RLOG_TRACE_LEVEL=bodydump.go=5, so the
+// middleware costs next to nothing otherwise. Text is quoted, so that each
+// body stays on one line, while bodies that aren't text are only logged with
+// their length. The messages are logged via the Logger of the request's
+// context, so they carry the request ID.
+//
+//
+// GROUPING RELATED MESSAGES
+//
+// The messages of a multi-step operation, such as a database migration, can
+// be tied together with rlog.Group(). It returns a Logger, which adds the
+// fields 'group' and 'group_id' to every message, where the ID is random and
+// different for every group. Log backends can then collapse or query the
+// messages of the group as a unit:
+//
+//     g := rlog.Group("migration 42")
+//     g.Info("Copying table")
+//     ...
+//     g.Done()
+//
+// A begin marker is logged at INFO level when the group is started, and an
+// end marker when Done() is called, with the time since the start in the
+// field 'duration'. A group may also be started from any other Logger, with
+// Logger.Group(), in which case its messages carry the fields of that
+// Logger as well, for example the request ID.
+//
+//
+// HOOKS
+//
+// Hooks let you attach custom side effects to log messages, for example to
+// increment a metric, trip a circuit breaker or push a message to an alerting
+// system, without changing the output of rlog. A hook is called for every
+// message with the given level or a more severe one:
+//
+//     rlog.AddHook(rlog.LevelError, func(e rlog.Entry) {
+//         errorCount.Inc()
+//     })
+//
+// The Entry contains the time, level, message, fields and caller of the message.
+// Use rlog.LevelTrace to see all messages, including trace messages. Hooks are
+// called synchronously, so they should return quickly, and they must not log
+// messages via rlog themselves.
+//
+// Hooks can also form a processing pipeline, which redacts, enriches
+// or reroutes messages. Entry.Clone() returns a copy of an entry that can be
+// modified without affecting other hooks. A hook that is added with
+// AddEmitHook() receives an Emitter, whose EmitTo() logs an entry as if it
+// had been logged via the given Logger, with the time, level, message, fields
+// and caller of the entry:
+//
+//     rlog.AddEmitHook(rlog.LevelTrace, func(e rlog.Entry, em rlog.Emitter) {
+//         if _, ok := e.Fields["password"]; ok {
+//             c := e.Clone()
+//             c.Fields["password"] = "***"
+//             em.EmitTo(auditLogger, c)
+//         }
+//     })
+//
+// Outside of hooks, Logger.Emit() does the same. Emitted entries pass the
+// level and trace filters for their file, but don't run any hooks, so that a
+// pipeline can't loop.
+//
+//
+// GROUPING ALERTS
+//
+// When log messages are forwarded to an alerting system, an error that is logged
+// over and over should not page you once per log line. Every Entry passed to a
+// hook has a Fingerprint(), which is computed from the call site and the
+// message, ignoring all words that contain digits, such as numbers, IDs or
+// addresses. GroupAlerts() uses this to group repeated occurrences into one
+// incident:
+//
+//     rlog.AddHook(rlog.LevelError, rlog.GroupAlerts(time.Minute, sendAlert))
+//
+// The first occurrence of an error is passed to sendAlert immediately, with a
+// count of 1. Further occurrences with the same fingerprint within the time
+// window are only counted. At the end of the window the most recent of them is
+// sent once, together with the number of occurrences it stands for.
+//
+//
+// PREDICTABLE OUTPUT IN TESTS
+//
+// Time stamps make it hard to compare log output with the expected output in
+// tests. The clock that provides the time stamps can be replaced:
+//
+//     start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+//     rlog.SetClock(func() time.Time { return start })
+//     defer rlog.SetClock(nil)
+//
+// The clock also drives sampling and rate limiting, so those can be tested
+// without waiting.
+//
+// For comparing the complete log output of a test with a golden file, rlog
+// offers a test mode:
+//
+//     rlog.SetTestMode(true)
+//     defer rlog.SetTestMode(false)
+//
+// In test mode all time stamps are 2000-01-01T00:00:00Z in UTC, unless a
+// different clock is set with SetClock() afterwards. Process IDs and goroutine
+// IDs are not shown. Caller info only consists of the file name and the function
+// name without the package path, and no line numbers, so that the output doesn't
+// change whenever the code is edited.
+//
+//
+// WORKING WITH LOG/SLOG
+//
+// With Go 1.21 or newer, rlog can be combined with the standard log/slog
+// package in either direction, which allows a gradual migration.
+//
+// Code that uses slog can write through rlog, so that its output is configured
+// and formatted like all other rlog output. Attributes become fields, and groups
+// are written as "group.key":
+//
+//     logger := slog.New(rlog.NewSlogHandler())
+//     logger.Info("Request done", "status", 200)
+//
+// The other way around, SetSlogBackend() passes all rlog entries to an
+// existing slog.Handler, instead of writing them to rlog's own outputs. rlog
+// still decides which messages are logged. CRITICAL messages arrive with a level
+// above slog's ERROR and trace messages with a level below slog's DEBUG, plus a
+// "trace" attribute:
+//
+//     rlog.SetSlogBackend(slog.NewJSONHandler(os.Stdout, nil))
+//
+//
+// COMPRESSED LOGFILES
+//
+// If the name of the logfile ends in ".gz" then the output is written gzip
+// compressed. To avoid having a dependency on compression packages, rlog only
+// has gzip built in, but other formats, such as zstd or lz4, can be plugged in
+// with rlog.RegisterCodec(). A codec creates a writer that compresses into the
+// logfile. The writers of the common zstd and lz4 packages can be used directly:
+//
+//     rlog.RegisterCodec(".zst", rlog.CodecFunc(
+//         func(w io.Writer) (rlog.CompressWriter, error) {
+//             return zstd.NewWriter(w)
+//         }))
+//     rlog.UpdateEnv()
+//
+// Each time the logfile is opened a new compressed stream is appended to it, so
+// the format needs to support concatenated streams. Unlike a plain logfile, a
+// compressed one is only opened again when its name changes, not every time the
+// configuration is re-read. Codecs should be registered before the
+// configuration is applied, which is why UpdateEnv() is called above. Logfiles
+// that are already open remain as they are.
+//
+//
+// WRITE TIMEOUTS
+//
+// Normally, writing a log entry takes no noticeable time. But if an output gets
+// stuck, for example a pipe whose reader doesn't read, a logfile on an
+// unresponsive NFS server or the network connection to syslog, then every log
+// call blocks. RLOG_WRITE_TIMEOUT bounds the time a write to each output may
+// take. Once that has passed, the write is abandoned and the entry is counted
+// as dropped. It may still be written later, if the output recovers. While
+// the output is stuck, further entries for it are dropped right away. The other
+// outputs are not affected. Time critical code can also use a Logger with its
+// own timeout, which replaces the configured ones:
+//
+//     log := rlog.WithWriteTimeout(10 * time.Millisecond)
+//     log.Info("Order received")
+//
+// rlog.DroppedCounts() returns the number of dropped entries for each output,
+// which can be published as metric, just like the counts of suppressed
+// messages.
+//
+//
+// CLOCKS OF INDIVIDUAL OUTPUTS
+//
+// Some outputs need time stamps from a different time source than the rest,
+// for example an audit log that has to use a trusted clock, or a clock that is
+// corrected for a known offset. rlog.SetOutputClock() replaces the clock for
+// one output, while the other outputs keep using the clock of the process, or
+// the one set with SetClock():
+//
+//     rlog.SetOutputClock("file", func() time.Time {
+//         return time.Now().Add(trustedOffset)
+//     })
+//
+// The names of the outputs are "stream", "file", "collector", "syslog" and
+// "tee", as in RLOG_WRITE_TIMEOUT. The clock of an output is called when an
+// entry is written to it. Setting nil goes back to the default clock.
+//
+//
+// DEBUG PAGE
+//
+// rlog.DebugHandler() returns an HTTP handler for a read-only page with the
+// state of the logging in a running program. It is usually mounted at
+// /debug/rlog, next to the other debug handlers:
+//
+//     http.Handle("/debug/rlog", rlog.DebugHandler())
+//
+// The page shows the current log and trace levels and the output format. For
+// every filter it shows how many messages it let through and how many it
+// suppressed. Each file is counted for the first filter that matches it, since
+// that one decides about its messages. The page also shows the state of the
+// outputs, with the number of entries that were dropped because of write
+// timeouts, and the most recent errors that rlog encountered itself, such as
+// invalid settings. Together with OnConfigChange() this gives a full view of
+// what the logging does. Like other debug handlers, it should only be reachable
+// by operators.
+//
+//
+// LOGGING IN EMERGENCIES
+//
+// The normal log functions format messages, allocate memory and take locks,
+// which isn't safe everywhere: A crash handler may run while another goroutine
+// holds a lock of rlog, and the initialization of a package may run before
+// rlog is configured. For such cases, rlog.EmergencyWrite() writes a
+// preformatted message directly to the log stream and the logfile:
+//
+//     var crashMsg = []byte("worker crashed, restarting\n")
+//     ...
+//     rlog.EmergencyWrite(crashMsg)
+//
+// It doesn't allocate, format, take any lock or read the configuration, so the
+// message is not filtered, decorated or sent to other outputs, and it is
+// written ahead of any output that is still held in a buffer. Only outputs that
+// are plain files, such as stderr, are written to. If there are none, the
+// message goes to stderr.
+//
+//
+// SHUTTING DOWN
+//
+// Servers usually have a shutdown sequence, which is started by a signal or by
+// cancelling a context. rlog.ShutdownContext() wires the logging into it: Once
+// the context is cancelled, all buffered output is written, the logfile is
+// committed to storage and closed, and the connections to syslog and to a
+// collector are closed. The returned channel is closed when this is done:
+//
+//     ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//     defer stop()
+//     loggingDone := rlog.ShutdownContext(ctx)
+//     ...
+//     <-loggingDone
+//
+// This is given up to five seconds. To use a different deadline, call
+// rlog.Shutdown() with a context of your own instead. Messages logged after
+// the shutdown still go to the log stream.
+//
+//
+// PARSING LOG OUTPUT
+//
+// Tools that process log files, and tests that check them, can use
+// rlog.ParseLine() to turn a line of rlog output back into an Entry, rather
+// than using regular expressions of their own:
+//
+//     e, err := rlog.ParseLine(line)
+//     if err == nil && e.Level <= rlog.LevelError {
+//         fmt.Println(e.Time, e.File, e.Line, e.Message)
+//     }
+//
+// Lines in text, JSON and Docker format are understood. In text format, the
+// key=value pairs at the end of a line are taken as fields, with string values.
+// Time stamps are parsed with the time format that is currently configured, or
+// with one of the well known formats. Lines that were not written by rlog return
+// the error rlog.ErrNotALogLine.
+//
+//
+// PERFORMANCE
+//
+// Log calls for messages that are not logged, because of their level or trace
+// level, don't allocate any memory. The location of each call site is resolved
+// only once and then cached. The subpackage benchmarks measures the cost of
+// disabled and filtered messages, as well as of entries in text and JSON output
+// and with caller info:
+//
+//     go test -bench . -benchmem github.com/romana/rlog/benchmarks
+//
+// The package also defines the targets for each benchmark as constants. Its
+// tests fail if a log call allocates more than its target allows. The time per
+// call is only checked against the targets if RLOG_BENCH_TIMING is set, since
+// it depends on the machine.
+//
+//
+// VERSION 2 API
+//
+// The package github.com/romana/rlog/v2 offers the functionality of rlog
+// through a smaller set of types. It is built on this package, which keeps its
+// functions, so that existing programs work unchanged. Both packages write to the
+// same outputs and read the same environment variables and config file, so they
+// can be used in the same program while code is moved to the new API.
+//
+// Loggers are created with options, and settings for the whole program are
+// applied with Configure:
+//
+//     import rlog "github.com/romana/rlog/v2"
+//
+//     err := rlog.Configure(rlog.FileOutput("/var/log/myapp.log"))
+//     log := rlog.New(rlog.WithSampleKey(requestID), rlog.WithCaller())
+//     log.Infof("Serving %s", path)
+//
+// The types Option and Setting, and the functions New, With,
+// Configure and the settings that are passed to it, only exist in v2. The
+// WithSampleKey, WithTimeFormat, WithCaller and WithWriteTimeout
+// functions of this package return a Logger, while those of v2 return an
+// Option.
+//
+// A Formatter turns an Entry into the line that is written to an output.
+// With SetStreamFormatter and SetFileFormatter, or the v2 settings
+// StreamFormatter and FileFormatter, it replaces the format that is
+// configured for the log stream or the logfile:
+//
+//     rlog.SetStreamFormatter(rlog.FormatterFunc(func(e rlog.Entry) []byte {
+//         return []byte(e.Level.String() + " " + e.Message)
+//     }))
+//
+// A Sink receives the log entries in addition to the configured outputs,
+// together with their formatted lines. WriterSink turns an io.Writer into a
+// sink. Sinks are added with AddSink, optionally only for the entries of a
+// level or a more severe one, and with a formatter of their own:
+//
+//     sink := rlog.WriterSink(conn)
+//     rlog.AddSink(sink, rlog.SinkLevel(rlog.LevelWarn))
+//     ...
+//     rlog.RemoveSink(sink)
+//
+// The entries are queued for each sink, so that a slow sink doesn't hold up the
+// log calls. A sink that can't keep up, or whose WriteEntry returns an error,
+// is removed. In v2, AddSink and RemoveSink are settings for Configure.
+//
 //
 // USAGE EXAMPLE
 //
@@ -0,0 +1,72 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// rawStream is the writer of the log stream without any buffer in front of
+// it, or nil if there is no log stream. Protected by initMutex.
+var rawStream io.Writer
+
+// emergencyFiles holds the files to which EmergencyWrite writes, as a slice
+// of *os.File. It is read without any lock.
+var emergencyFiles atomic.Value
+
+// EmergencyWrite writes a preformatted message, including its trailing
+// newline, directly to the log stream and the logfile. It is meant for
+// contexts in which the normal log functions are unsafe, for example in a
+// crash handler while another goroutine may hold a lock of rlog, or during
+// the initialization of a package before rlog is configured. EmergencyWrite
+// doesn't allocate, format, take any lock or read the configuration, so the
+// message is not filtered, decorated or sent to other outputs, and it is
+// written ahead of any output that is still held in a buffer. Only outputs
+// that are plain files, such as stderr, are written to. If there are none,
+// for example before rlog was initialized, the message goes to stderr.
+func EmergencyWrite(msg []byte) {
+	files, _ := emergencyFiles.Load().([]*os.File)
+	if len(files) == 0 {
+		os.Stderr.Write(msg)
+		return
+	}
+	for _, f := range files {
+		f.Write(msg)
+	}
+}
+
+// updateEmergencyFiles determines the files to which EmergencyWrite writes,
+// which are the log stream and the logfiles, as far as they are plain files.
+// The caller needs to hold the write lock on initMutex.
+func updateEmergencyFiles() {
+	var files []*os.File
+	if f, ok := rawStream.(*os.File); ok {
+		files = append(files, f)
+	}
+	switch lf := currentLogFile.(type) {
+	case *os.File:
+		files = append(files, lf)
+	case multiLogFile:
+		for _, w := range lf {
+			if f, ok := w.(*os.File); ok {
+				files = append(files, f)
+			}
+		}
+	}
+	emergencyFiles.Store(files)
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestEmergencyWrite checks that messages are written directly to the
+// logfile, without allocations.
+func TestEmergencyWrite(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	Info("Test Info 1")
+	EmergencyWrite([]byte("Emergency 1\n"))
+	Info("Test Info 2")
+	msg := []byte("Emergency 2\n")
+	if n := testing.AllocsPerRun(2, func() { EmergencyWrite(msg) }); n != 0 {
+		t.Errorf("EmergencyWrite allocates: %f", n)
+	}
+
+	checkLines := []string{
+		"INFO     : Test Info 1",
+		"Emergency 1",
+		"INFO     : Test Info 2",
+		"Emergency 2",
+		"Emergency 2",
+		"Emergency 2",
+	}
+	fileMatch(t, checkLines, "")
+}
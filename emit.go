@@ -0,0 +1,152 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"sort"
+	"time"
+)
+
+// Clone returns a copy of the entry, which can be modified without affecting
+// the original, for example to redact or add fields before passing it on
+// with Emit.
+func (e Entry) Clone() Entry {
+	if e.Fields != nil {
+		fields := make(map[string]interface{}, len(e.Fields))
+		for k, v := range e.Fields {
+			fields[k] = v
+		}
+		e.Fields = fields
+	}
+	return e
+}
+
+// Emit logs an entry, for example one that was transformed. See the Emit
+// method of Logger for details.
+func Emit(e Entry) {
+	(*Logger)(nil).Emit(e)
+}
+
+// Emit logs an entry as if it had been logged via the Logger, with the time,
+// level, message, fields and caller of the entry. Fields of the Logger with
+// the same keys as fields of the entry are replaced. The entry passes the
+// level and trace filters for its file, like any other message. An entry
+// without a level is logged at INFO level, while an entry with an unknown
+// level, or a trace message with a negative trace level, is dropped. Caller
+// info is only shown for entries with a file. This is the building block for
+// processing pipelines: A hook that was added with AddEmitHook may modify a
+// Clone of its entry and pass it on with the Emitter it receives, since Emit
+// itself must not be called from within a hook. Entries that are logged with
+// Emit don't run hooks, so that they can't loop.
+func (l *Logger) Emit(e Entry) {
+	l.emit(e, false)
+}
+
+// Emitter logs entries from within a hook, which was added with AddEmitHook.
+// Emit can't be used there, since hooks run while rlog holds its lock.
+type Emitter struct{}
+
+// Emit logs an entry, like the package level Emit function.
+func (Emitter) Emit(e Entry) {
+	(*Logger)(nil).emit(e, true)
+}
+
+// EmitTo logs an entry via a Logger, like the Emit method of the Logger.
+func (Emitter) EmitTo(l *Logger, e Entry) {
+	l.emit(e, true)
+}
+
+// emit logs an entry for Emit. The isLocked flag indicates that the caller
+// already holds the read lock on initMutex.
+func (l *Logger) emit(e Entry, isLocked bool) {
+	if !isLocked {
+		ensureInitialized()
+		initMutex.RLock()
+		defer initMutex.RUnlock()
+	}
+	if e.Level == 0 {
+		e.Level = LevelInfo
+	}
+	if e.Level < LevelCritical || e.Level > LevelTrace {
+		rlogIssue("Unknown level %d of emitted entry. Dropped.", int(e.Level))
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = currentTime()
+	}
+	level, traceLevel, prefixAddition := int(e.Level), notATrace, ""
+	if e.Level == LevelTrace {
+		if e.TraceLevel < 0 {
+			rlogIssue("Invalid trace level %d of emitted entry. Dropped.", e.TraceLevel)
+			return
+		}
+		if !traceEnabled() {
+			return
+		}
+		traceLevel = e.TraceLevel
+		prefixAddition = tracePrefix(traceLevel)
+	}
+	caller := callerData{funcName: e.Func, moduleAndFileName: e.File, line: e.Line}
+	logEntry(e.Time, emitLogger(l, e.Fields), level, traceLevel, caller, "%s\n", prefixAddition, e.Message)
+}
+
+// emitLogger returns a copy of the Logger for emitting an entry, which holds
+// all the fields of the entry, sorted by key, after those of its own fields
+// and of the fields attached to every entry that the entry doesn't replace.
+// The caller needs to hold at least the read lock on initMutex.
+func emitLogger(l *Logger, entryFieldValues map[string]interface{}) *Logger {
+	nl := l.clone()
+	nl.emitted = true
+	nl.fields = nil
+	for _, f := range entryFields(l) {
+		if _, ok := entryFieldValues[f.key]; !ok {
+			nl.fields = append(nl.fields, f)
+		}
+	}
+	keys := make([]string, 0, len(entryFieldValues))
+	for k := range entryFieldValues {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		nl.fields = append(nl.fields, valueField(k, entryFieldValues[k]))
+	}
+	return nl
+}
+
+// valueField returns a field for a value of an entry, which is stored without
+// reflection if it has one of the types that fieldValue returns.
+func valueField(key string, value interface{}) field {
+	switch v := value.(type) {
+	case string:
+		return field{key: key, kind: fieldString, str: v}
+	case int64:
+		return intField(key, v)
+	case uint64:
+		return field{key: key, kind: fieldUint, num: int64(v)}
+	case float64:
+		return field{key: key, kind: fieldFloat, fl: v}
+	case bool:
+		f := field{key: key, kind: fieldBool}
+		if v {
+			f.num = 1
+		}
+		return f
+	case time.Duration:
+		return field{key: key, kind: fieldDuration, num: int64(v)}
+	}
+	return anyField(key, value)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"os"
+	"strconv"
+	"testing"
+)
+
+// TestEntryClone checks that a clone of an entry has its own fields.
+func TestEntryClone(t *testing.T) {
+	e := Entry{Message: "Test", Fields: map[string]interface{}{"n": int64(1)}}
+	c := e.Clone()
+	c.Fields["n"] = int64(2)
+	c.Message = "Changed"
+	if e.Fields["n"] != int64(1) || e.Message != "Test" {
+		t.Errorf("Original entry was modified: %+v", e)
+	}
+	if c := (Entry{}).Clone(); c.Fields != nil {
+		t.Errorf("Incorrect clone of entry without fields: %+v", c)
+	}
+}
+
+// TestEmitFromHook checks that a hook can re-emit a modified entry, which
+// doesn't run the hooks again.
+func TestEmitFromHook(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer func() { hooks = nil }()
+	initialize(conf, true)
+
+	calls := 0
+	AddEmitHook(LevelError, func(e Entry, em Emitter) {
+		calls++
+		if _, ok := e.Fields["password"]; !ok {
+			return
+		}
+		c := e.Clone()
+		c.Fields["password"] = "***"
+		c.Message += " (redacted)"
+		em.EmitTo(WithSampleKey("key"), c)
+	})
+	l := Ev().Level(LevelError).Str("user", "joe").Str("password", "secret")
+	l.Msg("Test Error")
+	Emit(Entry{Level: LevelWarn, Message: "Test Warning", Fields: map[string]interface{}{
+		"ok": true, "n": int64(3)}})
+	Emit(Entry{Level: LevelDebug, Message: "Test Debug"})
+	Emit(Entry{Level: LevelTrace, TraceLevel: 1, Message: "Trace 1"})
+
+	if calls != 1 {
+		t.Errorf("Incorrect number of hook calls: %d", calls)
+	}
+	checkLines := []string{
+		"ERROR    : Test Error user=joe password=secret",
+		"ERROR    : Test Error (redacted) password=*** user=joe",
+		"WARN     : Test Warning n=3 ok=true",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestEmitReplacesFields checks that fields of the entry replace those of
+// the Logger with the same keys.
+func TestEmitReplacesFields(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	l := Group("g1")
+	l.Emit(Entry{Level: LevelInfo, Message: "Test Info", Fields: map[string]interface{}{
+		"group": "g2"}})
+
+	checkLines := []string{
+		"INFO     : Begin of group g1 group=g1 group_id=" + l.fields[1].str,
+		"INFO     : Test Info group_id=" + l.fields[1].str + " group=g2",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestEmitInvalid checks that an entry without a level is logged at INFO
+// level and without caller info, and that entries with invalid levels are
+// dropped.
+func TestEmitInvalid(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.showCallerInfo = "yes"
+	conf.traceLevel = "5"
+	initialize(conf, true)
+
+	Emit(Entry{Message: "Test Info"})
+	Emit(Entry{Level: Level(42), Message: "Test Unknown"})
+	Emit(Entry{Level: LevelTrace, TraceLevel: -1, Message: "Test Trace"})
+	Emit(Entry{Level: LevelWarn, Message: "Test Warn", File: "rlog/emit.go", Line: 7, Func: "rlog.Test"})
+
+	checkLines := []string{
+		"INFO     : Test Info",
+		"WARN     : [" + strconv.Itoa(os.Getpid()) + " rlog/emit.go:7 (rlog.Test)] Test Warn",
+	}
+	fileMatch(t, checkLines, "")
+}
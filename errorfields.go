@@ -0,0 +1,112 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// errorArgs examines the format string and arguments of a formatted log
+// message for an error that should be attached to the entry as field. This is
+// the argument for a %w verb, which is then formatted as %v, or an error that
+// was passed as last argument without a verb for it, which is then left out
+// of the message. The format string and arguments to be used for the message
+// are returned, together with the error, or nil if there is none.
+func errorArgs(format string, a []interface{}) (string, []interface{}, error) {
+	var wrapped error
+	verbs := 0
+	countable := true // verbs map to arguments one by one
+	fixedFormat := []byte(nil)
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		// Skip flags, width and precision to find the verb
+		j := i + 1
+		for j < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[j]) >= 0 {
+			if format[j] == '*' || format[j] == '[' {
+				countable = false
+			}
+			j++
+		}
+		if j == len(format) {
+			break
+		}
+		if format[j] == '%' {
+			i = j
+			continue
+		}
+		if format[j] == 'w' {
+			if fixedFormat == nil {
+				fixedFormat = []byte(format)
+			}
+			fixedFormat[j] = 'v'
+			if countable && verbs < len(a) && wrapped == nil {
+				wrapped, _ = a[verbs].(error)
+			}
+		}
+		verbs++
+		i = j
+	}
+	if fixedFormat != nil {
+		return string(fixedFormat), a, wrapped
+	}
+	if countable && len(a) > verbs {
+		if err, ok := a[len(a)-1].(error); ok && err != nil {
+			return format, a[:len(a)-1], err
+		}
+	}
+	return format, a, nil
+}
+
+// errorKey returns the key for an error field that doesn't collide with any of
+// the given fields: 'error', or if that exists already, 'error_2', 'error_3'
+// and so on.
+func errorKey(fields []field) string {
+	key := "error"
+	for n := 2; hasField(fields, key); n++ {
+		key = "error_" + strconv.Itoa(n)
+	}
+	return key
+}
+
+// errorFields returns the fields that describe an error: The message as field
+// with the given key, for example 'error', and, if the error wraps other
+// errors, their messages as list in a field with the suffix '_chain', for
+// example 'error_chain', outermost first.
+func errorFields(key string, err error) []field {
+	fields := []field{{key: key, kind: fieldString, str: err.Error()}}
+	var chain []string
+	for e := errors.Unwrap(err); e != nil && len(chain) < jsonMaxItems; e = errors.Unwrap(e) {
+		chain = append(chain, e.Error())
+	}
+	if len(chain) > 0 {
+		fields = append(fields, anyField(key+"_chain", chain))
+	}
+	return fields
+}
+
+// hasField returns true if there is a field with the given key.
+func hasField(fields []field, key string) bool {
+	for i := range fields {
+		if fields[i].key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestErrorArgs checks which errors are found in the arguments of formatted
+// messages.
+func TestErrorArgs(t *testing.T) {
+	err := errors.New("boom")
+	tests := []struct {
+		format         string
+		a              []interface{}
+		expectedFormat string
+		expectedArgs   int
+		expectedErr    error
+	}{
+		{"failed: %v", []interface{}{err}, "failed: %v", 1, nil},
+		{"failed %d: %w", []interface{}{1, err}, "failed %d: %v", 2, err},
+		{"100%% failed: %w", []interface{}{err}, "100%% failed: %v", 1, err},
+		{"failed %s", []interface{}{"x", err}, "failed %s", 1, err},
+		{"failed %*d", []interface{}{3, 1, err}, "failed %*d", 3, nil},
+		{"failed", []interface{}{"x"}, "failed", 1, nil},
+	}
+	for _, test := range tests {
+		format, a, e := errorArgs(test.format, test.a)
+		if format != test.expectedFormat || len(a) != test.expectedArgs || e != test.expectedErr {
+			t.Errorf("Incorrect result for '%s': '%s', %d args, error %v",
+				test.format, format, len(a), e)
+		}
+	}
+}
+
+// TestErrorFields checks that wrapped errors are attached as fields.
+func TestErrorFields(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	err := fmt.Errorf("open config: %w", errors.New("no such file"))
+	Errorf("Startup failed: %w", err)
+	Warnf("Retrying %s", "later", errors.New("timeout"))
+	Errorf("Plain %v", err)
+	l := &Logger{fields: []field{{key: "error", kind: fieldString, str: "earlier"}}}
+	l.Warnf("Retrying %s", "later", errors.New("timeout"))
+
+	checkLines := []string{
+		"ERROR    : Startup failed: open config: no such file error=\"open config: no such file\" error_chain=\"[no such file]\"",
+		"WARN     : Retrying later error=timeout",
+		"ERROR    : Plain open config: no such file",
+		"WARN     : Retrying later error=earlier error_2=timeout",
+	}
+	fileMatch(t, checkLines, "")
+}
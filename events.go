@@ -0,0 +1,62 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"sync"
+)
+
+// eventDef describes a registered event code.
+type eventDef struct {
+	level    int
+	template string
+}
+
+var (
+	eventCatalog      = map[int]eventDef{} // registered event codes
+	eventCatalogMutex sync.RWMutex         // protects eventCatalog
+)
+
+// RegisterEvent adds an event code to the catalog of events, together with
+// the level at which the event is logged and a message template, which is a
+// format string as used by Printf. Registering a code again replaces the
+// previous definition.
+func RegisterEvent(code int, level Level, template string) {
+	eventCatalogMutex.Lock()
+	defer eventCatalogMutex.Unlock()
+	eventCatalog[code] = eventDef{level: int(level), template: template}
+}
+
+// Event logs the event with the given code from the catalog of events. The
+// message is produced from the template registered for the code and the
+// provided arguments. The code itself is attached to the log entry as the
+// field 'event', so that support can search for stable codes instead of
+// message texts, which may change between releases. An unregistered code is
+// logged at WARN level, with the arguments as message.
+func Event(code int, a ...interface{}) {
+	eventCatalogMutex.RLock()
+	def, ok := eventCatalog[code]
+	eventCatalogMutex.RUnlock()
+
+	l := &Logger{fields: []field{intField("event", int64(code))}}
+	if !ok {
+		basicLog(l, levelWarn, notATrace, false, "Unregistered event: %s", "",
+			fmt.Sprintln(a...))
+		return
+	}
+	basicLog(l, def.level, notATrace, false, def.template+"\n", "", a...)
+}
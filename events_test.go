@@ -0,0 +1,43 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestEvents checks that events are logged with the registered level and
+// template, and that the event code is attached as field.
+func TestEvents(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	RegisterEvent(1042, LevelError, "Disk %s is %d%% full")
+	Event(1042, "/dev/sda", 95)
+	Event(1043, "foo", 1)
+
+	conf.logFormat = "json"
+	initialize(conf, true)
+	Event(1042, "/dev/sdb", 90)
+
+	checkLines := []string{
+		"ERROR    : Disk /dev/sda is 95% full event=1042",
+		"WARN     : Unregistered event: foo 1 event=1043",
+		`{"level":"ERROR","msg":"Disk /dev/sdb is 90% full","event":1042}`,
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// The exit code used by Fatal and Fatalf, unless configured otherwise.
+	defaultFatalExitCode = 1
+	// How long Fatal and Fatalf wait for the output to be written, unless
+	// configured otherwise.
+	defaultFatalTimeout = 5 * time.Second
+)
+
+var (
+	settingFatalExitCode int           = defaultFatalExitCode // exit code for Fatal
+	settingFatalTimeout  time.Duration = defaultFatalTimeout  // limit for writing output in Fatal
+
+	exitFunc      func(code int) = os.Exit // called by Fatal and Fatalf
+	fatalPanics   bool                     // Fatal panics instead of exiting
+	exitFuncMutex sync.Mutex               // protects exitFunc and fatalPanics
+)
+
+// FatalPanic is the value with which Fatal and Fatalf panic, rather than
+// exiting the program, once SetFatalPanics was enabled.
+type FatalPanic struct {
+	Code int // the exit code that would have been used
+}
+
+// Error returns a description of the fatal exit.
+func (p FatalPanic) Error() string {
+	return fmt.Sprintf("rlog: fatal message logged, exit code %d", p.Code)
+}
+
+// SetExitFunc replaces the function that is called by Fatal and Fatalf after
+// the message was logged. By default this is os.Exit. A program may want to
+// perform some cleanup first, or a test may want to check that Fatal was
+// called without actually exiting. Passing nil restores the default.
+func SetExitFunc(f func(code int)) {
+	exitFuncMutex.Lock()
+	defer exitFuncMutex.Unlock()
+	if f == nil {
+		f = os.Exit
+	}
+	exitFunc = f
+}
+
+// SetFatalPanics makes Fatal and Fatalf panic with a FatalPanic value after
+// the message was logged, rather than calling the exit function. This is
+// useful in tests, which can recover the panic, or under supervisors that
+// should see a stack trace. The exit function is not called then.
+func SetFatalPanics(panics bool) {
+	exitFuncMutex.Lock()
+	defer exitFuncMutex.Unlock()
+	fatalPanics = panics
+}
+
+// fatalExit is used by Fatal and Fatalf to exit the program once the message
+// was logged. A watchdog makes sure that the program exits even if writing
+// the message or the buffered output blocks, for example because the reader
+// of a pipe is stuck.
+type fatalExit struct {
+	code    int
+	panics  bool          // panic in finish rather than exiting
+	timer   *time.Timer   // the watchdog, nil if we wait without limit
+	once    sync.Once     // makes sure that the exit function is called once
+	expired chan struct{} // closed when the watchdog fired
+}
+
+// startFatal determines the exit code and starts the watchdog. This needs to
+// be called before the fatal message is logged, so that a blocking write is
+// covered by the timeout as well.
+func startFatal() *fatalExit {
+	ensureInitialized()
+	initMutex.RLock()
+	e := &fatalExit{code: settingFatalExitCode, expired: make(chan struct{})}
+	timeout := settingFatalTimeout
+	initMutex.RUnlock()
+	exitFuncMutex.Lock()
+	e.panics = fatalPanics
+	exitFuncMutex.Unlock()
+	if timeout > 0 {
+		e.timer = time.AfterFunc(timeout, func() {
+			close(e.expired)
+			// A panic can only be raised by the goroutine that called
+			// Fatal, which does so once it stops waiting.
+			if !e.panics {
+				e.exit()
+			}
+		})
+	}
+	return e
+}
+
+// finish makes sure that buffered output is written and that the logfile is
+// committed to storage, and then calls the exit function. If this doesn't
+// complete before the watchdog fires then the program exits anyway. If
+// SetFatalPanics was enabled then this panics instead.
+func (e *fatalExit) finish() {
+	done := make(chan struct{})
+	go func() {
+		initMutex.RLock()
+		flushStream()
+		syncLogFile()
+		initMutex.RUnlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-e.expired:
+	}
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	if e.panics {
+		panic(FatalPanic{Code: e.code})
+	}
+	e.exit()
+}
+
+// exit calls the exit function with the configured exit code. Only the first
+// call has an effect, but all calls return only after the exit function did.
+func (e *fatalExit) exit() {
+	e.once.Do(func() {
+		exitFuncMutex.Lock()
+		f := exitFunc
+		exitFuncMutex.Unlock()
+		f(e.code)
+	})
+}
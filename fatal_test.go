@@ -0,0 +1,148 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestFatal checks that Fatal logs the message and calls the exit function
+// with the configured exit code.
+func TestFatal(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.fatalExitCode = "3"
+	initialize(conf, true)
+
+	exitCode := -1
+	SetExitFunc(func(code int) { exitCode = code })
+	defer SetExitFunc(nil)
+
+	Fatalf("Test Fatal %d", 1)
+	if exitCode != 3 {
+		t.Fatalf("Incorrect exit code %d. Should be 3.", exitCode)
+	}
+	checkLines := []string{
+		"CRITICAL : Test Fatal 1",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestFatalPanics checks that Fatal panics instead of exiting, if that was
+// requested, and that the message is logged before.
+func TestFatalPanics(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	exited := false
+	SetExitFunc(func(code int) { exited = true })
+	defer SetExitFunc(nil)
+	SetFatalPanics(true)
+	defer SetFatalPanics(false)
+
+	func() {
+		defer func() {
+			p, ok := recover().(FatalPanic)
+			if !ok || p.Code != 1 {
+				t.Fatalf("Incorrect panic value: %#v", p)
+			}
+		}()
+		Fatal("Test Fatal 1")
+		t.Fatal("Fatal did not panic")
+	}()
+	if exited {
+		t.Fatal("The exit function should not have been called")
+	}
+	checkLines := []string{
+		"CRITICAL : Test Fatal 1",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// blockingWriter blocks all writes until it is released.
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+// TestFatalBuffered checks that the fatal message is not lost in the buffer
+// of the log stream.
+func TestFatalBuffered(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	var buf bytes.Buffer
+	streamOutput = &buf
+	defer func() { streamOutput = nil }()
+	conf.streamBuffer = "size"
+	initialize(conf, true)
+
+	SetExitFunc(func(code int) {})
+	defer SetExitFunc(nil)
+
+	Fatal("Test Fatal 1")
+	if buf.String() != "CRITICAL : Test Fatal 1\n" {
+		t.Fatalf("Fatal message was not flushed: %q", buf.String())
+	}
+}
+
+// TestFatalTimeout checks that Fatal exits after the configured timeout, if
+// the output blocks.
+func TestFatalTimeout(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	w := blockingWriter{release: make(chan struct{})}
+	streamOutput = w
+	defer func() { streamOutput = nil }()
+	conf.fatalTimeout = "1"
+	initialize(conf, true)
+
+	exited := make(chan int, 1)
+	SetExitFunc(func(code int) { exited <- code })
+	defer SetExitFunc(nil)
+
+	returned := make(chan struct{})
+	start := time.Now()
+	go func() {
+		Fatal("Test Fatal 1")
+		close(returned)
+	}()
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Errorf("Incorrect exit code %d. Should be 1.", code)
+		}
+		if d := time.Since(start); d < time.Second {
+			t.Errorf("Exited after %s, before the timeout", d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Fatal did not exit after the timeout")
+	}
+	close(w.release)
+	<-returned
+	if len(exited) != 0 {
+		t.Fatal("The exit function was called more than once")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+var (
+	// addedFilters are the log level filters added with AddFilter. They take
+	// precedence over the configured filters. Protected by initMutex.
+	addedFilters []filter
+	// configuredLogFilters are the log level filters from the configuration.
+	// Protected by initMutex.
+	configuredLogFilters *filterSpec
+)
+
+// AddFilter adds a log level filter for the files matching the pattern, as if
+// "pattern=level" was given in RLOG_LOG_LEVEL. This allows admin tools to
+// refine the filters of a running program. Filters added this way take
+// precedence over the configured ones and are kept when the configuration is
+// re-read. Adding a filter for a pattern that already has one replaces it.
+// The pattern is a shell glob for the file name, such as "client.go" or "ip*".
+func AddFilter(pattern string, level Level) error {
+	if _, err := filepath.Match(pattern, ""); err != nil || pattern == "" {
+		return fmt.Errorf("rlog: illegal filter pattern '%s'", pattern)
+	}
+	if _, ok := levelStrings[int(level)]; !ok || level == LevelTrace {
+		return fmt.Errorf("rlog: illegal filter level %d", level)
+	}
+	ensureInitialized()
+	initMutex.Lock()
+	defer notifyConfigChange()
+	defer initMutex.Unlock()
+	removeAddedFilter(pattern)
+	addedFilters = append(addedFilters, filter{pattern, int(level)})
+	logFilterSpec = withAddedFilters(configuredLogFilters)
+	return nil
+}
+
+// RemoveFilter removes the filter for the pattern that was added with
+// AddFilter. Messages from the matching files are then filtered as configured
+// again.
+func RemoveFilter(pattern string) {
+	ensureInitialized()
+	initMutex.Lock()
+	defer notifyConfigChange()
+	defer initMutex.Unlock()
+	removeAddedFilter(pattern)
+	logFilterSpec = withAddedFilters(configuredLogFilters)
+}
+
+// removeAddedFilter removes the added filter for a pattern, if there is one.
+// The caller needs to hold the write lock on initMutex.
+func removeAddedFilter(pattern string) {
+	for i, f := range addedFilters {
+		if f.Pattern == pattern {
+			addedFilters = append(addedFilters[:i:i], addedFilters[i+1:]...)
+			return
+		}
+	}
+}
+
+// withAddedFilters returns the filters of the spec, preceded by the added
+// filters. The caller needs to hold the write lock on initMutex.
+func withAddedFilters(spec *filterSpec) *filterSpec {
+	if len(addedFilters) == 0 {
+		return spec
+	}
+	newSpec := &filterSpec{allFiles: spec.allFiles}
+	newSpec.filters = append(newSpec.filters, addedFilters...)
+	newSpec.filters = append(newSpec.filters, spec.filters...)
+	newSpec.updateLevelBounds()
+	return newSpec
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestAddFilter checks that filters can be added and removed at runtime, and
+// that they are kept when the configuration is re-read.
+func TestAddFilter(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer Reset()
+	conf.logLevel = "WARN"
+	initialize(conf, true)
+
+	if AddFilter("[", LevelDebug) == nil || AddFilter("x.go", LevelTrace) == nil {
+		t.Fatal("Illegal filters should be rejected")
+	}
+	Info("Test Info 1")
+	if err := AddFilter("filters_*.go", LevelInfo); err != nil {
+		t.Fatal(err)
+	}
+	AddFilter("filters_*.go", LevelDebug)
+	Debug("Test Debug 2")
+	initialize(conf, false)
+	Debug("Test Debug 3")
+	RemoveFilter("filters_*.go")
+	Debug("Test Debug 4")
+	Warn("Test Warn 5")
+
+	checkLines := []string{
+		"DEBUG    : Test Debug 2",
+		"DEBUG    : Test Debug 3",
+		"WARN     : Test Warn 5",
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Fingerprint returns an identifier for the kind of problem an entry reports.
+// It is computed from the call site and the message, in which all words that
+// contain digits (numbers, IDs, addresses and so on) are ignored. Therefore,
+// repeated occurrences of the same error have the same fingerprint, even if
+// the details in their messages differ.
+func (e Entry) Fingerprint() string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s:%d\n%s", e.File, e.Line, normalizeMessage(e.Message))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// normalizeMessage replaces every word of a message that contains a digit
+// with '#'.
+func normalizeMessage(msg string) string {
+	var b strings.Builder
+	word := make([]rune, 0, 32)
+	hasDigit := false
+	flush := func() {
+		if hasDigit {
+			b.WriteByte('#')
+		} else {
+			b.WriteString(string(word))
+		}
+		word = word[:0]
+		hasDigit = false
+	}
+	for _, r := range msg {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+			hasDigit = hasDigit || unicode.IsDigit(r)
+			continue
+		}
+		flush()
+		b.WriteRune(r)
+	}
+	flush()
+	return b.String()
+}
+
+// alertGroup collects the repeated occurrences of an entry.
+type alertGroup struct {
+	last  Entry // the most recent occurrence
+	count int   // number of occurrences since the first one
+}
+
+// GroupAlerts returns a hook function, which forwards entries to an alerting
+// sink, such as a pager or a webhook, but groups entries with the same
+// fingerprint. The first occurrence is forwarded immediately, with a count of
+// 1. Further occurrences within the given time window are only counted. At the
+// end of the window the most recent of them is forwarded once, together with
+// the number of occurrences it stands for. Use it with AddHook:
+//
+//     rlog.AddHook(rlog.LevelError, rlog.GroupAlerts(time.Minute, sendAlert))
+//
+// The send function may be called concurrently from different goroutines.
+func GroupAlerts(window time.Duration, send func(e Entry, count int)) func(Entry) {
+	var mutex sync.Mutex
+	groups := make(map[string]*alertGroup)
+
+	closeGroup := func(fp string) {
+		mutex.Lock()
+		g := groups[fp]
+		delete(groups, fp)
+		mutex.Unlock()
+		if g != nil && g.count > 0 {
+			send(g.last, g.count)
+		}
+	}
+
+	return func(e Entry) {
+		fp := e.Fingerprint()
+		mutex.Lock()
+		if g, ok := groups[fp]; ok {
+			g.last = e
+			g.count++
+			mutex.Unlock()
+			return
+		}
+		groups[fp] = &alertGroup{}
+		mutex.Unlock()
+		time.AfterFunc(window, func() { closeGroup(fp) })
+		send(e, 1)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestFingerprint checks that messages differing only in numbers and IDs get
+// the same fingerprint.
+func TestFingerprint(t *testing.T) {
+	e1 := Entry{File: "foo/bar.go", Line: 10, Message: "Request 1234 failed after 3.5s (id 0xbeef12)"}
+	e2 := Entry{File: "foo/bar.go", Line: 10, Message: "Request 99 failed after 12.1s (id 0xcafe34)"}
+	e3 := Entry{File: "foo/bar.go", Line: 10, Message: "Request 99 timed out"}
+	e4 := Entry{File: "foo/bar.go", Line: 11, Message: e1.Message}
+	if e1.Fingerprint() != e2.Fingerprint() {
+		t.Fatal("Same error should have same fingerprint")
+	}
+	if e1.Fingerprint() == e3.Fingerprint() || e1.Fingerprint() == e4.Fingerprint() {
+		t.Fatal("Different errors should have different fingerprints")
+	}
+	if s := normalizeMessage(e1.Message); s != "Request # failed after #.# (id #)" {
+		t.Fatalf("Incorrect normalized message: %s", s)
+	}
+}
+
+// TestGroupAlerts checks that repeated entries are forwarded as one group.
+func TestGroupAlerts(t *testing.T) {
+	var mutex sync.Mutex
+	var counts []int
+	group := GroupAlerts(50*time.Millisecond, func(e Entry, count int) {
+		mutex.Lock()
+		counts = append(counts, count)
+		mutex.Unlock()
+	})
+	for i := 0; i < 5; i++ {
+		group(Entry{File: "foo.go", Line: 1, Message: "Disk full"})
+	}
+	group(Entry{File: "foo.go", Line: 2, Message: "Other"})
+	time.Sleep(200 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(counts) != 3 || counts[0] != 1 || counts[1] != 1 || counts[2] != 4 {
+		t.Fatalf("Incorrect alert counts: %v", counts)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+// Formatter turns a log entry into the line that is written to an output, in
+// place of the format selected with RLOG_LOG_FORMAT. A newline is added to
+// the line, unless it already ends with one. The entry only contains caller
+// info if that is shown for the message.
+type Formatter interface {
+	Format(e Entry) []byte
+}
+
+// FormatterFunc adapts an ordinary function to the Formatter interface.
+type FormatterFunc func(e Entry) []byte
+
+// Format calls f(e).
+func (f FormatterFunc) Format(e Entry) []byte {
+	return f(e)
+}
+
+var (
+	// settingStreamFormatter replaces the configured format of the log
+	// stream, if set. Protected by initMutex.
+	settingStreamFormatter Formatter
+	// settingFileFormatter replaces the configured format of the logfile, if
+	// set. Protected by initMutex.
+	settingFileFormatter Formatter
+)
+
+// SetStreamFormatter formats the entries that are written to the log stream
+// with the Formatter, instead of in the format set with RLOG_LOG_FORMAT or
+// RLOG_FORMAT_STREAM. Setting nil goes back to the configured format.
+func SetStreamFormatter(f Formatter) {
+	ensureInitialized()
+	initMutex.Lock()
+	defer initMutex.Unlock()
+	settingStreamFormatter = f
+}
+
+// SetFileFormatter formats the entries that are written to the logfile with
+// the Formatter, instead of in the format set with RLOG_LOG_FORMAT or
+// RLOG_FORMAT_FILE. Setting nil goes back to the configured format.
+func SetFileFormatter(f Formatter) {
+	ensureInitialized()
+	initMutex.Lock()
+	defer initMutex.Unlock()
+	settingFileFormatter = f
+}
+
+// recordEntry returns the Entry for a log record, as it is passed to
+// Formatters and Sinks.
+func recordEntry(r *logRecord) Entry {
+	traceLevel := notATrace
+	if r.level == levelTrace {
+		traceLevel = r.traceLevel
+	}
+	caller := r.caller
+	if caller == nil {
+		caller = &callerData{}
+	}
+	return *newEntry(r, traceLevel, caller)
+}
+
+// formatEntry formats a log record with a Formatter.
+func formatEntry(f Formatter, r *logRecord) string {
+	line := f.Format(recordEntry(r))
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		return string(line) + "\n"
+	}
+	return string(line)
+}
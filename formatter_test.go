@@ -0,0 +1,83 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// levelFormatter formats entries as their level and message.
+var levelFormatter = FormatterFunc(func(e Entry) []byte {
+	return []byte(e.Level.String() + " " + e.Message)
+})
+
+// TestStreamFormatter checks that the stream formatter replaces the format of
+// the log stream, and that nil goes back to the configured format.
+func TestStreamFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	SetTestMode(true)
+	SetStreamOutput(&buf)
+	SetStreamFormatter(levelFormatter)
+	defer Reset()
+
+	Warn("Test 1")
+	if buf.String() != "WARN Test 1\n" {
+		t.Errorf("Incorrect formatted line: %q", buf.String())
+	}
+
+	buf.Reset()
+	SetStreamFormatter(nil)
+	Warn("Test 2")
+	if buf.String() == "WARN Test 2\n" || !bytes.Contains(buf.Bytes(), []byte("Test 2")) {
+		t.Errorf("Expected the configured format, got: %q", buf.String())
+	}
+}
+
+// TestFileFormatter checks that the file formatter only applies to the
+// logfile, while the log stream keeps the configured format.
+func TestFileFormatter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rlog-formatter")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "test.log")
+
+	var buf bytes.Buffer
+	SetTestMode(true)
+	SetStreamOutput(&buf)
+	if err := SetFileOutput(fileName); err != nil {
+		t.Fatalf("SetFileOutput failed: %s", err)
+	}
+	SetFileFormatter(levelFormatter)
+	defer Reset()
+
+	Info("Test 1")
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Unable to read logfile: %s", err)
+	}
+	if string(content) != "INFO Test 1\n" {
+		t.Errorf("Incorrect formatted line in logfile: %q", content)
+	}
+	if buf.String() == "INFO Test 1\n" || !bytes.Contains(buf.Bytes(), []byte("Test 1")) {
+		t.Errorf("Expected the configured format in the log stream, got: %q", buf.String())
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// packageLoggers caches the Loggers returned by ForPackage, by program counter
+// of the caller.
+var packageLoggers sync.Map
+
+// ForPackage returns a Logger named after the package from which it is
+// called, for example "github.com/example/app/db". The name is attached to
+// each message in the field "logger". It can also be used in log and trace
+// level filters, where a pattern with a '/' selects the messages of the named
+// package and all packages below it:
+//
+//     RLOG_LOG_LEVEL=INFO,github.com/example/app/db=DEBUG
+//
+// The Logger is looked up only once per call site, so that ForPackage can be
+// called wherever a message is logged.
+func ForPackage() *Logger {
+	pc, _, _, ok := runtime.Caller(1)
+	if !ok {
+		return nil
+	}
+	if l, ok := packageLoggers.Load(pc); ok {
+		return l.(*Logger)
+	}
+	name := packageName(runtime.FuncForPC(pc))
+	l := &Logger{
+		name:   name,
+		fields: []field{{key: "logger", kind: fieldString, str: name}},
+	}
+	packageLoggers.Store(pc, l)
+	return l
+}
+
+// packageName returns the import path of the package in which the function
+// is defined.
+func packageName(f *runtime.Func) string {
+	if f == nil {
+		return "unknown"
+	}
+	// The function name is qualified with the import path, for example
+	// "github.com/example/app/db.(*Conn).Close". The package name itself may
+	// not contain dots, but the path before it may.
+	name := f.Name()
+	dir := ""
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		dir, name = name[:i+1], name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	return dir + name
+}
+
+// callerName returns the name under which a message is matched against the
+// filters: The package of a named Logger and the base name of the calling
+// file, or else the last directory and name of the calling file.
+func callerName(l *Logger, caller *callerData) string {
+	if l == nil || l.name == "" {
+		return caller.moduleAndFileName
+	}
+	return l.name + "/" + filepath.Base(caller.moduleAndFileName)
+}
+
+// matchFilePattern checks whether a filter pattern matches the name of the
+// file a message comes from, which is given with its last directory, or the
+// package of a named Logger, such as "agent/client.go". Patterns without a '/'
+// only apply to the base name of the file, while those with a '/' apply to the
+// directory or package as well.
+func matchFilePattern(pattern string, name string) bool {
+	if strings.Contains(pattern, "/") {
+		return matchPackage(pattern, name)
+	}
+	matched, _ := filepath.Match(pattern, filepath.Base(name))
+	return matched
+}
+
+// matchPackage checks whether a filter pattern, which contains a '/', matches
+// the name of a message's package and file, either directly or because the
+// package lies below the one in the pattern.
+func matchPackage(pattern string, name string) bool {
+	if matched, _ := filepath.Match(pattern, name); matched {
+		return true
+	}
+	dir := filepath.Dir(name)
+	return dir == pattern || strings.HasPrefix(dir, pattern+"/")
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestForPackage checks that package Loggers are named after the caller's
+// package, cached per call site and matched by package filters.
+func TestForPackage(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.logLevel = "INFO,github.com/romana=DEBUG"
+	initialize(conf, true)
+
+	var loggers []*Logger
+	for i := 0; i < 2; i++ {
+		loggers = append(loggers, ForPackage())
+	}
+	if loggers[0] != loggers[1] {
+		t.Errorf("Different Loggers for the same call site")
+	}
+	if loggers[0].name != "github.com/romana/rlog" {
+		t.Errorf("Wrong package name: %s", loggers[0].name)
+	}
+	func() {
+		ForPackage().Debug("Test Debug 1")
+	}()
+	Debug("Test Debug 2")
+
+	checkLines := []string{
+		"DEBUG    : Test Debug 1 logger=github.com/romana/rlog",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestMatchPackage checks the matching of filter patterns with package names.
+func TestMatchPackage(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"github.com/a/b", "github.com/a/b/x.go", true},
+		{"github.com/a", "github.com/a/b/x.go", true},
+		{"github.com/a/b/x.go", "github.com/a/b/x.go", true},
+		{"github.com/a/*/x.go", "github.com/a/b/x.go", true},
+		{"github.com/a/b", "github.com/a/bc/x.go", false},
+		{"github.com/a/b/c", "github.com/a/b/x.go", false},
+	}
+	for _, test := range tests {
+		if got := matchPackage(test.pattern, test.name); got != test.match {
+			t.Errorf("matchPackage(%q, %q) = %v", test.pattern, test.name, got)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// glogSeverities maps our log levels to the severity letters of glog. Debug
+// and trace messages correspond to glog's verbose messages, which are logged
+// with "I".
+var glogSeverities = map[int]byte{
+	levelCrit:  'F',
+	levelErr:   'E',
+	levelWarn:  'W',
+	levelInfo:  'I',
+	levelDebug: 'I',
+	levelTrace: 'I',
+}
+
+// formatRecordGlog formats a record with the line header of glog, for example
+//
+//     I0613 12:04:33.123456   23730 file.go:42] Test Info
+//
+// so that tools which parse glog output can process it. The process ID is
+// padded to seven characters, like glog does. If there is no caller info
+// then "???:1" is shown as file and line, also like glog does.
+func formatRecordGlog(r *logRecord) string {
+	file, line := "???", 1
+	if r.caller != nil {
+		file, line = filepath.Base(r.caller.moduleAndFileName), r.caller.line
+	}
+	msg := r.msg
+	if len(r.fields) > 0 {
+		msg = strings.TrimRight(msg, "\n") + " " + formatFieldsText(r.fields) + "\n"
+	}
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	return fmt.Sprintf("%c%s %7d %s:%d] %s", glogSeverities[r.level],
+		recordTime(r).Format("0102 15:04:05.000000"), os.Getpid(), file, line, msg)
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// TestFormatRecordGlog checks the glog line header.
+func TestFormatRecordGlog(t *testing.T) {
+	settingTimeUTC = true
+	defer func() { settingTimeUTC = false }()
+	r := &logRecord{
+		time:   time.Date(2024, 6, 13, 12, 4, 33, 123456789, time.UTC),
+		level:  levelWarn,
+		msg:    "Test Warning\n",
+		fields: []field{intField("n", 3)},
+		caller: &callerData{moduleAndFileName: "rlog/file.go", line: 42},
+	}
+	should := fmt.Sprintf("W0613 12:04:33.123456 %7d file.go:42] Test Warning n=3\n", os.Getpid())
+	if line := formatRecordGlog(r); line != should {
+		t.Errorf("Incorrect glog line.\nSHOULD: %sIS:     %s", should, line)
+	}
+
+	r.caller = nil
+	r.level = levelCrit
+	should = fmt.Sprintf("F0613 12:04:33.123456 %7d ???:1] Test Warning n=3\n", os.Getpid())
+	if line := formatRecordGlog(r); line != should {
+		t.Errorf("Incorrect glog line.\nSHOULD: %sIS:     %s", should, line)
+	}
+}
+
+// TestGlogPreset checks that the glog preset produces glog lines with the
+// file and line of the caller.
+func TestGlogPreset(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.showCallerInfo = ""
+	conf.preset = "glog"
+	initialize(conf, true)
+	Info("Test Info")
+
+	content, err := ioutil.ReadFile(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := regexp.MustCompile(`^I\d{4} \d\d:\d\d:\d\d\.\d{6} +\d+ glog_test\.go:\d+\] Test Info\n$`)
+	if !re.Match(content) {
+		t.Errorf("Incorrect glog output: %s", content)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bytes"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// maxGoroutineCreators limits the number of goroutines whose creation site is
+// remembered. When it is reached, the cache is emptied, since most of those
+// goroutines have probably ended already.
+const maxGoroutineCreators = 10000
+
+// settingGoroutineCreatorLevel is the trace level from which on trace messages
+// carry the creation site of the logging goroutine, or notATrace if they
+// never do.
+var settingGoroutineCreatorLevel = notATrace
+
+// goroutineCreators caches the creation site of goroutines by goroutine ID,
+// so that the stack only needs to be parsed once per goroutine.
+var goroutineCreators struct {
+	sync.Mutex
+	sites map[uint64]string
+}
+
+// parseGoroutineCreatorLevel interprets the value of RLOG_GOROUTINE_CREATOR,
+// which is a trace level.
+func parseGoroutineCreatorLevel(s string) int {
+	if s == "" {
+		return notATrace
+	}
+	level, err := strconv.Atoi(s)
+	if err != nil || level < 0 {
+		rlogIssue("Cannot parse goroutine creator trace level '%s'. Ignored.", s)
+		return notATrace
+	}
+	return level
+}
+
+// goroutineCreator returns the function, file name and line number from which
+// the current goroutine was started, or "" for the main goroutine.
+func goroutineCreator() string {
+	gid := getGID()
+	goroutineCreators.Lock()
+	site, ok := goroutineCreators.sites[gid]
+	goroutineCreators.Unlock()
+	if ok {
+		return site
+	}
+
+	site = creatorFromStack(ownStack())
+	goroutineCreators.Lock()
+	if goroutineCreators.sites == nil || len(goroutineCreators.sites) >= maxGoroutineCreators {
+		goroutineCreators.sites = make(map[uint64]string)
+	}
+	goroutineCreators.sites[gid] = site
+	goroutineCreators.Unlock()
+	return site
+}
+
+// ownStack returns the stack trace of the current goroutine. The buffer is
+// grown until it is large enough, since the creation site is at the end.
+func ownStack() []byte {
+	buf := make([]byte, 4*1024)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// creatorFromStack extracts the creation site from a goroutine's stack trace,
+// which ends like this:
+//
+//     created by main.startWorkers in goroutine 1
+//         /src/app/main.go:42 +0x65
+//
+// The result has the form "main.startWorkers main.go:42".
+func creatorFromStack(stack []byte) string {
+	i := bytes.LastIndex(stack, []byte("\ncreated by "))
+	if i < 0 {
+		return ""
+	}
+	lines := bytes.SplitN(stack[i+len("\ncreated by "):], []byte("\n"), 3)
+	funcName := lines[0]
+	if j := bytes.Index(funcName, []byte(" in goroutine ")); j >= 0 {
+		funcName = funcName[:j]
+	}
+	if len(lines) < 2 {
+		return string(funcName)
+	}
+	location := bytes.TrimSpace(lines[1])
+	if j := bytes.LastIndex(location, []byte(" +0x")); j >= 0 {
+		location = location[:j]
+	}
+	return string(funcName) + " " + filepath.Base(string(location))
+}
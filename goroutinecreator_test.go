@@ -0,0 +1,74 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestCreatorFromStack checks that the creation site is extracted from
+// goroutine stacks of old and new Go versions.
+func TestCreatorFromStack(t *testing.T) {
+	tests := []struct {
+		stack string
+		site  string
+	}{
+		{"goroutine 1 [running]:\nmain.main()\n\t/src/app/main.go:10 +0x1d\n", ""},
+		{"goroutine 7 [running]:\nmain.work()\n\t/src/app/work.go:5 +0x1d\n" +
+			"created by main.start in goroutine 1\n\t/src/app/main.go:42 +0x65\n",
+			"main.start main.go:42"},
+		{"goroutine 7 [running]:\nmain.work()\n\t/src/app/work.go:5 +0x1d\n" +
+			"created by main.start\n\t/src/app/main.go:42 +0x65\n",
+			"main.start main.go:42"},
+	}
+	for _, test := range tests {
+		if site := creatorFromStack([]byte(test.stack)); site != test.site {
+			t.Errorf("Incorrect creation site for %q: %q", test.stack, site)
+		}
+	}
+}
+
+// TestGoroutineCreator checks that trace messages of the configured trace
+// levels carry the creation site of the logging goroutine.
+func TestGoroutineCreator(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.traceLevel = "3"
+	conf.goCreator = "2"
+	initialize(conf, true)
+
+	done := make(chan struct{})
+	_, file, line, _ := runtime.Caller(0)
+	go func() {
+		Trace(1, "Trace 1")
+		Trace(2, "Trace 2")
+		Trace(3, "Trace 3")
+		close(done)
+	}()
+	<-done
+
+	site := fmt.Sprintf("github.com/romana/rlog.TestGoroutineCreator %s:%d",
+		filepath.Base(file), line+1)
+	checkLines := []string{
+		"TRACE(1) : Trace 1",
+		"TRACE(2) : Trace 2 goroutine_creator=\"" + site + "\"",
+		"TRACE(3) : Trace 3 goroutine_creator=\"" + site + "\"",
+	}
+	fileMatch(t, checkLines, "")
+}
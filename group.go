@@ -0,0 +1,78 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LogGroup is a Logger for the entries of a multi-step operation, which is
+// started with Group and ended with Done. All its entries carry the fields
+// 'group' and 'group_id', so that log backends can collapse or query them as
+// a unit.
+type LogGroup struct {
+	*Logger
+	name  string
+	start time.Time
+	done  int32 // set to 1 by the first call of Done
+}
+
+// Group starts a group of related log entries, for example:
+//
+//     g := rlog.Group("migration 42")
+//     g.Info("Copying table")
+//     g.Done()
+//
+// A begin marker is logged at INFO level, when the group is started.
+func Group(name string) *LogGroup {
+	g := newGroup(nil, name)
+	basicLog(g.Logger, levelInfo, notATrace, false, "Begin of group %s\n", "", name)
+	return g
+}
+
+// Group starts a group of related log entries, which also carry the fields
+// and settings of the Logger. See the package level Group function for
+// details.
+func (l *Logger) Group(name string) *LogGroup {
+	g := newGroup(l, name)
+	basicLog(g.Logger, levelInfo, notATrace, false, "Begin of group %s\n", "", name)
+	return g
+}
+
+// newGroup creates a group based on the Logger, with a new random group ID.
+func newGroup(l *Logger, name string) *LogGroup {
+	nl := l.clone()
+	n := len(nl.fields)
+	nl.fields = append(nl.fields[:n:n],
+		field{key: "group", kind: fieldString, str: name},
+		field{key: "group_id", kind: fieldString, str: NewRequestID()[:16]})
+	return &LogGroup{Logger: nl, name: name, start: currentTime()}
+}
+
+// Done ends the group and logs an end marker at INFO level, with the time
+// since the group was started in the field 'duration'. Only the first call of
+// Done has an effect.
+func (g *LogGroup) Done() {
+	if !atomic.CompareAndSwapInt32(&g.done, 0, 1) {
+		return
+	}
+	d := currentTime().Sub(g.start)
+	l := g.Logger.clone()
+	n := len(l.fields)
+	l.fields = append(l.fields[:n:n], field{key: "duration", kind: fieldDuration, num: int64(d)})
+	basicLog(l, levelInfo, notATrace, false, "End of group %s\n", "", g.name)
+}
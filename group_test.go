@@ -0,0 +1,55 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGroup checks that the entries of a group carry the group fields and
+// that it is enclosed by begin and end markers.
+func TestGroup(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer SetClock(nil)
+	initialize(conf, true)
+
+	tm := time.Date(2020, 2, 29, 12, 30, 0, 0, time.UTC)
+	SetClock(func() time.Time {
+		tm = tm.Add(time.Second)
+		return tm
+	})
+	g := Group("migration-42")
+	g.Info("Test Info")
+	g.Done()
+	g.Done()
+	other := WithSampleKey("key").Group("other")
+	other.Done()
+
+	id, otherID := g.fields[1].str, other.fields[1].str
+	if len(id) != 16 || len(otherID) != 16 || id == otherID {
+		t.Fatalf("Incorrect group IDs: %q, %q", id, otherID)
+	}
+	checkLines := []string{
+		"INFO     : Begin of group migration-42 group=migration-42 group_id=" + id,
+		"INFO     : Test Info group=migration-42 group_id=" + id,
+		"INFO     : End of group migration-42 group=migration-42 group_id=" + id + " duration=3s",
+		"INFO     : Begin of group other group=other group_id=" + otherID,
+		"INFO     : End of group other group=other group_id=" + otherID + " duration=2s",
+	}
+	fileMatch(t, checkLines, "")
+}
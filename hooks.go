@@ -0,0 +1,117 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strings"
+	"time"
+)
+
+// Entry describes a log message, as it is passed to hooks.
+type Entry struct {
+	Time       time.Time
+	Level      Level                  // LevelTrace for trace messages
+	TraceLevel int                    // trace level, -1 if not a trace message
+	Message    string                 // the message, without trailing newline
+	Fields     map[string]interface{} // additional key/value pairs, may be nil
+	File       string                 // last directory and name of the caller's file
+	Line       int                    // line number in the caller's file
+	Func       string                 // name of the calling function
+}
+
+// hook is a function that is called for log messages at or above a level.
+type hook struct {
+	level Level
+	fn    func(Entry, Emitter)
+}
+
+// hooks holds all registered hooks. Protected by initMutex.
+var hooks []hook
+
+// AddHook registers a function, which is called for every message that is
+// logged with the given level or a more severe one. Hooks allow custom side
+// effects, such as incrementing a metric, without changing the output of
+// rlog. They are called synchronously after the message was written, so they
+// should return quickly. A hook must not log messages via rlog itself. Use
+// AddEmitHook for hooks that need to log entries.
+func AddHook(level Level, fn func(Entry)) {
+	AddEmitHook(level, func(e Entry, _ Emitter) { fn(e) })
+}
+
+// AddEmitHook registers a hook like AddHook, which also receives an Emitter.
+// With that the hook can log entries, for example a redacted Clone of the
+// entry it received, or pass them on to other Loggers.
+func AddEmitHook(level Level, fn func(Entry, Emitter)) {
+	ensureInitialized()
+	initMutex.Lock()
+	defer initMutex.Unlock()
+	hooks = append(hooks, hook{level: level, fn: fn})
+}
+
+// runHooks calls all hooks that are registered for the level of the record.
+// The caller needs to hold at least the read lock on initMutex.
+func runHooks(r *logRecord, traceLevel int, caller *callerData) {
+	var entry *Entry
+	for _, h := range hooks {
+		if r.level > int(h.level) {
+			continue
+		}
+		if entry == nil {
+			entry = newEntry(r, traceLevel, caller)
+		}
+		h.fn(*entry, Emitter{})
+	}
+}
+
+// newEntry creates the Entry that is passed to hooks for a log record.
+func newEntry(r *logRecord, traceLevel int, caller *callerData) *Entry {
+	e := &Entry{
+		Time:       r.time,
+		Level:      Level(r.level),
+		TraceLevel: traceLevel,
+		Message:    strings.TrimSuffix(r.msg, "\n"),
+		File:       caller.moduleAndFileName,
+		Line:       caller.line,
+		Func:       caller.funcName,
+	}
+	if len(r.fields) > 0 {
+		e.Fields = make(map[string]interface{}, len(r.fields))
+		for i := range r.fields {
+			e.Fields[r.fields[i].key] = fieldValue(&r.fields[i])
+		}
+	}
+	return e
+}
+
+// fieldValue returns the value of a field with its original type.
+func fieldValue(f *field) interface{} {
+	switch f.kind {
+	case fieldString:
+		return f.str
+	case fieldInt:
+		return f.num
+	case fieldUint:
+		return uint64(f.num)
+	case fieldFloat:
+		return f.fl
+	case fieldBool:
+		return f.num != 0
+	case fieldDuration:
+		return time.Duration(f.num)
+	default:
+		return f.any
+	}
+}
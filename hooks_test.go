@@ -0,0 +1,52 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestHooks checks that hooks are called for messages at or above their level
+// and receive the details of the message.
+func TestHooks(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer func() { hooks = nil }()
+	conf.traceLevel = "1"
+	initialize(conf, true)
+
+	var warnings, all []Entry
+	AddHook(LevelWarn, func(e Entry) { warnings = append(warnings, e) })
+	AddHook(LevelTrace, func(e Entry) { all = append(all, e) })
+
+	Info("Test Info")
+	Ev().Level(LevelError).Int("n", 3).Msg("Test Error")
+	Trace(1, "Trace 1")
+	Debug("Test Debug")
+
+	if len(warnings) != 1 || len(all) != 3 {
+		t.Fatalf("Incorrect number of hook calls: %d, %d", len(warnings), len(all))
+	}
+	e := warnings[0]
+	if e.Level != LevelError || e.Message != "Test Error" || e.TraceLevel != -1 ||
+		e.Fields["n"] != int64(3) || !strings.HasSuffix(e.File, "/hooks_test.go") {
+		t.Fatalf("Incorrect entry: %+v", e)
+	}
+	if all[2].Level != LevelTrace || all[2].TraceLevel != 1 {
+		t.Fatalf("Incorrect trace entry: %+v", all[2])
+	}
+}
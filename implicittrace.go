@@ -0,0 +1,69 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strconv"
+	"strings"
+)
+
+// globalLevel returns the level of the filter that applies to all files, or
+// levelNone if there is none.
+func (spec *filterSpec) globalLevel() int {
+	for _, f := range spec.filters {
+		if f.Pattern == "" {
+			return f.Level
+		}
+	}
+	return levelNone
+}
+
+// implicitTraceLevel interprets the value of RLOG_IMPLICIT_TRACE and returns
+// the trace level that goes with the global log level, or an empty string if
+// there is none. The value is a list of log levels with the trace level that
+// is enabled with them, for example "DEBUG=2,INFO=0". The most verbose of the
+// log levels that are enabled applies.
+func implicitTraceLevel(s string, logSpec *filterSpec) string {
+	global := logSpec.globalLevel()
+	bestLevel := levelNone
+	traceLevel := ""
+	for _, m := range strings.Split(s, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		tokens := strings.Split(m, "=")
+		if len(tokens) != 2 {
+			rlogIssue("Malformed implicit trace level: '%s'", m)
+			continue
+		}
+		level, ok := levelNumbers[strings.ToUpper(strings.TrimSpace(tokens[0]))]
+		if !ok || level == levelNone || level == levelTrace {
+			rlogIssue("Illegal log level in implicit trace level: '%s'", m)
+			continue
+		}
+		trace := strings.TrimSpace(tokens[1])
+		if _, err := strconv.Atoi(trace); err != nil {
+			rlogIssue("Trace level '%s' is not a number.", trace)
+			continue
+		}
+		if level <= global && level > bestLevel {
+			bestLevel = level
+			traceLevel = trace
+		}
+	}
+	return traceLevel
+}
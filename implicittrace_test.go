@@ -0,0 +1,51 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestImplicitTraceLevel checks that the trace level follows from the log
+// level, unless it was set explicitly.
+func TestImplicitTraceLevel(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.implicitTrace = "DEBUG=2, INFO=0, foo"
+	conf.logLevel = "DEBUG"
+	initialize(conf, true)
+	Trace(2, "Test Trace 1")
+	Trace(3, "Test Trace 2")
+
+	conf.logLevel = "example.go=DEBUG"
+	initialize(conf, true)
+	Trace(1, "Test Trace 3")
+
+	conf.logLevel = "DEBUG"
+	conf.traceLevel = "1"
+	initialize(conf, true)
+	Trace(2, "Test Trace 4")
+
+	conf.logLevel = "WARN"
+	conf.traceLevel = ""
+	initialize(conf, true)
+	Trace(0, "Test Trace 5")
+
+	checkLines := []string{
+		"TRACE(2) : Test Trace 1",
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// internalOutput receives the notices about problems of rlog itself. It is
+// protected by its own mutex, since notices are written in any state of
+// initMutex.
+var internalOutput struct {
+	sync.Mutex
+	writer   io.Writer // set with SetInternalOutput, nil if not set
+	name     string    // value of RLOG_INTERNAL_OUTPUT
+	file     *os.File  // open if RLOG_INTERNAL_OUTPUT names a file
+	disabled bool      // whether RLOG_INTERNAL_OUTPUT is "NONE"
+	level    int       // least severe level that is written, 0 for all
+}
+
+// SetInternalOutput directs the notices about problems of rlog itself, such
+// as errors in the configuration or lost outputs, to the writer, instead of
+// the output set with RLOG_INTERNAL_OUTPUT. This keeps them apart from the
+// messages of the program. Setting nil goes back to the configured output.
+func SetInternalOutput(writer io.Writer) {
+	internalOutput.Lock()
+	defer internalOutput.Unlock()
+	internalOutput.writer = writer
+}
+
+// updateInternalOutput applies the values of RLOG_INTERNAL_OUTPUT, which is
+// "STDERR", "STDOUT", "NONE" or the name of a file to which the notices are
+// appended, and of RLOG_INTERNAL_LEVEL. A file stays open until the setting
+// changes.
+func updateInternalOutput(name string, levelName string) {
+	level, ok := 0, true
+	if levelName != "" {
+		level, ok = levelNumbers[strings.ToUpper(strings.TrimSpace(levelName))]
+		if !ok || level == levelTrace {
+			level, ok = 0, false
+		}
+	}
+	internalOutput.Lock()
+	internalOutput.level = level
+	if name == internalOutput.name {
+		internalOutput.Unlock()
+	} else {
+		if internalOutput.file != nil {
+			internalOutput.file.Close()
+			internalOutput.file = nil
+		}
+		internalOutput.name = name
+		internalOutput.disabled = false
+		var err error
+		switch strings.ToUpper(name) {
+		case "", "STDERR", "STDOUT":
+		case "NONE":
+			internalOutput.disabled = true
+		default:
+			internalOutput.file, err = os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		}
+		internalOutput.Unlock()
+		if err != nil {
+			rlogIssue("Unable to open file for internal notices: %s", err)
+		}
+	}
+	if !ok {
+		rlogIssue("Unknown internal notice level '%s'. Ignored.", levelName)
+	}
+}
+
+// writeInternal writes a notice about a problem of rlog itself to the
+// internal output, unless the level of the notice is less severe than
+// RLOG_INTERNAL_LEVEL. If the file for the notices can't be written, stderr
+// is used instead.
+func writeInternal(level int, notice string) {
+	internalOutput.Lock()
+	defer internalOutput.Unlock()
+	if internalOutput.level != 0 && level > internalOutput.level {
+		return
+	}
+	switch {
+	case internalOutput.writer != nil:
+		io.WriteString(internalOutput.writer, notice)
+		return
+	case internalOutput.disabled:
+		return
+	case internalOutput.file != nil:
+		if _, err := internalOutput.file.WriteString(notice); err == nil {
+			return
+		}
+	case strings.ToUpper(internalOutput.name) == "STDOUT":
+		os.Stdout.WriteString(notice)
+		return
+	}
+	os.Stderr.WriteString(notice)
+}
+
+// writeNotice writes a notice of rlog about its own operation, such as a
+// change of the configuration or messages that are dropped. If an output for
+// internal notices was configured then the notice goes there, like the
+// notices about problems. Otherwise it is logged like any other message, so
+// that it is noticed along with the messages of the program. The caller needs
+// to hold at least the read lock on initMutex.
+func writeNotice(level int, msg string, fields []field) {
+	internalOutput.Lock()
+	internal := internalOutput.writer != nil || internalOutput.name != ""
+	internalOutput.Unlock()
+	if !internal {
+		writeRecord(&logRecord{
+			time:            currentTime(),
+			level:           level,
+			levelDecoration: levelStrings[level],
+			timeFormat:      settingDateTimeFormat,
+			msg:             msg,
+			fields:          fields,
+		})
+		return
+	}
+	notice := "rlog - " + strings.TrimPrefix(strings.TrimRight(msg, "\n"), "rlog: ")
+	if len(fields) > 0 {
+		notice += " " + formatFieldsText(fields)
+	}
+	writeInternal(level, notice+"\n")
+}
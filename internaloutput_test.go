@@ -0,0 +1,106 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSetInternalOutput checks that notices about problems of rlog go to the
+// writer set with SetInternalOutput.
+func TestSetInternalOutput(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	var buf bytes.Buffer
+	SetInternalOutput(&buf)
+	defer SetInternalOutput(nil)
+	conf.fatalTimeout = "soon"
+	initialize(conf, true)
+	Info("Test Info")
+
+	if !strings.HasPrefix(buf.String(), "rlog - Cannot parse fatal timeout value 'soon'.") {
+		t.Errorf("Incorrect internal output: %q", buf.String())
+	}
+	fileMatch(t, []string{"INFO     : Test Info"}, "")
+}
+
+// TestInternalOutputFile checks that notices are appended to the file named
+// in RLOG_INTERNAL_OUTPUT, or discarded if it is NONE.
+func TestInternalOutputFile(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	dir, err := ioutil.TempDir("", "rlog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer updateInternalOutput("", "")
+
+	name := filepath.Join(dir, "notices.log")
+	conf.internalOutput = name
+	conf.fatalTimeout = "soon"
+	initialize(conf, true)
+	conf.internalOutput = "none"
+	conf.fatalTimeout = "later"
+	initialize(conf, true)
+
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "rlog - Cannot parse fatal timeout value 'soon'. Using default.\n" {
+		t.Errorf("Incorrect internal notices: %q", s)
+	}
+}
+
+// TestInternalNotices checks that notices about errors in the config file go
+// to the internal output, if one was set, and that notices below the level
+// set with RLOG_INTERNAL_LEVEL are discarded.
+func TestInternalNotices(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	var buf bytes.Buffer
+	SetInternalOutput(&buf)
+	defer SetInternalOutput(nil)
+	defer updateInternalOutput("", "")
+
+	conf.confFile = writeLogfile([]string{"RLOG_LOG_LEVEL=INFO", "RLOG_NO_SUCH_SETTING=1"})
+	defer os.Remove(conf.confFile)
+	conf.confErrors = "warn"
+	initialize(conf, true)
+	Info("Test Info")
+
+	should := "rlog - Errors in config file " + conf.confFile +
+		" ignored: line 2: Unknown or illegal setting name\n"
+	if buf.String() != should {
+		t.Errorf("Incorrect internal output.\nSHOULD: %q\nIS:     %q", should, buf.String())
+	}
+	fileMatch(t, []string{"INFO     : Test Info"}, "")
+
+	buf.Reset()
+	conf.confFile = ""
+	conf.internalLevel = "ERROR"
+	conf.fatalTimeout = "soon"
+	initialize(conf, true)
+	if buf.Len() != 0 {
+		t.Errorf("Notice below the internal level written: %q", buf.String())
+	}
+}
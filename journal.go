@@ -0,0 +1,82 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// settingStreamJournal is true if the log stream is connected to the systemd
+// journal, which records the time of each line itself. Time stamps are then
+// left out of the lines written to the stream.
+var settingStreamJournal bool
+
+// settingPriorityPrefix is true if each line written to the stream starts with
+// the priority of the entry, as defined by the sd-daemon protocol.
+var settingPriorityPrefix bool
+
+// sdPriorities maps our log levels to the prefixes of the sd-daemon protocol,
+// which are the syslog severities in angle brackets.
+var sdPriorities = map[int]string{
+	levelCrit:  "<2>",
+	levelErr:   "<3>",
+	levelWarn:  "<4>",
+	levelInfo:  "<6>",
+	levelDebug: "<7>",
+	levelTrace: "<7>",
+}
+
+// streamJournal decides, based on the value of RLOG_JOURNAL, whether the given
+// log stream is treated as connected to the systemd journal. By default this
+// is detected automatically.
+func streamJournal(mode string, w io.Writer) bool {
+	switch strings.ToLower(mode) {
+	case "", "auto":
+		f, ok := w.(*os.File)
+		return ok && connectedToJournal(f)
+	default:
+		return isTrueBoolString(mode)
+	}
+}
+
+// priorityPrefix decides, based on the value of RLOG_PRIORITY_PREFIX, whether
+// lines written to the stream start with the priority of the entry. By default
+// this is done if the stream is connected to the journal.
+func priorityPrefix(mode string) bool {
+	switch strings.ToLower(mode) {
+	case "", "auto":
+		return settingStreamJournal
+	default:
+		return isTrueBoolString(mode)
+	}
+}
+
+// prefixLines puts the priority prefix for the level in front of every line
+// of the output, since the journal splits messages with several lines.
+func prefixLines(level int, output string) string {
+	prefix := sdPriorities[level]
+	lines := strings.SplitAfter(output, "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		if line != "" {
+			b.WriteString(prefix)
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// TestJournalStream checks that time stamps are left out of the stream if it
+// is connected to the journal, but not out of the logfile.
+func TestJournalStream(t *testing.T) {
+	stream, err := ioutil.TempFile("", "rlog-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(stream.Name())
+	defer stream.Close()
+	fi, err := stream.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := fi.Sys().(*syscall.Stat_t)
+	os.Setenv("JOURNAL_STREAM", fmt.Sprintf("%d:%d", st.Dev, st.Ino))
+	defer os.Unsetenv("JOURNAL_STREAM")
+
+	conf := setup()
+	defer cleanup()
+	defer func() { streamOutput = nil }()
+	conf.logNoTime = ""
+	conf.logTimeFormat = "2006"
+	streamOutput = stream
+	initialize(conf, true)
+	if !settingStreamJournal {
+		t.Fatalf("Journal not detected")
+	}
+	Info("Test Info 1")
+
+	content, err := ioutil.ReadFile(stream.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "<6>INFO     : Test Info 1\n" {
+		t.Errorf("Wrong stream output: %q", content)
+	}
+	checkLines := []string{
+		"INFO     : Test Info 1",
+	}
+	fileMatch(t, checkLines, "2006")
+
+	conf.journal = "no"
+	initialize(conf, true)
+	if settingStreamJournal {
+		t.Errorf("Journal detected, although switched off")
+	}
+	if streamJournal("auto", &strings.Builder{}) {
+		t.Errorf("Journal detected for a writer that isn't a file")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestPriorityPrefix checks that each line written to the stream starts with
+// the sd-daemon priority, while the logfile is not affected.
+func TestPriorityPrefix(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer func() { streamOutput = nil }()
+	var buf bytes.Buffer
+	streamOutput = &buf
+	conf.priorityPrefix = "yes"
+	initialize(conf, true)
+	Warn("Test Warn 1\nsecond line")
+	Error("Test Error 1")
+
+	expected := "<4>WARN     : Test Warn 1\n<4>second line\n<3>ERROR    : Test Error 1\n"
+	if buf.String() != expected {
+		t.Errorf("Wrong stream output: %q", buf.String())
+	}
+	checkLines := []string{
+		"WARN     : Test Warn 1",
+		"second line",
+		"ERROR    : Test Error 1",
+	}
+	fileMatch(t, checkLines, "")
+
+	conf.priorityPrefix = ""
+	initialize(conf, true)
+	if settingPriorityPrefix {
+		t.Errorf("Priority prefix used without the journal")
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits for the encoding of nested field values in JSON output. Maps, slices
+// and structs below jsonMaxDepth are replaced by a placeholder string, and
+// only the first jsonMaxItems elements of a map or slice are encoded.
+const (
+	jsonMaxDepth = 8
+	jsonMaxItems = 100
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// appendJSONValue appends a field value of arbitrary type as JSON. Maps,
+// slices and structs are encoded as JSON objects and arrays, within the depth
+// and size limits. Types that know how to marshal themselves do so. Values
+// that can't be represented in JSON are encoded as string, the way fmt would
+// print them.
+func appendJSONValue(b []byte, v reflect.Value, depth int) []byte {
+	if !v.IsValid() {
+		return append(b, "null"...)
+	}
+	// Values reached through unexported embedded structs can't be passed on
+	// as interface, which is only needed by marshalers and for fmt.
+	canInterface := v.CanInterface()
+	if canInterface && (v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType)) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return append(b, "null"...)
+		}
+		val, err := json.Marshal(v.Interface())
+		if err != nil {
+			return appendJSONString(b, fmt.Sprint(v.Interface()))
+		}
+		return append(b, val...)
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return append(b, "null"...)
+		}
+		return appendJSONValue(b, v.Elem(), depth)
+	case reflect.Bool:
+		return strconv.AppendBool(b, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(b, v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(b, v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return appendJSONString(b, strconv.FormatFloat(f, 'g', -1, 64))
+		}
+		return strconv.AppendFloat(b, f, 'g', -1, 64)
+	case reflect.String:
+		return appendJSONString(b, v.String())
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		if depth >= jsonMaxDepth {
+			return appendJSONString(b, "...")
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			return appendJSONMap(b, v, depth+1)
+		case reflect.Struct:
+			b = append(b, '{')
+			b, _ = appendJSONStructFields(b, v, depth+1, true)
+			return append(b, '}')
+		default:
+			if v.Kind() == reflect.Slice && v.IsNil() {
+				return append(b, "null"...)
+			}
+			if canInterface && v.Type().Elem().Kind() == reflect.Uint8 {
+				// Byte slices are base64 encoded, as by encoding/json
+				val, _ := json.Marshal(v.Interface())
+				return append(b, val...)
+			}
+			return appendJSONArray(b, v, depth+1)
+		}
+	default:
+		if !canInterface {
+			return appendJSONString(b, v.Type().String())
+		}
+		return appendJSONString(b, fmt.Sprint(v.Interface()))
+	}
+}
+
+// appendJSONArray appends a slice or array as JSON array. If there are too
+// many elements then the last one is a string with the number of elements
+// that were left out.
+func appendJSONArray(b []byte, v reflect.Value, depth int) []byte {
+	b = append(b, '[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if i == jsonMaxItems {
+			b = appendJSONString(b, fmt.Sprintf("... %d more", v.Len()-i))
+			break
+		}
+		b = appendJSONValue(b, v.Index(i), depth)
+	}
+	return append(b, ']')
+}
+
+// appendJSONMap appends a map as JSON object, with the members sorted by key.
+// If there are too many entries then a member "..." with the number of
+// entries that were left out is added.
+func appendJSONMap(b []byte, v reflect.Value, depth int) []byte {
+	if v.IsNil() {
+		return append(b, "null"...)
+	}
+	keys := make([]string, 0, v.Len())
+	values := make(map[string]reflect.Value, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key := fmt.Sprint(iter.Key())
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+	sort.Strings(keys)
+	b = append(b, '{')
+	for i, key := range keys {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if i == jsonMaxItems {
+			b = appendJSONString(b, "...")
+			b = append(b, ':')
+			b = appendJSONString(b, fmt.Sprintf("%d more", len(keys)-i))
+			break
+		}
+		b = appendJSONString(b, key)
+		b = append(b, ':')
+		b = appendJSONValue(b, values[key], depth)
+	}
+	return append(b, '}')
+}
+
+// appendJSONStructFields appends the exported fields of a struct as members
+// of a JSON object, without the braces. The names and options in json struct
+// tags are respected, and the fields of embedded structs are added as if they
+// were fields of the outer struct, as encoding/json does. The returned flag
+// is true as long as no member was added yet.
+func appendJSONStructFields(b []byte, v reflect.Value, depth int, first bool) ([]byte, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := sf.Name
+		omitEmpty := false
+		if tag, ok := sf.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			tokens := strings.Split(tag, ",")
+			if tokens[0] != "" {
+				name = tokens[0]
+			}
+			for _, opt := range tokens[1:] {
+				omitEmpty = omitEmpty || opt == "omitempty"
+			}
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && sf.Tag.Get("json") == "" {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				b, first = appendJSONStructFields(b, fv, depth, first)
+				continue
+			}
+		}
+		if sf.PkgPath != "" || (omitEmpty && fv.IsZero()) {
+			continue
+		}
+		if !first {
+			b = append(b, ',')
+		}
+		first = false
+		b = appendJSONString(b, name)
+		b = append(b, ':')
+		b = appendJSONValue(b, fv, depth)
+	}
+	return b, first
+}
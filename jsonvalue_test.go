@@ -0,0 +1,84 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type jsonTestInner struct {
+	Tags []string
+}
+
+type jsonTestPayload struct {
+	jsonTestInner
+	ID      int               `json:"id"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Skipped string            `json:"-"`
+	Started time.Time         `json:"started"`
+	secret  string
+}
+
+// TestAppendJSONValue checks the JSON encoding of nested field values.
+func TestAppendJSONValue(t *testing.T) {
+	deep := []interface{}{}
+	for i := 0; i < jsonMaxDepth; i++ {
+		deep = []interface{}{deep}
+	}
+	long := make([]int, jsonMaxItems+5)
+
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, `null`},
+		{map[string]int{"b": 2, "a": 1}, `{"a":1,"b":2}`},
+		{map[int][]string{1: {"x"}}, `{"1":["x"]}`},
+		{[]interface{}{1, "two", 3.5, true, nil}, `[1,"two",3.5,true,null]`},
+		{[]byte("hi"), `"aGk="`},
+		{jsonTestPayload{jsonTestInner: jsonTestInner{Tags: []string{"a"}}, ID: 7,
+			Skipped: "x", Started: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), secret: "s"},
+			`{"Tags":["a"],"id":7,"started":"2000-01-01T00:00:00Z"}`},
+		{deep, strings.Repeat("[", jsonMaxDepth) + `"..."` + strings.Repeat("]", jsonMaxDepth)},
+		{long, "[" + strings.Repeat("0,", jsonMaxItems) + `"... 5 more"]`},
+	}
+	for _, test := range tests {
+		s := string(appendJSONValue(nil, reflect.ValueOf(test.value), 0))
+		if s != test.expected {
+			t.Errorf("Incorrect JSON for %#v: %s, expected %s", test.value, s, test.expected)
+		}
+	}
+}
+
+// TestLogFormatJSONNestedFields checks that nested field values are written as
+// JSON structures.
+func TestLogFormatJSONNestedFields(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.logFormat = "json"
+	initialize(conf, true)
+
+	Ev().Any("req", map[string]interface{}{"ids": []int{1, 2}}).Msg("Test")
+
+	checkLines := []string{
+		`{"level":"INFO","msg":"Test","req":{"ids":[1,2]}}`,
+	}
+	fileMatch(t, checkLines, "")
+}
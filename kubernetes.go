@@ -0,0 +1,63 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"os"
+)
+
+// kubernetesEnvVars lists the environment variables that are conventionally
+// filled from the Kubernetes downward API, together with the names of the
+// fields as which they are attached to log entries.
+var kubernetesEnvVars = []struct {
+	envVar string
+	key    string
+}{
+	{"POD_NAME", "pod_name"},
+	{"POD_NAMESPACE", "pod_namespace"},
+	{"NODE_NAME", "node_name"},
+}
+
+// kubernetesFields returns fields for those of the Kubernetes metadata
+// variables that are set in the environment.
+func kubernetesFields() []field {
+	var fields []field
+	for _, v := range kubernetesEnvVars {
+		if val := os.Getenv(v.envVar); val != "" {
+			fields = append(fields, field{key: v.key, kind: fieldString, str: val})
+		}
+	}
+	return fields
+}
+
+// entryFields returns the fields for an entry logged via the given Logger,
+// which are the fields attached to every entry, followed by the Logger's own
+// fields. A Logger used by Emit already holds all fields of the entry. The
+// caller needs to hold at least the read lock on initMutex.
+func entryFields(l *Logger) []field {
+	if l != nil && l.emitted {
+		return l.fields
+	}
+	if l == nil || len(l.fields) == 0 {
+		return settingGlobalFields
+	}
+	if len(settingGlobalFields) == 0 {
+		return l.fields
+	}
+	fields := make([]field, 0, len(settingGlobalFields)+len(l.fields))
+	fields = append(fields, settingGlobalFields...)
+	return append(fields, l.fields...)
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"os"
+	"testing"
+)
+
+// TestKubernetesFields checks that the Kubernetes metadata from the
+// environment is attached to every entry, if requested.
+func TestKubernetesFields(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	os.Setenv("POD_NAME", "web-5d8f")
+	os.Setenv("POD_NAMESPACE", "shop")
+	defer os.Unsetenv("POD_NAME")
+	defer os.Unsetenv("POD_NAMESPACE")
+
+	initialize(conf, true)
+	Info("Test Info 1")
+
+	conf.k8sFields = "yes"
+	initialize(conf, true)
+	Info("Test Info 2")
+	Ev().Int("n", 1).Msg("Test Info 3")
+
+	checkLines := []string{
+		"INFO     : Test Info 1",
+		"INFO     : Test Info 2 pod_name=web-5d8f pod_namespace=shop",
+		"INFO     : Test Info 3 pod_name=web-5d8f pod_namespace=shop n=1",
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+)
+
+// lazyFunc is the type of field values that are only computed if the entry is
+// actually logged.
+type lazyFunc = func() interface{}
+
+// Lazy adds a field whose value is computed by the given function, but only
+// if the entry is actually logged. This avoids expensive computations for
+// entries that are filtered out. The same is done for values of this function
+// type passed to Any.
+func (b *EventBuilder) Lazy(key string, value func() interface{}) *EventBuilder {
+	b.fields = append(b.fields, anyField(key, value))
+	return b
+}
+
+// resolveLazyFields replaces the values of lazy fields with the result of
+// their function, so that each function is called only once, regardless of
+// the number of outputs. The fields are copied if there are lazy ones among
+// them, since they may be shared with a Logger.
+func resolveLazyFields(fields []field) []field {
+	var resolved []field
+	for i := range fields {
+		fn, ok := fields[i].any.(lazyFunc)
+		if !ok || fields[i].kind != fieldAny {
+			continue
+		}
+		if resolved == nil {
+			resolved = make([]field, len(fields))
+			copy(resolved, fields)
+		}
+		resolved[i] = anyField(fields[i].key, callLazy(fn))
+	}
+	if resolved == nil {
+		return fields
+	}
+	return resolved
+}
+
+// callLazy calls the function of a lazy field. A panic in the function is
+// reported as the value of the field, rather than letting logging fail.
+func callLazy(fn lazyFunc) (value interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			value = fmt.Sprint("PANIC: ", r)
+		}
+	}()
+	return fn()
+}
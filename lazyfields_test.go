@@ -0,0 +1,56 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestLazyFields checks that the values of lazy fields are computed once, and
+// only if the entry is logged.
+func TestLazyFields(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer func() { streamOutput = nil }()
+	var buf bytes.Buffer
+	streamOutput = &buf
+	initialize(conf, true)
+
+	calls := 0
+	value := func() interface{} {
+		calls++
+		return calls
+	}
+	Ev().Level(LevelDebug).Lazy("n", value).Msg("Test Debug 1")
+	if calls != 0 {
+		t.Errorf("Lazy field computed for a filtered entry")
+	}
+	Ev().Lazy("n", value).Any("m", func() interface{} { return "x" }).Msg("Test Info 1")
+	if calls != 1 {
+		t.Errorf("Lazy field computed %d times", calls)
+	}
+	Ev().Lazy("p", func() interface{} { panic("oops") }).Msg("Test Info 2")
+
+	checkLines := []string{
+		"INFO     : Test Info 1 n=1 m=x",
+		"INFO     : Test Info 2 p=\"PANIC: oops\"",
+	}
+	fileMatch(t, checkLines, "")
+	if buf.String() != "INFO     : Test Info 1 n=1 m=x\nINFO     : Test Info 2 p=\"PANIC: oops\"\n" {
+		t.Errorf("Wrong stream output: %q", buf.String())
+	}
+}
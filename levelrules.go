@@ -0,0 +1,94 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strings"
+)
+
+// levelRule changes the level of messages from matching files.
+type levelRule struct {
+	pattern string // shell glob to match caller file name, empty for all
+	from    int    // the level of messages this applies to
+	to      int    // the level they are logged with instead
+}
+
+// settingLevelRules holds the rules for changing the level of messages.
+var settingLevelRules []levelRule
+
+// parseLevelRules interprets the value of RLOG_LEVEL_RULES. It uses the same
+// syntax as the log level filters, but with a pair of levels.
+//
+// Format "<rule>,<rule>,[<rule>]..."
+//     rule:
+//       <pattern=from:to> | <from:to>
+//     pattern:
+//       shell glob to match caller file name
+//     from, to:
+//       messages logged at level 'from' are logged at level 'to' instead
+//
+//     Example:
+//     - "RLOG_LEVEL_RULES=noisy*.go=ERROR:WARN,db.go=WARN:ERROR"
+//       ERROR messages from files whose names start with 'noisy' are
+//       downgraded to WARN, while WARN messages from db.go are upgraded to
+//       ERROR.
+func parseLevelRules(s string) []levelRule {
+	var rules []levelRule
+	for _, r := range strings.Split(s, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		var pattern string
+		levels := r
+		if i := strings.Index(r, "="); i >= 0 {
+			pattern = strings.TrimSpace(r[:i])
+			levels = r[i+1:]
+		}
+		tokens := strings.Split(levels, ":")
+		if len(tokens) != 2 {
+			rlogIssue("Malformed level rule: '%s'", r)
+			continue
+		}
+		from, fromOk := levelNumbers[strings.ToUpper(strings.TrimSpace(tokens[0]))]
+		to, toOk := levelNumbers[strings.ToUpper(strings.TrimSpace(tokens[1]))]
+		if !fromOk || !toOk || from == levelTrace || to == levelTrace ||
+			from == levelNone || to == levelNone {
+			rlogIssue("Illegal log level in level rule: '%s'", r)
+			continue
+		}
+		rules = append(rules, levelRule{pattern, from, to})
+	}
+	return rules
+}
+
+// applyLevelRules returns the level with which a message from the given file
+// is logged. The first matching rule applies. The caller needs to hold at
+// least the read lock on initMutex.
+func applyLevelRules(filename string, level int) int {
+	for _, r := range settingLevelRules {
+		if r.from != level {
+			continue
+		}
+		if r.pattern != "" {
+			if !matchFilePattern(r.pattern, filename) {
+				continue
+			}
+		}
+		return r.to
+	}
+	return level
+}
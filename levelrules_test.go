@@ -0,0 +1,41 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestLevelRules checks that the level of messages from matching files is
+// changed before the log level filter is applied.
+func TestLevelRules(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.logLevel = "WARN"
+	conf.levelRules = "levelrules_*.go=ERROR:INFO, WARN:ERROR, foo.go=DEBUG:ERROR, INFO, X:Y"
+	initialize(conf, true)
+
+	Error("Test Error")
+	Warn("Test Warn")
+	Debug("Test Debug")
+	Critical("Test Critical")
+
+	checkLines := []string{
+		"ERROR    : Test Warn",
+		"CRITICAL : Test Critical",
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// compressFlushInterval is the maximum time compressed log output may be held
+// back in the compressor, before it is flushed to the logfile.
+const compressFlushInterval = time.Second
+
+// openLogFile creates or opens the logfile with the given name for appending.
+// If a codec was registered for the extension of the name, for example ".gz",
+// then the output is compressed.
+func openLogFile(fileName string) (io.WriteCloser, error) {
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if codec := codecFor(fileName); codec != nil {
+		cw, err := codec.NewWriter(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &compressedFileWriter{file: f, cw: cw}, nil
+	}
+	return f, nil
+}
+
+// openLogFiles opens the logfiles given as comma separated list, rotated if
+// requested. If there are several then the returned writer writes to all of
+// them. A file that can't be opened is skipped, unless it is the only one.
+func openLogFiles(fileNames string, rotation int) (io.WriteCloser, error) {
+	var files multiLogFile
+	var err error
+	for _, fileName := range strings.Split(fileNames, ",") {
+		fileName = strings.TrimSpace(fileName)
+		if fileName == "" {
+			continue
+		}
+		var f io.WriteCloser
+		if rotation == rotateNone {
+			f, err = openLogFile(fileName)
+		} else {
+			f, err = openRotatingLogFile(fileName, rotation)
+		}
+		if err != nil {
+			rlogIssue("Unable to open log file: %s", err)
+			continue
+		}
+		files = append(files, f)
+	}
+	switch len(files) {
+	case 0:
+		if err == nil {
+			err = fmt.Errorf("no log file in '%s'", fileNames)
+		}
+		return nil, err
+	case 1:
+		return files[0], nil
+	default:
+		return files, nil
+	}
+}
+
+// multiLogFile writes to several logfiles at once.
+type multiLogFile []io.WriteCloser
+
+// Write writes the data to all files. The first error is returned, but the
+// data is written to the other files nevertheless.
+func (m multiLogFile) Write(p []byte) (int, error) {
+	var err error
+	for _, f := range m {
+		if _, ferr := f.Write(p); ferr != nil && err == nil {
+			err = ferr
+		}
+	}
+	return len(p), err
+}
+
+// Sync commits all files to stable storage.
+func (m multiLogFile) Sync() error {
+	var err error
+	for _, f := range m {
+		if s, ok := f.(interface{ Sync() error }); ok {
+			if serr := s.Sync(); serr != nil && err == nil {
+				err = serr
+			}
+		}
+	}
+	return err
+}
+
+// Close closes all files.
+func (m multiLogFile) Close() error {
+	var err error
+	for _, f := range m {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// keepLogFiles returns true if the logfiles given as comma separated list are
+// the ones currently in use, with the same rotation, and should be kept open,
+// because one of them is compressed. The caller needs to hold at least the
+// read lock on initMutex.
+func keepLogFiles(fileNames string, rotation int) bool {
+	if currentLogFile == nil || logWriterFile == nil ||
+		fileNames != currentLogFileName || rotation != currentLogRotation {
+		return false
+	}
+	for _, fileName := range strings.Split(fileNames, ",") {
+		if codecFor(strings.TrimSpace(fileName)) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// syncLogFile makes sure that everything written to the logfile so far has
+// been committed to storage. The caller needs to hold at least the read lock
+// on initMutex.
+func syncLogFile() {
+	if f, ok := currentLogFile.(interface{ Sync() error }); ok {
+		f.Sync()
+	}
+}
+
+// closeLogFile closes the logfile currently in use, if there is one. The
+// caller needs to hold the write lock on initMutex.
+func closeLogFile() {
+	if currentLogFile != nil {
+		currentLogFile.Close()
+		currentLogFile = nil
+	}
+	currentLogFileName = ""
+}
+
+// compressedFileWriter compresses everything that is written to it into a
+// file. Every time the logfile is opened a new compressed stream is appended
+// to the file. For gzip this is allowed by the format and transparently
+// handled by the usual tools, such as zcat or zgrep.
+//
+// Compression works best if output is not flushed after each log line.
+// Therefore, the output is flushed at the latest compressFlushInterval after
+// it was written, so that a reader of the file never lags far behind.
+type compressedFileWriter struct {
+	mutex      sync.Mutex
+	file       *os.File
+	cw         CompressWriter // nil once closed
+	flushTimer *time.Timer    // pending flush, nil if nothing is pending
+}
+
+// Write compresses the data and schedules a flush, if none is pending yet.
+func (w *compressedFileWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.cw == nil {
+		return 0, os.ErrClosed
+	}
+	n, err := w.cw.Write(p)
+	if err == nil && w.flushTimer == nil {
+		w.flushTimer = time.AfterFunc(compressFlushInterval, w.flush)
+	}
+	return n, err
+}
+
+// flush writes all pending compressed output to the file.
+func (w *compressedFileWriter) flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.flushTimer = nil
+	if w.cw != nil {
+		w.cw.Flush()
+	}
+}
+
+// Sync writes all pending compressed output to the file and commits the file
+// to stable storage.
+func (w *compressedFileWriter) Sync() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.cw == nil {
+		return os.ErrClosed
+	}
+	if err := w.cw.Flush(); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Close writes the remaining output, terminates the compressed stream and
+// closes the file.
+func (w *compressedFileWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.cw == nil {
+		return os.ErrClosed
+	}
+	if w.flushTimer != nil {
+		w.flushTimer.Stop()
+		w.flushTimer = nil
+	}
+	err := w.cw.Close()
+	w.cw = nil
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestGzipLogFile checks that a logfile name ending in ".gz" results in
+// compressed output, which is kept open when the configuration is re-read,
+// and remains readable after the file was re-opened.
+func TestGzipLogFile(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	logfile += ".gz"
+	conf.logFile = logfile
+	initialize(conf, true)
+	Info("Test Info 1")
+
+	// Re-initializing with the same name keeps the file open.
+	initialize(conf, true)
+	Info("Test Info 2")
+
+	// Closing and opening the file again appends a new gzip member.
+	conf.logFile = ""
+	initialize(conf, true)
+	conf.logFile = logfile
+	initialize(conf, true)
+	Info("Test Info 3")
+
+	// Close the logfile, which flushes the compressed output
+	conf.logFile = ""
+	initialize(conf, true)
+
+	f, err := os.Open(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var members []string
+	for {
+		gz.Multistream(false)
+		content, err := ioutil.ReadAll(gz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		members = append(members, string(content))
+		if err := gz.Reset(r); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	should := []string{
+		"INFO     : Test Info 1\nINFO     : Test Info 2\n",
+		"INFO     : Test Info 3\n",
+	}
+	if len(members) != len(should) {
+		t.Fatalf("Incorrect number of gzip members: %q", members)
+	}
+	for i := range should {
+		if members[i] != should[i] {
+			t.Errorf("Incorrect compressed output.\nSHOULD: %s\nIS:     %s\n", should[i], members[i])
+		}
+	}
+}
+
+// TestMultipleLogFiles checks that output is written to every file given in
+// the comma separated list.
+func TestMultipleLogFiles(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	second := logfile + ".2"
+	defer os.Remove(second)
+
+	conf.logFile = logfile + ", " + second + ",/nonexistent/dir/rlog.log"
+	initialize(conf, true)
+	Info("Test Info")
+	conf.logFile = ""
+	initialize(conf, true)
+
+	for _, name := range []string{logfile, second} {
+		content, err := ioutil.ReadFile(name)
+		if err != nil || string(content) != "INFO     : Test Info\n" {
+			t.Errorf("Incorrect content of %s: '%s' (%v)", name, content, err)
+		}
+	}
+}
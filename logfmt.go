@@ -0,0 +1,130 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// formatRecordLogfmt formats a record as a line of key=value pairs, for
+// example
+//
+//     time=2024-06-13T12:04:33Z level=TRACE trace=2 topic=cache msg="Evicted 3 entries"
+//
+// Like in JSON output, the trace level and the topic of trace messages are
+// values of their own, so that log backends can query them.
+func formatRecordLogfmt(r *logRecord) string {
+	var b []byte
+	if r.timeFormat != "" {
+		b = append(b, "time="...)
+		b = appendLogfmtString(b, recordTime(r).Format(strings.TrimSuffix(r.timeFormat, " ")))
+		b = append(b, ' ')
+	}
+	b = append(b, "level="...)
+	if r.level == levelTrace {
+		b = append(b, levelStrings[levelTrace]...)
+		b = append(b, " trace="...)
+		b = strconv.AppendInt(b, int64(r.traceLevel), 10)
+		if r.topic != "" {
+			b = append(b, " topic="...)
+			b = appendLogfmtString(b, r.topic)
+		}
+	} else {
+		b = appendLogfmtString(b, r.levelDecoration)
+	}
+	if r.caller != nil && !settingTestMode {
+		b = append(b, " pid="...)
+		b = strconv.AppendInt(b, int64(os.Getpid()), 10)
+		if r.goroutineID != 0 {
+			b = append(b, " goroutine="...)
+			b = strconv.AppendUint(b, r.goroutineID, 10)
+		}
+		if r.threadID != 0 {
+			b = append(b, " thread="...)
+			b = strconv.AppendInt(b, int64(r.threadID), 10)
+		}
+	}
+	if settingMonotonic {
+		b = append(b, " mono="...)
+		b = strconv.AppendInt(b, int64(r.time.Sub(processStart)), 10)
+	}
+	if r.caller != nil {
+		b = append(b, " caller="...)
+		if settingTestMode {
+			b = appendLogfmtString(b, r.caller.moduleAndFileName)
+		} else {
+			b = appendLogfmtString(b, fmt.Sprintf("%s:%d", r.caller.moduleAndFileName, r.caller.line))
+		}
+		b = append(b, " func="...)
+		b = appendLogfmtString(b, r.caller.funcName)
+	}
+	b = append(b, " msg="...)
+	b = appendLogfmtString(b, messageWithoutTopic(r))
+	for i := range r.fields {
+		b = append(b, ' ')
+		b = append(b, r.fields[i].key...)
+		b = append(b, '=')
+		b = appendFieldValueLogfmt(b, &r.fields[i])
+	}
+	return string(append(b, '\n'))
+}
+
+// messageWithoutTopic returns the message of a record, without the topic
+// that a TraceLogger puts in front of it, for the formats in which the topic
+// is a value of its own.
+func messageWithoutTopic(r *logRecord) string {
+	msg := strings.TrimRight(r.msg, "\n")
+	if r.topic != "" {
+		msg = strings.TrimPrefix(msg, r.topic+": ")
+	}
+	return msg
+}
+
+// appendFieldValueLogfmt appends the value of a field for logfmt output.
+func appendFieldValueLogfmt(b []byte, f *field) []byte {
+	switch f.kind {
+	case fieldString:
+		return appendLogfmtString(b, f.str)
+	case fieldInt, fieldUint, fieldFloat, fieldBool:
+		return appendFieldValueText(b, f)
+	case fieldDuration:
+		if settingCanonicalTimes {
+			return append(b, isoDuration(time.Duration(f.num))...)
+		}
+		return append(b, time.Duration(f.num).String()...)
+	default:
+		if s, ok := canonicalValue(f.any); ok && settingCanonicalTimes {
+			return appendLogfmtString(b, s)
+		}
+		return appendLogfmtString(b, fmt.Sprint(f.any))
+	}
+}
+
+// appendLogfmtString appends a string value for logfmt output. It is quoted
+// if it is empty or contains spaces, quotes, '=' or control characters.
+func appendLogfmtString(b []byte, s string) []byte {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || unicode.IsControl(r)
+	}) >= 0 {
+		return strconv.AppendQuote(b, s)
+	}
+	return append(b, s...)
+}
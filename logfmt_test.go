@@ -0,0 +1,44 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestLogFormatLogfmt checks the logfmt output format, in which trace level
+// and topic are values of their own.
+func TestLogFormatLogfmt(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.logFormat = "logfmt"
+	conf.traceLevel = "1"
+	initialize(conf, true)
+
+	Info("Test \"Info\"")
+	Ev().Str("user", "alice smith").Int("n", 2).Msg("Test Info 2")
+	Tracef(1, "Trace %d", 1)
+	TraceAt(0).Topic("cache").Printf("Evicted %d", 3)
+
+	checkLines := []string{
+		`level=INFO msg="Test \"Info\""`,
+		`level=INFO msg="Test Info 2" user="alice smith" n=2`,
+		`level=TRACE trace=1 msg="Trace 1"`,
+		`level=TRACE trace=0 topic=cache msg="Evicted 3"`,
+	}
+	fileMatch(t, checkLines, "")
+}
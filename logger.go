@@ -0,0 +1,206 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import "time"
+
+// Logger provides the same log functions as the package itself, but with
+// additional properties that apply to all messages logged through it. A nil
+// Logger behaves exactly like the package level log functions.
+type Logger struct {
+	name          string        // package path for filtering, set by ForPackage
+	sampleKey     string        // key on which the sampling decision is based
+	fields        []field       // attached to every message logged through this
+	hasTimeFormat bool          // whether timeFormat replaces the configured one
+	timeFormat    string        // time stamp layout plus space, empty for none
+	withCaller    bool          // whether caller info is shown, regardless of config
+	topic         string        // topic of trace messages, set by TraceLogger
+	writeTimeout  time.Duration // replaces the configured write timeouts
+	emitted       bool          // logs an entry passed to Emit, with all fields
+	panicReport   bool          // logs a panic, whose crash report is written anyway
+}
+
+// WithSampleKey returns a Logger whose messages are sampled based on the
+// given key, for example a request or trace ID. If sampling is enabled, the
+// decision to log is then made once per key, rather than per message, so that
+// either all or none of the messages for a request are kept.
+func WithSampleKey(key string) *Logger {
+	return (*Logger)(nil).WithSampleKey(key)
+}
+
+// WithSampleKey returns a copy of the Logger, whose messages are sampled based
+// on the given key. See the package level WithSampleKey function for details.
+func (l *Logger) WithSampleKey(key string) *Logger {
+	nl := l.clone()
+	nl.sampleKey = key
+	return nl
+}
+
+// WithTimeFormat returns a Logger whose messages show time stamps in the given
+// format, instead of the format set with RLOG_TIME_FORMAT. The format is one
+// of the names allowed in RLOG_TIME_FORMAT, such as "Kitchen", or a time
+// layout. An empty format means that the messages have no time stamp.
+func WithTimeFormat(format string) *Logger {
+	return (*Logger)(nil).WithTimeFormat(format)
+}
+
+// WithTimeFormat returns a copy of the Logger, whose messages show time stamps
+// in the given format. See the package level WithTimeFormat function for
+// details.
+func (l *Logger) WithTimeFormat(format string) *Logger {
+	nl := l.clone()
+	nl.hasTimeFormat = true
+	nl.timeFormat = ""
+	if format != "" {
+		nl.timeFormat = timeLayout(format) + " "
+	}
+	return nl
+}
+
+// WithCaller returns a Logger whose messages always contain caller info, even
+// if RLOG_CALLER_INFO is not set. This is useful for important messages, whose
+// location in the code should be known, without the cost of collecting caller
+// info for every message.
+func WithCaller() *Logger {
+	return (*Logger)(nil).WithCaller()
+}
+
+// WithCaller returns a copy of the Logger, whose messages always contain
+// caller info. See the package level WithCaller function for details.
+func (l *Logger) WithCaller() *Logger {
+	nl := l.clone()
+	nl.withCaller = true
+	return nl
+}
+
+// WithWriteTimeout returns a Logger whose messages are written to each output
+// within the given time, regardless of RLOG_WRITE_TIMEOUT. If a write takes
+// longer then it is abandoned and the message is counted as dropped. This
+// bounds the latency that logging adds to time critical code. A timeout of 0
+// means that the configured write timeouts apply.
+func WithWriteTimeout(d time.Duration) *Logger {
+	return (*Logger)(nil).WithWriteTimeout(d)
+}
+
+// WithWriteTimeout returns a copy of the Logger, whose messages are written to
+// each output within the given time. See the package level WithWriteTimeout
+// function for details.
+func (l *Logger) WithWriteTimeout(d time.Duration) *Logger {
+	nl := l.clone()
+	nl.writeTimeout = d
+	return nl
+}
+
+// clone returns a copy of the Logger, or a new Logger if it is nil.
+func (l *Logger) clone() *Logger {
+	nl := &Logger{}
+	if l != nil {
+		*nl = *l
+	}
+	return nl
+}
+
+// Trace is for low level tracing of activities. See the package level Trace
+// function for details.
+func (l *Logger) Trace(traceLevel int, a ...interface{}) {
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	if traceEnabled() {
+		prefixAddition := tracePrefix(traceLevel)
+		basicLog(l, levelTrace, traceLevel, true, "", prefixAddition, a...)
+	}
+}
+
+// Tracef prints trace messages, with formatting.
+func (l *Logger) Tracef(traceLevel int, format string, a ...interface{}) {
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	if traceEnabled() {
+		prefixAddition := tracePrefix(traceLevel)
+		basicLog(l, levelTrace, traceLevel, true, format, prefixAddition, a...)
+	}
+}
+
+// Debug prints a message if RLOG_LEVEL is set to DEBUG.
+func (l *Logger) Debug(a ...interface{}) {
+	basicLog(l, levelDebug, notATrace, false, "", "", a...)
+}
+
+// Debugf prints a message if RLOG_LEVEL is set to DEBUG, with formatting.
+func (l *Logger) Debugf(format string, a ...interface{}) {
+	basicLog(l, levelDebug, notATrace, false, format, "", a...)
+}
+
+// Info prints a message if RLOG_LEVEL is set to INFO or lower.
+func (l *Logger) Info(a ...interface{}) {
+	basicLog(l, levelInfo, notATrace, false, "", "", a...)
+}
+
+// Infof prints a message if RLOG_LEVEL is set to INFO or lower, with
+// formatting.
+func (l *Logger) Infof(format string, a ...interface{}) {
+	basicLog(l, levelInfo, notATrace, false, format, "", a...)
+}
+
+// Warn prints a message if RLOG_LEVEL is set to WARN or lower.
+func (l *Logger) Warn(a ...interface{}) {
+	basicLog(l, levelWarn, notATrace, false, "", "", a...)
+}
+
+// Warnf prints a message if RLOG_LEVEL is set to WARN or lower, with
+// formatting.
+func (l *Logger) Warnf(format string, a ...interface{}) {
+	basicLog(l, levelWarn, notATrace, false, format, "", a...)
+}
+
+// Error prints a message if RLOG_LEVEL is set to ERROR or lower.
+func (l *Logger) Error(a ...interface{}) {
+	basicLog(l, levelErr, notATrace, false, "", "", a...)
+}
+
+// Errorf prints a message if RLOG_LEVEL is set to ERROR or lower, with
+// formatting.
+func (l *Logger) Errorf(format string, a ...interface{}) {
+	basicLog(l, levelErr, notATrace, false, format, "", a...)
+}
+
+// Fatal prints a message at CRITICAL level and then exits the program.
+func (l *Logger) Fatal(a ...interface{}) {
+	e := startFatal()
+	basicLog(l, levelCrit, notATrace, false, "", "", a...)
+	e.finish()
+}
+
+// Fatalf prints a message at CRITICAL level, with formatting, and then exits
+// the program.
+func (l *Logger) Fatalf(format string, a ...interface{}) {
+	e := startFatal()
+	basicLog(l, levelCrit, notATrace, false, format, "", a...)
+	e.finish()
+}
+
+// Critical prints a message if RLOG_LEVEL is set to CRITICAL or lower.
+func (l *Logger) Critical(a ...interface{}) {
+	basicLog(l, levelCrit, notATrace, false, "", "", a...)
+}
+
+// Criticalf prints a message if RLOG_LEVEL is set to CRITICAL or lower, with
+// formatting.
+func (l *Logger) Criticalf(format string, a ...interface{}) {
+	basicLog(l, levelCrit, notATrace, false, format, "", a...)
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLoggerTimeFormat checks that a Logger can have its own time format.
+func TestLoggerTimeFormat(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer SetClock(nil)
+	conf.logNoTime = "false"
+	conf.logTimeFormat = "RFC3339"
+	conf.timeUTC = "yes"
+	initialize(conf, true)
+	SetClock(func() time.Time {
+		return time.Date(2020, 2, 29, 15, 4, 0, 0, time.UTC)
+	})
+
+	Info("Test Info")
+	WithTimeFormat("Kitchen").Info("Test Info")
+	WithSampleKey("req-1").WithTimeFormat("").Info("Test Info")
+	WithTimeFormat("15:04:05.000").Info("Test Info")
+
+	checkLines := []string{
+		"2020-02-29T15:04:00Z INFO     : Test Info",
+		"3:04PM INFO     : Test Info",
+		"INFO     : Test Info",
+		"15:04:00.000 INFO     : Test Info",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestLoggerWithCaller checks that caller info can be forced on for single
+// messages and for messages of a given level.
+func TestLoggerWithCaller(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.callerInfoLevel = "ERROR"
+	initialize(conf, true)
+	SetTestMode(true)
+	defer SetTestMode(false)
+
+	Info("Test Info")
+	WithCaller().Info("Test Info")
+	WithSampleKey("req-1").WithCaller().Warn("Test Warn")
+	Error("Test Error")
+	Critical("Test Critical")
+
+	checkLines := []string{
+		"INFO     : Test Info",
+		"INFO     : [logger_test.go (rlog.TestLoggerWithCaller)] Test Info",
+		"WARN     : [logger_test.go (rlog.TestLoggerWithCaller)] Test Warn",
+		"ERROR    : [logger_test.go (rlog.TestLoggerWithCaller)] Test Error",
+		"CRITICAL : [logger_test.go (rlog.TestLoggerWithCaller)] Test Critical",
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// settingMaxBufferBytes is the memory budget for log output that is held in
+// buffers, or 0 if there is no limit.
+var settingMaxBufferBytes int
+
+var (
+	// queueLimit is the part of the memory budget for the lines that wait in
+	// the queues of tees and sinks, or 0 if there is no limit. Accessed
+	// atomically, since the queues are written without holding initMutex.
+	queueLimit int64
+	// queuedBytes is the total length of the lines that wait in the queues of
+	// tees and sinks. Accessed atomically.
+	queuedBytes int64
+)
+
+// parseMaxBufferMB interprets the value of RLOG_MAX_BUFFER_MB, which is a
+// number of megabytes, possibly with a fraction, such as "0.5". It returns
+// the budget in bytes.
+func parseMaxBufferMB(s string) int {
+	if s == "" {
+		return 0
+	}
+	mb, err := strconv.ParseFloat(s, 64)
+	if err != nil || mb < 0 {
+		rlogIssue("Cannot parse max buffer size '%s'. Ignored.", s)
+		return 0
+	}
+	return int(mb * 1024 * 1024)
+}
+
+// streamBufferBytes returns the size of the buffer for the log stream. With
+// a memory budget, the stream buffer gets a quarter of it, but not more than
+// its usual size. The caller needs to hold at least the read lock on
+// initMutex.
+func streamBufferBytes() int {
+	if settingMaxBufferBytes > 0 && settingMaxBufferBytes/4 < streamBufferSize {
+		return settingMaxBufferBytes / 4
+	}
+	return streamBufferSize
+}
+
+// queueBytes returns the limit for the total length of the lines that wait in
+// the queues of tees and sinks, which is a quarter of the memory budget, or 0
+// if there is no limit. The caller needs to hold at least the read lock on
+// initMutex.
+func queueBytes() int {
+	return settingMaxBufferBytes / 4
+}
+
+// recentEntriesBytes returns the limit for the total length of the log lines
+// that are kept in memory, which is the part of the memory budget that is
+// not reserved for the stream buffer and the queues, or 0 if there is no
+// limit. The caller needs to hold at least the read lock on initMutex.
+func recentEntriesBytes() int {
+	if settingMaxBufferBytes == 0 {
+		return 0
+	}
+	return settingMaxBufferBytes - streamBufferBytes() - queueBytes()
+}
+
+// setMaxBufferBytes sets the memory budget. The caller needs to hold the
+// lock on initMutex.
+func setMaxBufferBytes(n int) {
+	settingMaxBufferBytes = n
+	atomic.StoreInt64(&queueLimit, int64(queueBytes()))
+}
+
+// reserveQueued charges a line of the given length to the budget of the
+// queues. It returns false, without charging it, if the line would exceed the
+// budget.
+func reserveQueued(n int) bool {
+	limit := atomic.LoadInt64(&queueLimit)
+	if atomic.AddInt64(&queuedBytes, int64(n)) > limit && limit > 0 {
+		atomic.AddInt64(&queuedBytes, -int64(n))
+		return false
+	}
+	return true
+}
+
+// releaseQueued returns the length of a line that left a queue to the budget
+// of the queues.
+func releaseQueued(n int) {
+	atomic.AddInt64(&queuedBytes, -int64(n))
+}
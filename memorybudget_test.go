@@ -0,0 +1,51 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestMaxBufferBudget checks how the memory budget is divided between the
+// stream buffer, the queues of tees and sinks and the recent entries.
+func TestMaxBufferBudget(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	tests := []struct {
+		spec        string
+		streamBytes int
+		queueBytes  int
+		recentBytes int
+	}{
+		{"", streamBufferSize, 0, 0},
+		{"1", streamBufferSize, 256 * 1024, 768*1024 - streamBufferSize},
+		{"0.125", 32 * 1024, 32 * 1024, 64 * 1024},
+		{"lots", streamBufferSize, 0, 0},
+	}
+	for _, test := range tests {
+		conf.maxBufferMB = test.spec
+		initialize(conf, true)
+		if n := streamBufferBytes(); n != test.streamBytes {
+			t.Errorf("Incorrect stream buffer size for %q: %d", test.spec, n)
+		}
+		if n := queueBytes(); n != test.queueBytes {
+			t.Errorf("Incorrect queue limit for %q: %d", test.spec, n)
+		}
+		if n := recentEntriesBytes(); n != test.recentBytes {
+			t.Errorf("Incorrect recent entries limit for %q: %d", test.spec, n)
+		}
+	}
+}
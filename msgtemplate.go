@@ -0,0 +1,163 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Args holds the values for the named placeholders of a message template.
+type Args map[string]interface{}
+
+// renderTemplate replaces the placeholders like "{user}" in a message template
+// with the values of the args. The args are also returned as fields, in the
+// order in which they appear in the template, followed by the args that don't
+// appear in it, sorted by name. Placeholders without value are kept as they
+// are, and "{{" and "}}" stand for a literal "{" and "}".
+func renderTemplate(template string, args Args) (string, []field) {
+	var b strings.Builder
+	fields := make([]field, 0, len(args))
+	used := make(map[string]bool, len(args))
+	for {
+		start := strings.IndexAny(template, "{}")
+		if start < 0 {
+			b.WriteString(template)
+			break
+		}
+		b.WriteString(template[:start])
+		template = template[start:]
+		if strings.HasPrefix(template, "{{") || strings.HasPrefix(template, "}}") {
+			b.WriteByte(template[0])
+			template = template[2:]
+			continue
+		}
+		if template[0] == '}' {
+			b.WriteByte('}')
+			template = template[1:]
+			continue
+		}
+		end := strings.IndexByte(template, '}')
+		if end < 0 {
+			b.WriteString(template)
+			break
+		}
+		name := template[1:end]
+		value, ok := args[name]
+		if !ok {
+			b.WriteString(template[:end+1])
+		} else {
+			fmt.Fprint(&b, value)
+			if !used[name] {
+				used[name] = true
+				fields = append(fields, anyField(name, value))
+			}
+		}
+		template = template[end+1:]
+	}
+
+	var rest []string
+	for name := range args {
+		if !used[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		fields = append(fields, anyField(name, args[name]))
+	}
+	return b.String(), fields
+}
+
+// templateLog logs a message template at the given level. The template is
+// only rendered, and the Logger only copied to add the args as fields, if the
+// message passes the filters.
+func templateLog(l *Logger, logLevel int, template string, args Args) {
+	now := currentTime()
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	checkConfigFile()
+
+	caller := getCaller(3)
+	level, ok := filterEntry(l, logLevel, notATrace, &caller)
+	if !ok {
+		countSuppressed(caller.moduleAndFileName, level, notATrace)
+		return
+	}
+	msg, fields := renderTemplate(template, args)
+	nl := l.clone()
+	n := len(nl.fields)
+	nl.fields = append(nl.fields[:n:n], fields...)
+	logFiltered(now, nl, level, notATrace, caller, "%s\n", "", msg)
+}
+
+// DebugT logs a message template at DEBUG level. The placeholders in the
+// template, such as "{user}", are replaced with the values of the args in the
+// message, and the args are added as fields, for example:
+//
+//     rlog.InfoT("user {user} logged in from {ip}", rlog.Args{"user": u, "ip": ip})
+func DebugT(template string, args Args) {
+	templateLog(nil, levelDebug, template, args)
+}
+
+// InfoT logs a message template at INFO level. See DebugT for details.
+func InfoT(template string, args Args) {
+	templateLog(nil, levelInfo, template, args)
+}
+
+// WarnT logs a message template at WARN level. See DebugT for details.
+func WarnT(template string, args Args) {
+	templateLog(nil, levelWarn, template, args)
+}
+
+// ErrorT logs a message template at ERROR level. See DebugT for details.
+func ErrorT(template string, args Args) {
+	templateLog(nil, levelErr, template, args)
+}
+
+// CriticalT logs a message template at CRITICAL level. See DebugT for
+// details.
+func CriticalT(template string, args Args) {
+	templateLog(nil, levelCrit, template, args)
+}
+
+// DebugT logs a message template at DEBUG level. See the package level DebugT
+// function for details.
+func (l *Logger) DebugT(template string, args Args) {
+	templateLog(l, levelDebug, template, args)
+}
+
+// InfoT logs a message template at INFO level.
+func (l *Logger) InfoT(template string, args Args) {
+	templateLog(l, levelInfo, template, args)
+}
+
+// WarnT logs a message template at WARN level.
+func (l *Logger) WarnT(template string, args Args) {
+	templateLog(l, levelWarn, template, args)
+}
+
+// ErrorT logs a message template at ERROR level.
+func (l *Logger) ErrorT(template string, args Args) {
+	templateLog(l, levelErr, template, args)
+}
+
+// CriticalT logs a message template at CRITICAL level.
+func (l *Logger) CriticalT(template string, args Args) {
+	templateLog(l, levelCrit, template, args)
+}
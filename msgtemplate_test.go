@@ -0,0 +1,99 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestRenderTemplate checks the replacement of placeholders in message
+// templates.
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		args     Args
+		msg      string
+		keys     string
+	}{
+		{"user {user} logged in from {ip}", Args{"user": "bob", "ip": "10.0.0.1"},
+			"user bob logged in from 10.0.0.1", "user,ip"},
+		{"{n} of {n} {missing}", Args{"n": 3, "b": 1, "a": 2},
+			"3 of 3 {missing}", "n,a,b"},
+		{"{{literal} {unterminated", nil, "{literal} {unterminated", ""},
+		{"{{literal}} {{{n}}}", Args{"n": 1}, "{literal} {1}", "n"},
+	}
+	for _, test := range tests {
+		msg, fields := renderTemplate(test.template, test.args)
+		keys := ""
+		for i, f := range fields {
+			if i > 0 {
+				keys += ","
+			}
+			keys += f.key
+		}
+		if msg != test.msg || keys != test.keys {
+			t.Errorf("Incorrect rendering of '%s': '%s' with fields %s",
+				test.template, msg, keys)
+		}
+	}
+}
+
+// TestInfoT checks that message templates are logged with their args as
+// fields.
+func TestInfoT(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	InfoT("user {user} logged in from {ip}", Args{"user": "bob", "ip": "10.0.0.1"})
+	DebugT("Test Debug {n}", Args{"n": 1})
+	WithSampleKey("x").WarnT("{n} retries", Args{"n": 2})
+
+	checkLines := []string{
+		"INFO     : user bob logged in from 10.0.0.1 user=bob ip=10.0.0.1",
+		"WARN     : 2 retries n=2",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// countingStringer counts how often it is formatted.
+type countingStringer struct {
+	calls int
+}
+
+func (c *countingStringer) String() string {
+	c.calls++
+	return "value"
+}
+
+// TestTemplateFiltered checks that templates of messages, which don't pass
+// the filters, are not rendered.
+func TestTemplateFiltered(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	c := &countingStringer{}
+	DebugT("Test Debug {c}", Args{"c": c})
+	WithSampleKey("x").DebugT("Test Debug {c}", Args{"c": c})
+	if c.calls != 0 {
+		t.Errorf("Template of filtered message rendered %d times", c.calls)
+	}
+	InfoT("Test Info {c}", Args{"c": c})
+	if c.calls == 0 {
+		t.Error("Template of logged message not rendered")
+	}
+}
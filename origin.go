@@ -0,0 +1,48 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+var (
+	settingFileOrigin bool   // whether file output shows where it came from
+	fileSequence      uint64 // number of entries written to the logfile
+)
+
+// originRecord returns a copy of the record with additional fields, which
+// tell where a line in the logfile came from: The process ID (unless caller
+// info already shows it), the name of the log stream that the entry was also
+// written to, if any, and a sequence number. This keeps the lines attributable
+// if several processes append to the same logfile. The caller needs to hold
+// at least the read lock on initMutex.
+func originRecord(r *logRecord) *logRecord {
+	or := *r
+	or.fields = make([]field, 0, len(r.fields)+3)
+	or.fields = append(or.fields, r.fields...)
+	if r.caller == nil {
+		or.fields = append(or.fields, intField("pid", int64(os.Getpid())))
+	}
+	if logWriterStream != nil {
+		or.fields = append(or.fields, field{key: "stream", kind: fieldString,
+			str: settingStreamName})
+	}
+	seq := atomic.AddUint64(&fileSequence, 1)
+	or.fields = append(or.fields, field{key: "seq", kind: fieldUint, num: int64(seq)})
+	return &or
+}
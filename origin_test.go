@@ -0,0 +1,47 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestFileOrigin checks that the origin of entries is shown in the logfile,
+// if requested.
+func TestFileOrigin(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.fileOrigin = "yes"
+	initialize(conf, true)
+	fileSequence = 0
+
+	Info("Test Info")
+	Ev().Str("user", "joe").Msg("Test Info")
+
+	conf.logFormat = "json"
+	initialize(conf, true)
+	Info("Test Info")
+
+	pid := os.Getpid()
+	checkLines := []string{
+		fmt.Sprintf("INFO     : Test Info pid=%d seq=1", pid),
+		fmt.Sprintf("INFO     : Test Info user=joe pid=%d seq=2", pid),
+		fmt.Sprintf(`{"level":"INFO","msg":"Test Info","pid":%d,"seq":3}`, pid),
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,688 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The supported output formats
+const (
+	formatText = iota
+	formatJSON
+	formatDocker
+	formatTemplate
+	formatGlog
+	formatLogfmt
+)
+
+// The quoting styles for messages in text output.
+const (
+	quoteNone = iota
+	quoteGo
+	quoteSingle
+)
+
+var (
+	settingLogFormat  int  // the output format
+	settingColor      bool // whether levels are shown in color on the stream
+	settingTimeUTC    bool // whether time stamps are shown in UTC
+	settingQuote      int  // how messages are quoted in text output
+	settingMonotonic  bool // whether JSON output contains monotonic time
+	settingSortFields bool // whether fields in text output are sorted by key
+	// longer output lines are split, 0 for no limit
+	settingMaxLineLength int
+	// fields attached to every entry, such as Kubernetes metadata
+	settingGlobalFields []field
+	// receives entries instead of the outputs, for example a slog.Handler
+	settingBackend func(r *logRecord, traceLevel int)
+	// name of the log stream, as shown in the Docker output format
+	settingStreamName string
+	// formats of the stream and the logfile, nil for the general format
+	settingStreamFormat *outputFormat
+	settingFileFormat   *outputFormat
+)
+
+// minLineChunk is the minimum number of message bytes in each line when a
+// long message is split, even if that means exceeding the line length limit.
+const minLineChunk = 16
+
+// ANSI color codes for the log levels, used in text output if requested.
+var levelColors = map[int]string{
+	levelCrit:  "\x1b[1;31m",
+	levelErr:   "\x1b[31m",
+	levelWarn:  "\x1b[33m",
+	levelInfo:  "\x1b[32m",
+	levelDebug: "\x1b[36m",
+	levelTrace: "\x1b[35m",
+}
+
+const colorReset = "\x1b[0m"
+
+// logRecord holds all the information that makes up a single log entry,
+// before it is formatted for output.
+type logRecord struct {
+	time            time.Time
+	level           int
+	levelDecoration string        // level name, plus trace level for traces
+	traceLevel      int           // trace level, only for TRACE records
+	topic           string        // topic of a trace message, if any
+	timeFormat      string        // time stamp layout plus space, empty for none
+	caller          *callerData   // nil if no caller info should be shown
+	goroutineID     uint64        // 0 if the goroutine ID should not be shown
+	threadID        int           // 0 if the OS thread ID should not be shown
+	msg             string        // the message, usually with trailing newline
+	fields          []field       // additional key/value pairs
+	writeTimeout    time.Duration // replaces the configured write timeouts
+}
+
+// The kinds of values a field may hold. Values of the well known types are
+// stored and formatted without the use of reflection.
+const (
+	fieldAny = iota
+	fieldString
+	fieldInt
+	fieldUint
+	fieldFloat
+	fieldBool
+	fieldDuration
+)
+
+// field is a key/value pair, which is attached to a log entry in addition to
+// the message. Depending on the kind, the value is stored in one of str, num,
+// fl or any.
+type field struct {
+	key  string
+	kind int
+	str  string
+	num  int64
+	fl   float64
+	any  interface{}
+}
+
+// anyField returns a field holding a value of arbitrary type.
+func anyField(key string, value interface{}) field {
+	return field{key: key, kind: fieldAny, any: value}
+}
+
+// intField returns a field holding an integer.
+func intField(key string, value int64) field {
+	return field{key: key, kind: fieldInt, num: value}
+}
+
+// appendFieldValueText appends the text representation of a field value.
+// Strings that contain spaces, quotes or '=' characters are quoted.
+func appendFieldValueText(b []byte, f *field) []byte {
+	switch f.kind {
+	case fieldString:
+		return appendTextString(b, f.str)
+	case fieldInt:
+		return strconv.AppendInt(b, f.num, 10)
+	case fieldUint:
+		return strconv.AppendUint(b, uint64(f.num), 10)
+	case fieldFloat:
+		return strconv.AppendFloat(b, f.fl, 'g', -1, 64)
+	case fieldBool:
+		return strconv.AppendBool(b, f.num != 0)
+	case fieldDuration:
+		if settingCanonicalTimes {
+			return append(b, isoDuration(time.Duration(f.num))...)
+		}
+		return append(b, time.Duration(f.num).String()...)
+	default:
+		if s, ok := canonicalValue(f.any); ok && settingCanonicalTimes {
+			return appendTextString(b, s)
+		}
+		return appendTextString(b, fmt.Sprint(f.any))
+	}
+}
+
+// appendTextString appends a string value for text output, quoted if needed.
+// If quoting was requested, values with colons or single quotes are quoted as
+// well, in the requested style.
+func appendTextString(b []byte, s string) []byte {
+	switch settingQuote {
+	case quoteSingle:
+		if s == "" || strings.ContainsAny(s, " \t\n\"'=:") {
+			return append(b, singleQuote(s)...)
+		}
+	case quoteGo:
+		if s == "" || strings.ContainsAny(s, " \t\n\"'=:") {
+			return strconv.AppendQuote(b, s)
+		}
+	default:
+		if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+			return strconv.AppendQuote(b, s)
+		}
+	}
+	return append(b, s...)
+}
+
+// getQuoteStyle returns the quoting style for messages in text output.
+func getQuoteStyle(config rlogConfig) int {
+	switch strings.ToUpper(config.quote) {
+	case "", "NO", "NONE", "0":
+		return quoteNone
+	case "GO":
+		return quoteGo
+	case "SINGLE":
+		return quoteSingle
+	default:
+		rlogIssue("Unknown quoting style '%s'. Messages are not quoted.", config.quote)
+		return quoteNone
+	}
+}
+
+// getFieldOrder returns whether fields in text output are sorted by key, as
+// configured, rather than shown in the order in which they were added.
+func getFieldOrder(config rlogConfig) bool {
+	switch strings.ToUpper(config.fieldOrder) {
+	case "", "INSERTION":
+		return false
+	case "SORTED":
+		return true
+	default:
+		rlogIssue("Unknown field order '%s'. Using insertion order.", config.fieldOrder)
+		return false
+	}
+}
+
+// singleQuote quotes a string in single quotes, the way a shell would need it.
+func singleQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// quoteMessage returns the message of a record for text output, without the
+// trailing newline and quoted in the configured style.
+func quoteMessage(msg string) string {
+	msg = strings.TrimRight(msg, "\n")
+	switch settingQuote {
+	case quoteGo:
+		return strconv.Quote(msg)
+	case quoteSingle:
+		return singleQuote(msg)
+	default:
+		return msg
+	}
+}
+
+// formatFieldsText formats fields as space separated key=value pairs.
+func formatFieldsText(fields []field) string {
+	if settingSortFields && len(fields) > 1 {
+		sorted := make([]field, len(fields))
+		copy(sorted, fields)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].key < sorted[j].key })
+		fields = sorted
+	}
+	var b []byte
+	for i := range fields {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = append(b, fields[i].key...)
+		b = append(b, '=')
+		b = appendFieldValueText(b, &fields[i])
+	}
+	return string(b)
+}
+
+// appendJSONString appends a string as JSON string, including the quotes.
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b = append(b, '\\', byte(r))
+		case r == '\n':
+			b = append(b, '\\', 'n')
+		case r == '\r':
+			b = append(b, '\\', 'r')
+		case r == '\t':
+			b = append(b, '\\', 't')
+		case r < 0x20 || r == '<' || r == '>' || r == '&' || r == utf8.RuneError:
+			if r == utf8.RuneError {
+				r = 0xfffd
+			}
+			b = append(b, '\\', 'u', hex[r>>12&0xf], hex[r>>8&0xf], hex[r>>4&0xf], hex[r&0xf])
+		default:
+			b = append(b, string(r)...)
+		}
+	}
+	return append(b, '"')
+}
+
+// appendFieldsJSON appends the fields as members to a JSON object, which is
+// given without the closing brace.
+func appendFieldsJSON(b []byte, fields []field) []byte {
+	for i := range fields {
+		f := &fields[i]
+		b = append(b, ',')
+		b = appendJSONString(b, f.key)
+		b = append(b, ':')
+		switch f.kind {
+		case fieldString:
+			b = appendJSONString(b, f.str)
+		case fieldInt, fieldUint, fieldBool:
+			b = appendFieldValueText(b, f)
+		case fieldFloat:
+			if math.IsInf(f.fl, 0) || math.IsNaN(f.fl) {
+				b = appendJSONString(b, strconv.FormatFloat(f.fl, 'g', -1, 64))
+			} else {
+				b = strconv.AppendFloat(b, f.fl, 'g', -1, 64)
+			}
+		case fieldDuration:
+			if settingCanonicalTimes {
+				b = appendJSONString(b, isoDuration(time.Duration(f.num)))
+			} else {
+				b = appendJSONString(b, time.Duration(f.num).String())
+			}
+		default:
+			if s, ok := canonicalValue(f.any); ok && settingCanonicalTimes {
+				b = appendJSONString(b, s)
+			} else {
+				b = appendJSONValue(b, reflect.ValueOf(f.any), 0)
+			}
+		}
+	}
+	return b
+}
+
+// getLogFormat returns the output format that was configured.
+func getLogFormat(config rlogConfig) int {
+	switch strings.ToUpper(config.logFormat) {
+	case "", "TEXT":
+		return formatText
+	case "JSON":
+		return formatJSON
+	case "DOCKER":
+		return formatDocker
+	case "GLOG":
+		return formatGlog
+	case "LOGFMT":
+		return formatLogfmt
+	default:
+		rlogIssue("Unknown log format '%s'. Using text.", config.logFormat)
+		return formatText
+	}
+}
+
+// The presets: Each one provides defaults for a number of settings.
+var presets = map[string]rlogConfig{
+	"DEV": {
+		logLevel:       "DEBUG",
+		showCallerInfo: "yes",
+		color:          "yes",
+		logFormat:      "text",
+	},
+	"PROD": {
+		logLevel:  "INFO",
+		logFormat: "json",
+		timeUTC:   "yes",
+		color:     "no",
+	},
+	"GLOG": {
+		showCallerInfo: "yes",
+		logFormat:      "glog",
+	},
+}
+
+// applyPreset fills all settings that weren't explicitly set with the values
+// from the configured preset.
+func applyPreset(config *rlogConfig) {
+	if config.preset == "" {
+		return
+	}
+	preset, ok := presets[strings.ToUpper(config.preset)]
+	if !ok {
+		rlogIssue("Unknown preset '%s'. Ignored.", config.preset)
+		return
+	}
+	config.logLevel = updateIfNeeded(config.logLevel, preset.logLevel, false)
+	config.showCallerInfo = updateIfNeeded(config.showCallerInfo, preset.showCallerInfo, false)
+	config.color = updateIfNeeded(config.color, preset.color, false)
+	config.logFormat = updateIfNeeded(config.logFormat, preset.logFormat, false)
+	config.timeUTC = updateIfNeeded(config.timeUTC, preset.timeUTC, false)
+}
+
+// writeMessage writes a message, which doesn't originate from a log function
+// call and therefore has no caller info, to all outputs. The caller needs to
+// hold at least the read lock on initMutex.
+func writeMessage(now time.Time, level int, msg string) {
+	writeRecord(&logRecord{
+		time:            now,
+		level:           level,
+		levelDecoration: levelStrings[level],
+		timeFormat:      settingDateTimeFormat,
+		msg:             msg,
+	})
+}
+
+// writeRecord formats a log record according to the configured output format
+// and writes it to all outputs. Records that would exceed the maximum line
+// length are split into several numbered records first. The caller needs to
+// hold at least the read lock on initMutex.
+func writeRecord(r *logRecord) {
+	if settingMaxLineLength > 0 {
+		for _, part := range splitRecord(r, settingMaxLineLength) {
+			writeFormattedRecord(part)
+		}
+		return
+	}
+	writeFormattedRecord(r)
+}
+
+// formatRecord formats a record according to the configured output format,
+// without colors.
+func formatRecord(r *logRecord) string {
+	switch settingLogFormat {
+	case formatJSON:
+		return formatRecordJSON(r)
+	case formatDocker:
+		return formatRecordDocker(r)
+	case formatGlog:
+		return formatRecordGlog(r)
+	case formatLogfmt:
+		return formatRecordLogfmt(r)
+	default:
+		return formatRecordText(r, false)
+	}
+}
+
+// splitRecord splits a record whose formatted output exceeds maxLen bytes
+// into several records, each with a part of the message, which is prefixed
+// with the part number and total number of parts, for example "[2/3] ".
+// A record that fits is returned unchanged.
+func splitRecord(r *logRecord, maxLen int) []*logRecord {
+	line := strings.TrimRight(formatRecord(r), "\n")
+	if len(line) <= maxLen {
+		return []*logRecord{r}
+	}
+	msg := strings.TrimRight(r.msg, "\n")
+	empty := *r
+	empty.msg = ""
+	overhead := len(strings.TrimRight(formatRecord(&empty), "\n"))
+
+	// The length of the part marker depends on the number of parts, which
+	// in turn depends on the length of the marker.
+	var chunks []string
+	for digits := 1; ; digits++ {
+		marker := "[" + strings.Repeat("9", digits) + "/" + strings.Repeat("9", digits) + "] "
+		chunkLen := maxLen - overhead - len(marker)
+		if chunkLen < minLineChunk {
+			chunkLen = minLineChunk
+		}
+		// Escaping, for example in JSON, can make the formatted message
+		// longer than the chunk, so each chunk is measured once formatted.
+		fits := func(c string) bool {
+			part := *r
+			part.msg = marker + c + "\n"
+			return len(strings.TrimRight(formatRecord(&part), "\n")) <= maxLen
+		}
+		chunks = splitFitting(msg, chunkLen, fits)
+		if len(strconv.Itoa(len(chunks))) <= digits {
+			break
+		}
+	}
+
+	parts := make([]*logRecord, len(chunks))
+	for i, c := range chunks {
+		part := *r
+		part.msg = fmt.Sprintf("[%d/%d] %s\n", i+1, len(chunks), c)
+		parts[i] = &part
+	}
+	return parts
+}
+
+// splitFitting splits a string into chunks of at most n bytes, without
+// splitting any UTF-8 encoded characters. Chunks for which fits returns
+// false are shortened further, but not below minLineChunk bytes.
+func splitFitting(s string, n int, fits func(string) bool) []string {
+	if s == "" {
+		return []string{""}
+	}
+	var chunks []string
+	for s != "" {
+		// The possible ends of the chunk, at character boundaries.
+		var ends []int
+		for i := range s {
+			if i > n {
+				break
+			}
+			if i > 0 {
+				ends = append(ends, i)
+			}
+		}
+		if len(s) <= n {
+			ends = append(ends, len(s))
+		}
+		if len(ends) == 0 {
+			_, size := utf8.DecodeRuneInString(s)
+			ends = append(ends, size)
+		}
+		first := sort.Search(len(ends), func(i int) bool { return ends[i] >= minLineChunk })
+		if first == len(ends) {
+			first = len(ends) - 1
+		}
+		ends = ends[first:]
+		k := sort.Search(len(ends), func(i int) bool { return !fits(s[:ends[i]]) })
+		end := ends[0]
+		if k > 0 {
+			end = ends[k-1]
+		}
+		chunks = append(chunks, s[:end])
+		s = s[end:]
+	}
+	return chunks
+}
+
+// writeFormattedRecord formats a record and writes it to all outputs.
+func writeFormattedRecord(r *logRecord) {
+	logLine := formatRecord(r)
+	lines := sameLines(logLine)
+	sr := sinkRecord(sinkStream, r)
+	if sr != r {
+		lines.stream = formatRecord(sr)
+	}
+	if settingStreamJournal && r.timeFormat != "" {
+		// The journal adds its own time stamps.
+		nr := *sr
+		nr.timeFormat = ""
+		sr = &nr
+		lines.stream = formatRecord(sr)
+	}
+	if settingStreamFormat != nil {
+		lines.stream = settingStreamFormat.format(sr, settingColor)
+	} else if settingColor && settingLogFormat == formatText {
+		lines.stream = formatRecordText(sr, true)
+	}
+	if settingStreamFormatter != nil {
+		lines.stream = formatEntry(settingStreamFormatter, sr)
+	}
+	if settingPriorityPrefix {
+		lines.stream = prefixLines(r.level, lines.stream)
+	}
+	fr := sinkRecord(sinkFile, r)
+	if fr != r {
+		lines.file = formatRecord(fr)
+	}
+	if settingFileOrigin && logWriterFile != nil {
+		fr = originRecord(fr)
+		lines.file = formatRecord(fr)
+	}
+	if settingFileFormat != nil {
+		lines.file = settingFileFormat.format(fr, false)
+	}
+	if settingFileFormatter != nil {
+		lines.file = formatEntry(settingFileFormatter, fr)
+	}
+	if settingMinFreeDisk > 0 && skipForDiskSpace(r) {
+		lines.file = ""
+	}
+	syslogLine := logLine
+	if settingHasSinkClocks {
+		lines.collector = sinkLine(sinkCollector, r, logLine)
+		lines.tee = sinkLine(sinkTee, r, logLine)
+		syslogLine = sinkLine(sinkSyslog, r, logLine)
+	}
+	writeSinks(r, logLine)
+	writeOutputs(lines, r.writeTimeout)
+	if settingHasWriteTimeouts || r.writeTimeout > 0 {
+		if d := writeTimeout(sinkSyslog, r.writeTimeout); d > 0 {
+			level := r.level
+			writeWithin(sinkSyslog, d, func() { syslogClient.send(level, syslogLine) })
+			return
+		}
+	}
+	syslogClient.send(r.level, syslogLine)
+}
+
+// recordTime returns the time stamp of a record, in UTC if so configured or in
+// test mode.
+func recordTime(r *logRecord) time.Time {
+	if settingTimeUTC || settingTestMode {
+		return r.time.UTC()
+	}
+	return r.time
+}
+
+// formatRecordText formats a record as a line of human readable text.
+func formatRecordText(r *logRecord, withColor bool) string {
+	callerInfo := ""
+	if r.caller != nil {
+		if settingTestMode {
+			callerInfo = fmt.Sprintf("[%s (%s)] ",
+				r.caller.moduleAndFileName, r.caller.funcName)
+		} else {
+			ids := strconv.Itoa(os.Getpid())
+			if r.goroutineID != 0 {
+				ids += ":" + strconv.FormatUint(r.goroutineID, 10)
+			}
+			if r.threadID != 0 {
+				ids += "/" + strconv.Itoa(r.threadID)
+			}
+			callerInfo = fmt.Sprintf("[%s %s:%d (%s)] ", ids,
+				r.caller.moduleAndFileName, r.caller.line, r.caller.funcName)
+		}
+	}
+	if settingCallerWidth > 0 {
+		callerInfo = fitCallerInfo(strings.TrimSuffix(callerInfo, " ")) + " "
+	}
+	levelDecoration := fmt.Sprintf("%-9s", r.levelDecoration)
+	if withColor {
+		levelDecoration = levelColors[r.level] + levelDecoration + colorReset
+	}
+	msg := r.msg
+	if settingQuote != quoteNone {
+		msg = quoteMessage(msg) + "\n"
+	}
+	if len(r.fields) > 0 {
+		msg = strings.TrimRight(msg, "\n") + " " + formatFieldsText(r.fields) + "\n"
+	}
+	return fmt.Sprintf("%s%s: %s%s",
+		recordTime(r).Format(r.timeFormat), levelDecoration, callerInfo, msg)
+}
+
+// jsonRecord defines the fields of a log entry in JSON output.
+type jsonRecord struct {
+	Time      string `json:"time,omitempty"`
+	Level     string `json:"level"`
+	Trace     *int   `json:"trace,omitempty"`
+	Topic     string `json:"topic,omitempty"`
+	PID       int    `json:"pid,omitempty"`
+	Goroutine uint64 `json:"goroutine,omitempty"`
+	Thread    int    `json:"thread,omitempty"`
+	Mono      int64  `json:"mono,omitempty"`
+	Caller    string `json:"caller,omitempty"`
+	Func      string `json:"func,omitempty"`
+	Msg       string `json:"msg"`
+}
+
+// formatRecordJSON formats a record as a single line JSON object.
+func formatRecordJSON(r *logRecord) string {
+	jr := jsonRecord{
+		Level: r.levelDecoration,
+		Msg:   strings.TrimRight(r.msg, "\n"),
+	}
+	if r.level == levelTrace {
+		// The trace level is a member of its own, so that it can be queried.
+		traceLevel := r.traceLevel
+		jr.Level = levelStrings[levelTrace]
+		jr.Trace = &traceLevel
+		jr.Topic = r.topic
+		jr.Msg = messageWithoutTopic(r)
+	}
+	if r.timeFormat != "" {
+		jr.Time = recordTime(r).Format(strings.TrimSuffix(r.timeFormat, " "))
+	}
+	if settingMonotonic {
+		// Both times carry a reading of the monotonic clock, which is used
+		// for the difference, unless the clock was replaced.
+		jr.Mono = int64(r.time.Sub(processStart))
+	}
+	if r.caller != nil && settingTestMode {
+		jr.Caller = r.caller.moduleAndFileName
+		jr.Func = r.caller.funcName
+	} else if r.caller != nil {
+		jr.PID = os.Getpid()
+		jr.Goroutine = r.goroutineID
+		jr.Thread = r.threadID
+		jr.Caller = fmt.Sprintf("%s:%d", r.caller.moduleAndFileName, r.caller.line)
+		jr.Func = r.caller.funcName
+	}
+	b, err := json.Marshal(jr)
+	if err != nil {
+		// Can't really happen with the field types above
+		return fmt.Sprintf("{\"level\":\"ERROR\",\"msg\":%q}\n", err.Error())
+	}
+	if len(r.fields) > 0 {
+		b = append(appendFieldsJSON(b[:len(b)-1], r.fields), '}')
+	}
+	return string(b) + "\n"
+}
+
+// dockerRecord defines the fields of a log entry in the format of Docker's
+// json-file logging driver.
+type dockerRecord struct {
+	Log    string `json:"log"`
+	Stream string `json:"stream"`
+	Time   string `json:"time"`
+}
+
+// formatRecordDocker formats a record like Docker's json-file logging driver
+// would store the text output of a container. This allows tools that parse
+// Docker logs to consume rlog's logfiles.
+func formatRecordDocker(r *logRecord) string {
+	b, err := json.Marshal(dockerRecord{
+		Log:    formatRecordText(r, false),
+		Stream: settingStreamName,
+		Time:   r.time.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		// Can't really happen with the field types above
+		return fmt.Sprintf("{\"log\":%q}\n", err.Error())
+	}
+	return string(b) + "\n"
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLogFormatJSON checks the JSON output format.
+func TestLogFormatJSON(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.logFormat = "json"
+	conf.traceLevel = "1"
+	initialize(conf, true)
+
+	Info("Test \"Info\"")
+	Tracef(1, "Trace %d", 1)
+	TraceAt(0).Topic("cache").Print("Evicted")
+
+	checkLines := []string{
+		`{"level":"INFO","msg":"Test \"Info\""}`,
+		`{"level":"TRACE","trace":1,"msg":"Trace 1"}`,
+		`{"level":"TRACE","trace":0,"topic":"cache","msg":"Evicted"}`,
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestLogFormatJSONMonotonic checks that JSON output contains the monotonic
+// time since the start of the process, if requested.
+func TestLogFormatJSONMonotonic(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer SetClock(nil)
+
+	conf.logFormat = "json"
+	conf.monotonic = "yes"
+	initialize(conf, true)
+	SetClock(func() time.Time {
+		return processStart.Add(1500 * time.Millisecond)
+	})
+	Info("Test Info 1")
+
+	checkLines := []string{
+		`{"level":"INFO","mono":1500000000,"msg":"Test Info 1"}`,
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestFieldOrder checks that fields in text output are sorted by key, if
+// requested.
+func TestFieldOrder(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.fieldOrder = "sorted"
+	initialize(conf, true)
+	Ev().Str("user", "alice").Int("b", 2).Int("a", 1).Msg("Test Info 1")
+	conf.fieldOrder = ""
+	initialize(conf, true)
+	Ev().Str("user", "alice").Int("b", 2).Int("a", 1).Msg("Test Info 2")
+
+	checkLines := []string{
+		"INFO     : Test Info 1 a=1 b=2 user=alice",
+		"INFO     : Test Info 2 user=alice b=2 a=1",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestLogFormatDocker checks the output format of Docker's json-file driver.
+func TestLogFormatDocker(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer SetClock(nil)
+
+	conf.logFormat = "docker"
+	conf.logStream = "STDOUT"
+	initialize(conf, true)
+	SetClock(func() time.Time {
+		return time.Date(2020, 2, 29, 12, 30, 0, 5000, time.UTC)
+	})
+	Info("Test \"Info\"")
+
+	checkLines := []string{
+		`{"log":"INFO     : Test \"Info\"\n","stream":"stdout","time":"2020-02-29T12:30:00.000005Z"}`,
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestLogColor checks that levels are colored, if requested.
+func TestLogColor(t *testing.T) {
+	r := &logRecord{
+		time:            time.Now(),
+		level:           levelWarn,
+		levelDecoration: "WARN",
+		msg:             "Test Warning\n",
+	}
+	should := "\x1b[33mWARN     \x1b[0m: Test Warning\n"
+	if is := formatRecordText(r, true); is != should {
+		t.Fatalf("Incorrect colored output.\nSHOULD: %q\nIS:     %q\n", should, is)
+	}
+}
+
+// TestPresets checks that a preset provides defaults for all settings that
+// were not set explicitly.
+func TestPresets(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.preset = "prod"
+	conf.logLevel = "WARN"
+	initialize(conf, true)
+	if settingLogFormat != formatJSON || !settingTimeUTC || settingColor {
+		t.Fatal("Prod preset was not applied")
+	}
+	checkLogFilter(t, "", levelWarn)
+
+	conf.preset = "Dev"
+	conf.logLevel = ""
+	conf.showCallerInfo = ""
+	initialize(conf, true)
+	if settingLogFormat != formatText || !settingShowCallerInfo || !settingColor {
+		t.Fatal("Dev preset was not applied")
+	}
+	checkLogFilter(t, "", levelDebug)
+}
+
+// TestMaxLineLength checks that long messages are split into numbered parts,
+// which each fit into the maximum line length.
+func TestMaxLineLength(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.maxLineLength = "40"
+	initialize(conf, true)
+
+	Info("Short message")
+	Info("This is a much longer message, which needs to be split")
+
+	checkLines := []string{
+		"INFO     : Short message",
+		"INFO     : [1/3] This is a much longer m",
+		"INFO     : [2/3] essage, which needs to ",
+		"INFO     : [3/3] be split",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestMaxLineLengthJSON checks that messages are split according to the
+// length of the lines once the messages are escaped in the JSON output.
+func TestMaxLineLengthJSON(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.logFormat = "json"
+	conf.maxLineLength = "60"
+	initialize(conf, true)
+
+	Info(strings.Repeat(`"quoted"`, 12))
+
+	content, err := ioutil.ReadFile(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("Message not split: %q", lines)
+	}
+	var msg string
+	for _, line := range lines {
+		if len(line) > 60 {
+			t.Errorf("Line too long (%d bytes): %s", len(line), line)
+		}
+		var entry struct{ Msg string }
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid JSON %q: %s", line, err)
+		}
+		msg += entry.Msg[strings.Index(entry.Msg, "] ")+2:]
+	}
+	if msg != strings.Repeat(`"quoted"`, 12) {
+		t.Errorf("Wrong message after joining the parts: %s", msg)
+	}
+}
+
+// TestQuoteMessages checks the quoting of messages and field values.
+func TestQuoteMessages(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.quote = "go"
+	initialize(conf, true)
+	Ev().Str("path", "C:\\tmp").Str("user", "joe").Msg("It's \"done\"")
+
+	conf.quote = "single"
+	initialize(conf, true)
+	Ev().Str("path", "a:b").Str("user", "joe").Msg("It's done")
+
+	checkLines := []string{
+		`INFO     : "It's \"done\"" path="C:\\tmp" user=joe`,
+		`INFO     : 'It'\''s done' path='a:b' user=joe`,
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// FormatPanic formats a value returned by recover() in a uniform way, so that
+// panics look the same in the log, no matter which recover handler caught
+// them. The kind of panic (runtime error, error or any other value) is named,
+// followed by the message and the stack of the panicking goroutine. It needs
+// to be called from within the deferred function that recovered the panic,
+// since otherwise the stack isn't that of the panic anymore:
+//
+//     defer func() {
+//         if r := recover(); r != nil {
+//             rlog.Error(rlog.FormatPanic(r))
+//         }
+//     }()
+func FormatPanic(value interface{}) string {
+	var kind, msg string
+	switch v := value.(type) {
+	case runtime.Error:
+		kind = "runtime error"
+		msg = strings.TrimPrefix(v.Error(), "runtime error: ")
+	case error:
+		kind = fmt.Sprintf("error %T", v)
+		msg = v.Error()
+	case string:
+		kind = "string"
+		msg = v
+	case fmt.Stringer:
+		kind = fmt.Sprintf("%T", v)
+		msg = v.String()
+	default:
+		kind = fmt.Sprintf("%T", v)
+		msg = fmt.Sprintf("%+v", v)
+	}
+	return fmt.Sprintf("Panic (%s): %s\n%s", kind, msg, debug.Stack())
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recoverFormatted runs the function and returns the formatted panic value.
+func recoverFormatted(f func()) (formatted string) {
+	defer func() {
+		formatted = FormatPanic(recover())
+	}()
+	f()
+	return ""
+}
+
+// TestFormatPanic checks the formatting of different kinds of panic values.
+func TestFormatPanic(t *testing.T) {
+	var nilMap map[string]int
+	checks := []struct {
+		f      func()
+		should string
+	}{
+		{func() { panic("boom") }, "Panic (string): boom\n"},
+		{func() { panic(errors.New("failed")) }, "Panic (error *errors.errorString): failed\n"},
+		{func() { nilMap["x"] = 1 }, "Panic (runtime error): assignment to entry in nil map\n"},
+		{func() { panic(42) }, "Panic (int): 42\n"},
+	}
+	for _, c := range checks {
+		is := recoverFormatted(c.f)
+		if !strings.HasPrefix(is, c.should) {
+			t.Fatalf("Incorrect panic formatting.\nSHOULD: %s\nIS:     %s\n", c.should, is)
+		}
+		if !strings.Contains(is, "TestFormatPanic") {
+			t.Fatalf("Stack of panicking goroutine missing:\n%s", is)
+		}
+	}
+}
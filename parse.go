@@ -0,0 +1,266 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotALogLine is returned by ParseLine for lines that were not written by
+// rlog.
+var ErrNotALogLine = errors.New("rlog: not a log line")
+
+var (
+	// ansiEscape matches the color codes in text output
+	ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+	// textLevel matches the level at the start of a text line, after the
+	// optional time stamp
+	textLevel = regexp.MustCompile(`(?:^| )(CRITICAL|ERROR|WARN|INFO|DEBUG|TRACE\((\d+)\)) *: `)
+	// textCaller matches the caller info at the start of a message, in normal
+	// and in test mode
+	textCaller = regexp.MustCompile(`^\[(?:\d+(?::\d+)?(?:/\d+)? ([^ ]+):(\d+)|([^ ]+)) \(([^)]*)\)\] `)
+	// fieldKey matches the key of a field in text output
+	fieldKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*=`)
+)
+
+// timeLayouts are the layouts that are tried for time stamps, after the one
+// that is configured.
+var timeLayouts = []string{
+	time.RFC3339Nano, time.ANSIC, time.UnixDate, time.RubyDate, time.RFC822,
+	time.RFC822Z, time.RFC1123, time.RFC1123Z, time.Kitchen,
+}
+
+// ParseLine parses a line, as it is written by rlog in text, JSON or Docker
+// output format, into an Entry. This allows tools and tests that process log
+// output to share a single parser. The time stamp is parsed with the time
+// format currently configured, or one of the well known formats. If that
+// fails then the Time of the Entry is zero. In text output the key=value pairs
+// at the end of the message are taken as fields, with string values. Caller
+// info is filled in if it was logged. Lines that don't look like rlog output
+// return ErrNotALogLine.
+func ParseLine(s string) (Entry, error) {
+	if strings.HasPrefix(s, "{") {
+		return parseJSONLine(s)
+	}
+	return parseTextLine(s)
+}
+
+// parseTextLine parses a line in text output format.
+func parseTextLine(s string) (Entry, error) {
+	s = strings.TrimRight(s, "\r\n")
+	s = ansiEscape.ReplaceAllString(s, "")
+	m := textLevel.FindStringSubmatchIndex(s)
+	if m == nil {
+		return Entry{}, ErrNotALogLine
+	}
+	e := Entry{TraceLevel: notATrace}
+	if err := parseLevel(&e, s[m[2]:m[3]]); err != nil {
+		return Entry{}, err
+	}
+	e.Time = parseTime(s[:m[0]])
+	rest := s[m[1]:]
+
+	if c := textCaller.FindStringSubmatch(rest); c != nil {
+		if c[1] != "" {
+			e.File = c[1]
+			e.Line, _ = strconv.Atoi(c[2])
+		} else {
+			e.File = c[3]
+		}
+		e.Func = c[4]
+		rest = rest[len(c[0]):]
+	}
+	e.Message, e.Fields = splitTextFields(rest)
+	if msg, n := unquoteValue(e.Message); n == len(e.Message) && n > 0 {
+		e.Message = msg
+	}
+	return e, nil
+}
+
+// parseJSONLine parses a line in JSON or Docker output format.
+func parseJSONLine(s string) (Entry, error) {
+	var members map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &members); err != nil {
+		return Entry{}, ErrNotALogLine
+	}
+	if log, ok := members["log"].(string); ok && members["level"] == nil {
+		// Docker format, with the text line inside
+		e, err := parseTextLine(log)
+		if t, ok := members["time"].(string); ok && err == nil {
+			e.Time, _ = time.Parse(time.RFC3339Nano, t)
+		}
+		return e, err
+	}
+	level, ok := members["level"].(string)
+	if !ok {
+		return Entry{}, ErrNotALogLine
+	}
+	e := Entry{TraceLevel: notATrace}
+	if trace, ok := members["trace"].(float64); ok && level == levelStrings[levelTrace] {
+		level = fmt.Sprintf("TRACE(%d)", int(trace))
+	}
+	if err := parseLevel(&e, level); err != nil {
+		return Entry{}, err
+	}
+	e.Message, _ = members["msg"].(string)
+	if t, ok := members["time"].(string); ok {
+		e.Time = parseTime(t)
+	}
+	if caller, ok := members["caller"].(string); ok {
+		e.File = caller
+		if i := strings.LastIndex(caller, ":"); i >= 0 {
+			if line, err := strconv.Atoi(caller[i+1:]); err == nil {
+				e.File, e.Line = caller[:i], line
+			}
+		}
+	}
+	e.Func, _ = members["func"].(string)
+	for _, key := range []string{"time", "level", "trace", "pid", "goroutine", "thread", "caller", "func", "msg"} {
+		delete(members, key)
+	}
+	if len(members) > 0 {
+		e.Fields = members
+	}
+	return e, nil
+}
+
+// parseLevel sets the level of an entry from its level decoration, such as
+// "INFO" or "TRACE(3)".
+func parseLevel(e *Entry, decoration string) error {
+	if strings.HasPrefix(decoration, "TRACE(") && strings.HasSuffix(decoration, ")") {
+		traceLevel, err := strconv.Atoi(decoration[6 : len(decoration)-1])
+		if err != nil {
+			return ErrNotALogLine
+		}
+		e.Level = LevelTrace
+		e.TraceLevel = traceLevel
+		return nil
+	}
+	level, ok := levelNumbers[decoration]
+	if !ok || level == levelNone || level == levelTrace {
+		return ErrNotALogLine
+	}
+	e.Level = Level(level)
+	return nil
+}
+
+// parseTime parses a time stamp with the configured layout or one of the well
+// known ones. The zero time is returned if this fails.
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	ensureInitialized()
+	initMutex.RLock()
+	configured := strings.TrimSuffix(settingDateTimeFormat, " ")
+	initMutex.RUnlock()
+	for _, layout := range append([]string{configured}, timeLayouts...) {
+		if layout == "" {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// splitTextFields splits the key=value pairs at the end of a message in text
+// output from the message. The fields start at the first position after a
+// space from which on the rest of the line consists of key=value pairs only.
+func splitTextFields(s string) (string, map[string]interface{}) {
+	for i := 0; i < len(s); i++ {
+		if s[i] != ' ' {
+			continue
+		}
+		if fields := parseTextFields(s[i+1:]); fields != nil {
+			return s[:i], fields
+		}
+	}
+	return s, nil
+}
+
+// parseTextFields parses space separated key=value pairs. It returns nil if
+// the string contains anything else.
+func parseTextFields(s string) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for len(s) > 0 {
+		key := fieldKey.FindString(s)
+		if key == "" {
+			return nil
+		}
+		s = s[len(key):]
+		value, n := unquoteValue(s)
+		if n < 0 {
+			value = s
+			if i := strings.IndexByte(s, ' '); i >= 0 {
+				value = s[:i]
+			}
+			n = len(value)
+		}
+		fields[key[:len(key)-1]] = value
+		s = s[n:]
+		if len(s) > 0 {
+			if s[0] != ' ' {
+				return nil
+			}
+			s = s[1:]
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// unquoteValue unquotes a value at the start of a string, which was quoted in
+// Go or in shell style. It returns the unquoted value and the length of the
+// quoted value, or -1 if the string doesn't start with a quoted value.
+func unquoteValue(s string) (string, int) {
+	if strings.HasPrefix(s, "\"") {
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == '"' {
+				value, err := strconv.Unquote(s[:i+1])
+				if err != nil {
+					return "", -1
+				}
+				return value, i + 1
+			}
+		}
+	} else if strings.HasPrefix(s, "'") {
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if strings.HasPrefix(s[i:], `'\''`) {
+				b.WriteByte('\'')
+				i += 3
+			} else if s[i] == '\'' {
+				return b.String(), i + 1
+			} else {
+				b.WriteByte(s[i])
+			}
+		}
+	}
+	return "", -1
+}
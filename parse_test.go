@@ -0,0 +1,109 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseLine checks the parsing of lines in the different output formats.
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected Entry
+	}{
+		{"2020-02-29T15:04:05Z INFO     : Test Info",
+			Entry{Time: time.Date(2020, 2, 29, 15, 4, 5, 0, time.UTC), Level: LevelInfo,
+				TraceLevel: -1, Message: "Test Info"}},
+		{"\x1b[31mERROR    \x1b[0m: [1234:7 rlog/x.go:42 (rlog.f)] Login failed user=bob n=\"x y\"",
+			Entry{Level: LevelError, TraceLevel: -1, Message: "Login failed",
+				Fields: map[string]interface{}{"user": "bob", "n": "x y"},
+				File:   "rlog/x.go", Line: 42, Func: "rlog.f"}},
+		{"TRACE(12): [x.go (rlog.f)] 'It'\\''s' q='a b'",
+			Entry{Level: LevelTrace, TraceLevel: 12, Message: "It's",
+				Fields: map[string]interface{}{"q": "a b"}, File: "x.go", Func: "rlog.f"}},
+		{`{"time":"2020-02-29T15:04:05Z","level":"WARN","pid":1,"caller":"rlog/x.go:42","func":"rlog.f","msg":"Test","n":3}`,
+			Entry{Time: time.Date(2020, 2, 29, 15, 4, 5, 0, time.UTC), Level: LevelWarn,
+				TraceLevel: -1, Message: "Test", Fields: map[string]interface{}{"n": 3.0},
+				File: "rlog/x.go", Line: 42, Func: "rlog.f"}},
+		{`{"level":"TRACE","trace":2,"topic":"cache","msg":"Test"}`,
+			Entry{Level: LevelTrace, TraceLevel: 2, Message: "Test",
+				Fields: map[string]interface{}{"topic": "cache"}}},
+		{`{"log":"DEBUG    : Test\n","stream":"stderr","time":"2020-02-29T15:04:05Z"}`,
+			Entry{Time: time.Date(2020, 2, 29, 15, 4, 5, 0, time.UTC), Level: LevelDebug,
+				TraceLevel: -1, Message: "Test"}},
+	}
+	for _, test := range tests {
+		e, err := ParseLine(test.line)
+		if err != nil {
+			t.Errorf("Unable to parse '%s': %s", test.line, err)
+			continue
+		}
+		if !e.Time.Equal(test.expected.Time) {
+			t.Errorf("Incorrect time for '%s': %s", test.line, e.Time)
+		}
+		e.Time = test.expected.Time
+		if !reflect.DeepEqual(e, test.expected) {
+			t.Errorf("Incorrect entry for '%s': %#v", test.line, e)
+		}
+	}
+
+	for _, line := range []string{"", "Hello world", "INFORMATION: x", `{"msg":"x"}`} {
+		if _, err := ParseLine(line); err != ErrNotALogLine {
+			t.Errorf("Expected error for '%s', got %v", line, err)
+		}
+	}
+}
+
+// TestParseLineRoundTrip checks that lines written by rlog can be parsed.
+func TestParseLineRoundTrip(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.logFormat = "json"
+	initialize(conf, true)
+	var entries []Entry
+	AddHook(LevelTrace, func(e Entry) { entries = append(entries, e) })
+	defer func() { hooks = nil }()
+
+	Ev().Str("user", "bob").Msg("Test Info")
+	conf.logFormat = "text"
+	conf.quote = "go"
+	initialize(conf, false)
+	Ev().Level(LevelWarn).Str("user", "bob").Msg("Test: Warn")
+
+	content, err := ioutil.ReadFile(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 2 || len(entries) != 2 {
+		t.Fatalf("Unexpected output: %v", lines)
+	}
+	for i, line := range lines {
+		e, err := ParseLine(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Level != entries[i].Level || e.Message != entries[i].Message ||
+			!reflect.DeepEqual(e.Fields, entries[i].Fields) {
+			t.Errorf("Incorrect entry for '%s': %#v", line, e)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// settingPrintLevels determines whether Print, Println and Printf take the
+// level of a message from a prefix such as "ERROR:".
+var settingPrintLevels bool
+
+// printLevelWords maps the level names that are recognized at the start of
+// messages logged via the Print functions to log levels.
+var printLevelWords = map[string]int{
+	"CRITICAL": levelCrit,
+	"CRIT":     levelCrit,
+	"FATAL":    levelCrit,
+	"ERROR":    levelErr,
+	"ERR":      levelErr,
+	"WARNING":  levelWarn,
+	"WARN":     levelWarn,
+	"INFO":     levelInfo,
+	"DEBUG":    levelDebug,
+}
+
+// printLevel returns the level for a message logged via one of the Print
+// functions, given the start of the message. Unless this was switched off,
+// a level name at the start of the message, followed by a colon or a dash,
+// or enclosed in brackets, determines the level. For example "ERROR: ...",
+// "warning - ..." or "[DEBUG] ...". Otherwise, the level is INFO. The caller
+// needs to hold at least the read lock on initMutex.
+func printLevel(msg string) int {
+	if !settingPrintLevels {
+		return levelInfo
+	}
+	msg = strings.TrimLeftFunc(msg, unicode.IsSpace)
+	bracketed := strings.HasPrefix(msg, "[")
+	if bracketed {
+		msg = msg[1:]
+	}
+	end := strings.IndexFunc(msg, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end <= 0 {
+		return levelInfo
+	}
+	level, ok := printLevelWords[strings.ToUpper(msg[:end])]
+	if !ok {
+		return levelInfo
+	}
+	rest := msg[end:]
+	if bracketed {
+		if strings.HasPrefix(rest, "]") {
+			return level
+		}
+		return levelInfo
+	}
+	rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	switch r, _ := utf8.DecodeRuneInString(rest); r {
+	case ':', '-', '–', '—':
+		return level
+	}
+	return levelInfo
+}
+
+// printArgsLevel returns the level for a message logged via Print or Println,
+// based on the first argument.
+func printArgsLevel(a []interface{}) int {
+	if len(a) == 0 {
+		return levelInfo
+	}
+	if s, ok := a[0].(string); ok {
+		return printLevel(s)
+	}
+	return levelInfo
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestPrintLevel checks the detection of levels at the start of messages.
+func TestPrintLevel(t *testing.T) {
+	settingPrintLevels = true
+	tests := map[string]int{
+		"ERROR: disk full":      levelErr,
+		"  warning — disk full": levelWarn,
+		"Warn - disk full":      levelWarn,
+		"[DEBUG] disk full":     levelDebug,
+		"[DEBUG disk full":      levelInfo,
+		"fatal: disk full":      levelCrit,
+		"Error count is 3":      levelInfo,
+		"Errors: 3":             levelInfo,
+		"disk full":             levelInfo,
+		"":                      levelInfo,
+	}
+	for msg, expected := range tests {
+		if level := printLevel(msg); level != expected {
+			t.Errorf("Incorrect level for '%s': %d, expected %d", msg, level, expected)
+		}
+	}
+}
+
+// TestPrintFunctions checks the levels of messages logged via Print, Println
+// and Printf, with and without level detection, which is off by default.
+func TestPrintFunctions(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.logLevel = "DEBUG"
+	initialize(conf, true)
+	Print("ERROR: Test Print")
+
+	conf.printLevels = "yes"
+	initialize(conf, false)
+	Print("ERROR: Test Print")
+	Println("warning - Test Println")
+	Printf("[DEBUG] Test %s", "Printf")
+	Println(42, "Test Println")
+
+	conf.printLevels = "no"
+	initialize(conf, false)
+	Printf("ERROR: Test %s", "Printf")
+
+	checkLines := []string{
+		"INFO     : ERROR: Test Print",
+		"ERROR    : ERROR: Test Print",
+		"WARN     : warning - Test Println",
+		"DEBUG    : [DEBUG] Test Printf",
+		"INFO     : 42 Test Println",
+		"INFO     : ERROR: Test Printf",
+	}
+	fileMatch(t, checkLines, "")
+}
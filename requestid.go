@@ -0,0 +1,107 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the HTTP header that carries the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the length of request IDs that are accepted from
+// clients.
+const maxRequestIDLength = 128
+
+// contextKey is the type of the keys for values rlog stores in a context.
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// NewRequestID returns a new random request ID of 32 hex digits.
+func NewRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// NewContext returns a copy of the context that carries the Logger.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the Logger carried by the context. If there is none then
+// nil is returned, which behaves like the package level log functions.
+func FromContext(ctx context.Context) *Logger {
+	l, _ := ctx.Value(loggerKey).(*Logger)
+	return l
+}
+
+// RequestID returns the request ID carried by the context, or "" if there is
+// none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// RequestIDMiddleware wraps an HTTP handler, so that every request has a
+// request ID: The one in the X-Request-ID header of the request, or a new one
+// if there is none. The ID is returned in the X-Request-ID header of the
+// response. The context of the request carries the ID, as well as a Logger
+// that adds it as field 'request_id' to all messages and uses it as sample
+// key. The handler gets this Logger with FromContext:
+//
+//     rlog.FromContext(r.Context()).Infof("Serving %s", r.URL.Path)
+//
+// To propagate the ID to other services, set the X-Request-ID header of
+// outgoing requests to RequestID(r.Context()).
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		l := FromContext(r.Context()).clone()
+		l.sampleKey = id
+		n := len(l.fields)
+		l.fields = append(l.fields[:n:n], field{key: "request_id", kind: fieldString, str: id})
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(ctx, l)))
+	})
+}
+
+// validRequestID returns true if a request ID received from a client is safe
+// to use: Not empty, not too long and only consisting of printable ASCII
+// characters other than spaces and quotes.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' || id[i] == '"' || id[i] == '\'' {
+			return false
+		}
+	}
+	return true
+}
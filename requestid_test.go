@@ -0,0 +1,58 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRequestIDMiddleware checks that request IDs are taken from requests or
+// generated, and that they are added to the messages of the context logger.
+func TestRequestIDMiddleware(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	var ids []string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, RequestID(r.Context()))
+		FromContext(r.Context()).Info("Test Info")
+	}))
+
+	for _, id := range []string{"req-1", "", "bad id"} {
+		r := httptest.NewRequest("GET", "/", nil)
+		if id != "" {
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Header().Get(RequestIDHeader) != ids[len(ids)-1] {
+			t.Errorf("Incorrect request ID in response: %s", w.Header().Get(RequestIDHeader))
+		}
+	}
+	if ids[0] != "req-1" || len(ids[1]) != 32 || len(ids[2]) != 32 || ids[1] == ids[2] {
+		t.Fatalf("Incorrect request IDs: %v", ids)
+	}
+
+	checkLines := []string{
+		"INFO     : Test Info request_id=req-1",
+		"INFO     : Test Info request_id=" + ids[1],
+		"INFO     : Test Info request_id=" + ids[2],
+	}
+	fileMatch(t, checkLines, "")
+}
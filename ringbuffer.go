@@ -0,0 +1,163 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strconv"
+	"sync"
+)
+
+// recentEntriesSize is the number of log lines that are kept in memory, so
+// that they can be included in crash reports, unless RLOG_RECENT_ENTRIES asks
+// for more.
+const recentEntriesSize = 100
+
+// ringBuffer keeps the most recently written log lines in memory. It only
+// stores anything once it has been enabled, so that there is no cost if none
+// of the features relying on it are used.
+type ringBuffer struct {
+	mutex    sync.Mutex
+	lines    []string // storage, nil if the buffer is disabled
+	next     int      // index at which the next line is stored
+	count    int      // number of stored lines
+	bytes    int      // total length of the stored lines
+	maxBytes int      // limit for the total length, 0 for no limit
+}
+
+// recentEntries holds the most recent log lines of this process.
+var recentEntries = &ringBuffer{}
+
+// enable sets the number of log lines that are stored, as well as the limit
+// for their total length, which is 0 if there is none. The most recent lines
+// are kept if the size changes. A size of 0 switches storing off and discards
+// all stored lines.
+func (rb *ringBuffer) enable(size int, maxBytes int) {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+	rb.maxBytes = maxBytes
+	if size != len(rb.lines) {
+		old := rb.ordered()
+		if len(old) > size {
+			old = old[len(old)-size:]
+		}
+		rb.lines = nil
+		rb.next, rb.count, rb.bytes = 0, 0, 0
+		if size > 0 {
+			rb.lines = make([]string, size)
+		}
+		for _, line := range old {
+			rb.store(line)
+		}
+	}
+	rb.trim()
+}
+
+// recentEntriesToKeep returns the number of log lines that need to be kept
+// in memory, according to RLOG_RECENT_ENTRIES and whether crash reports are
+// enabled. The caller needs to hold the write lock on initMutex.
+func recentEntriesToKeep(spec string) int {
+	n := 0
+	if spec != "" {
+		var err error
+		n, err = strconv.Atoi(spec)
+		if err != nil || n < 0 {
+			rlogIssue("Cannot parse recent entries value '%s'. Ignored.", spec)
+			n = 0
+		}
+	}
+	if settingCrashReportDir != "" && n < recentEntriesSize {
+		n = recentEntriesSize
+	}
+	return n
+}
+
+// add stores a log line, overwriting the oldest line if the buffer is full.
+// Further old lines are discarded if the limit for the total length of the
+// lines is exceeded. A line that exceeds the limit by itself is not stored.
+func (rb *ringBuffer) add(line string) {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+	if rb.lines == nil || (rb.maxBytes > 0 && len(line) > rb.maxBytes) {
+		return
+	}
+	rb.store(line)
+	rb.trim()
+}
+
+// store puts a line into the buffer, overwriting the oldest line if the
+// buffer is full. The caller needs to hold the mutex.
+func (rb *ringBuffer) store(line string) {
+	if rb.lines == nil {
+		return
+	}
+	if rb.count == len(rb.lines) {
+		rb.bytes -= len(rb.lines[rb.next])
+	} else {
+		rb.count++
+	}
+	rb.lines[rb.next] = line
+	rb.bytes += len(line)
+	rb.next = (rb.next + 1) % len(rb.lines)
+}
+
+// trim discards the oldest lines until their total length is within the
+// limit. The caller needs to hold the mutex.
+func (rb *ringBuffer) trim() {
+	for rb.maxBytes > 0 && rb.bytes > rb.maxBytes && rb.count > 0 {
+		oldest := (rb.next - rb.count + len(rb.lines)) % len(rb.lines)
+		rb.bytes -= len(rb.lines[oldest])
+		rb.lines[oldest] = ""
+		rb.count--
+	}
+}
+
+// entries returns a copy of the stored log lines, oldest first.
+func (rb *ringBuffer) entries() []string {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+	return rb.ordered()
+}
+
+// ordered returns a copy of the stored log lines, oldest first. The caller
+// needs to hold the mutex.
+func (rb *ringBuffer) ordered() []string {
+	lines := make([]string, 0, rb.count)
+	for i := rb.count; i > 0; i-- {
+		lines = append(lines, rb.lines[(rb.next-i+len(rb.lines))%len(rb.lines)])
+	}
+	return lines
+}
+
+// RecentEntries returns up to n of the most recent log entries that have the
+// given level or a more severe one, oldest first. A negative n returns all of
+// them. The entries are kept in memory if RLOG_RECENT_ENTRIES is set, or for
+// crash reports. This allows crash handlers and support bundles to include the
+// recent log. The entries are parsed from the log lines with ParseLine, so in
+// text output the values of fields are strings.
+func RecentEntries(n int, minLevel Level) []Entry {
+	var entries []Entry
+	for _, l := range recentEntries.entries() {
+		e, err := ParseLine(l)
+		if err != nil || e.Level > minLevel {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if n >= 0 && len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return entries
+}
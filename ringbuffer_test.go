@@ -0,0 +1,97 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRecentEntries checks that the most recent entries are returned,
+// filtered by level.
+func TestRecentEntries(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.logLevel = "DEBUG"
+	conf.recentEntries = "3"
+	initialize(conf, true)
+
+	Info("Test Info 1")
+	Warn("Test Warning 2")
+	Debug("Test Debug 3")
+	Error("Test Error 4")
+	Info("Test Info 5")
+
+	messages := func(entries []Entry) []string {
+		var msgs []string
+		for _, e := range entries {
+			msgs = append(msgs, e.Message)
+		}
+		return msgs
+	}
+	if msgs := messages(RecentEntries(-1, LevelTrace)); !reflect.DeepEqual(msgs,
+		[]string{"Test Debug 3", "Test Error 4", "Test Info 5"}) {
+		t.Errorf("Incorrect recent entries: %q", msgs)
+	}
+	if msgs := messages(RecentEntries(2, LevelInfo)); !reflect.DeepEqual(msgs,
+		[]string{"Test Error 4", "Test Info 5"}) {
+		t.Errorf("Incorrect recent entries: %q", msgs)
+	}
+	if msgs := messages(RecentEntries(5, LevelWarn)); !reflect.DeepEqual(msgs,
+		[]string{"Test Error 4"}) {
+		t.Errorf("Incorrect recent entries: %q", msgs)
+	}
+
+	// Growing the buffer keeps the stored entries
+	conf.recentEntries = "4"
+	initialize(conf, true)
+	Info("Test Info 6")
+	Info("Test Info 7")
+	if msgs := messages(RecentEntries(-1, LevelTrace)); !reflect.DeepEqual(msgs,
+		[]string{"Test Error 4", "Test Info 5", "Test Info 6", "Test Info 7"}) {
+		t.Errorf("Incorrect recent entries: %q", msgs)
+	}
+
+	conf.recentEntries = ""
+	initialize(conf, true)
+	if entries := RecentEntries(-1, LevelTrace); entries != nil {
+		t.Errorf("Entries should not be kept: %v", entries)
+	}
+}
+
+// TestRingBufferMaxBytes checks that the oldest lines are discarded when the
+// limit for the total length of the lines is exceeded.
+func TestRingBufferMaxBytes(t *testing.T) {
+	rb := &ringBuffer{}
+	rb.enable(4, 10)
+	for _, line := range []string{"aaa", "bbb", "ccc", "dddd", "this is too long", "e"} {
+		rb.add(line)
+	}
+	if lines := rb.entries(); !reflect.DeepEqual(lines, []string{"ccc", "dddd", "e"}) {
+		t.Errorf("Incorrect lines: %q", lines)
+	}
+
+	// Lowering the limit discards more lines, shrinking the buffer keeps the
+	// newest ones
+	rb.enable(4, 5)
+	if lines := rb.entries(); !reflect.DeepEqual(lines, []string{"dddd", "e"}) {
+		t.Errorf("Incorrect lines: %q", lines)
+	}
+	rb.enable(1, 0)
+	if lines := rb.entries(); !reflect.DeepEqual(lines, []string{"e"}) {
+		t.Errorf("Incorrect lines: %q", lines)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -70,6 +71,28 @@ var levelNumbers = map[string]int{
 	"NONE":     levelNone,
 }
 
+// Level is a log level, as it is used by those functions that take the log
+// level as a parameter.
+type Level int
+
+// The log levels, which may be passed to functions taking a Level parameter.
+const (
+	LevelCritical Level = levelCrit
+	LevelError    Level = levelErr
+	LevelWarn     Level = levelWarn
+	LevelInfo     Level = levelInfo
+	LevelDebug    Level = levelDebug
+	LevelTrace    Level = levelTrace // trace messages, regardless of trace level
+)
+
+// String returns the name of the log level, as it appears in the log output.
+func (l Level) String() string {
+	if s, ok := levelStrings[int(l)]; ok {
+		return s
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
 // filterSpec holds a list of filters. These are applied to the 'caller'
 // information of a log message (calling module and file) to see if this
 // message should be logged. Different log or trace levels per file can
@@ -77,6 +100,12 @@ var levelNumbers = map[string]int{
 // messages this is going to be the trace level.
 type filterSpec struct {
 	filters []filter
+	// Levels up to minLevel are accepted in all files, if allFiles is set.
+	// Levels above maxLevel are not accepted in any file. Only levels in
+	// between require matching the filename against the patterns.
+	minLevel int
+	maxLevel int
+	allFiles bool
 }
 
 // filter holds filename and level to match logs against log messages.
@@ -99,7 +128,55 @@ type rlogConfig struct {
 	logNoTime       string // Flag to determine if date/time is logged at all
 	showCallerInfo  string // Flag to determine if caller info is logged
 	showGoroutineID string // Flag to determine if goroute ID shows in caller info
+	showThreadID    string // Flag to determine if OS thread ID shows in caller info
 	confCheckInterv string // Interval in seconds for checking config file
+	crashReportDir  string // Directory for crash reports on CRITICAL/panic
+	statsInterv     string // Interval in seconds for logging runtime stats
+	statsLevel      string // Log level for runtime stats messages
+	collectorSocket string // Unix socket of a collector to forward logs to
+	fallbackLevel   string // Log level from generic LOG_LEVEL or DEBUG vars
+	logFormat       string // Output format: text or json
+	color           string // Flag to determine if levels are shown in color
+	timeUTC         string // Flag to determine if time stamps are in UTC
+	preset          string // Name of a preset with defaults: dev or prod
+	maxLineLength   string // Maximum length of output lines, longer are split
+	fatalExitCode   string // Exit code used by Fatal and Fatalf
+	fatalTimeout    string // Seconds Fatal waits for output to be written
+	sample          string // Sampling and rate limit specification
+	streamBuffer    string // Buffering and flush policy for the log stream
+	k8sFields       string // Flag to attach Kubernetes metadata as fields
+	syslog          string // Syslog address, or "local" for local syslog
+	syslogFacility  string // Syslog facility name
+	syslogLevels    string // Overrides of the level to syslog severity mapping
+	formatStream    string // Output format or template for the stream only
+	formatFile      string // Output format or template for the logfile only
+	quote           string // Quoting style for messages in text output
+	levelRules      string // Rules for changing the level of messages
+	fileOrigin      string // Flag to show the origin of entries in the logfile
+	printLevels     string // Flag to take the level of Print messages from prefix
+	callerInfoLevel string // Level from which on caller info is always logged
+	logFileRotate   string // Period after which a new logfile is started
+	streamFallback  string // Flag to use another output if the stream is unusable
+	uptimeFields    string // Flag to add uptime fields to CRITICAL entries
+	confErrors      string // How errors in the config file are handled
+	serviceMode     string // Whether to run without the standard streams
+	journal         string // Whether the stream is connected to the journal
+	priorityPrefix  string // Flag to start stream lines with the priority
+	monotonic       string // Flag to add monotonic time to JSON output
+	callerSkip      string // Packages that are never shown as the caller
+	canonicalTimes  string // Flag to format times and durations canonically
+	fieldOrder      string // Order of the fields in text output
+	implicitTrace   string // Trace levels enabled with the log levels
+	banner          string // Flag to write a banner when logging starts
+	minFreeDisk     string // Free space below which the logfile is limited
+	recentEntries   string // Number of log entries kept in memory
+	writeTimeout    string // Time after which writes to outputs are abandoned
+	goCreator       string // Trace level from which goroutine creators are shown
+	internalOutput  string // Where notices about problems of rlog are written
+	internalLevel   string // Least severe level of internal notices written
+	maxBufferMB     string // Memory budget for buffered output in MB
+	sampleReport    string // Interval of reports about sampled out messages
+	callerWidth     string // Width and alignment of the caller info column
 }
 
 // We keep a copy of what was supplied via environment variables, since we will
@@ -114,19 +191,28 @@ var configFromEnvVars rlogConfig
 // in those variables below.
 var (
 	settingShowCallerInfo  bool   // whether we log caller info
+	settingCallerInfoLevel int    // level up to which caller info is always logged
 	settingShowGoroutineID bool   // whether we show goroutine ID in caller info
+	settingShowThreadID    bool   // whether we show OS thread ID in caller info
 	settingDateTimeFormat  string // flags for date/time output
 	settingConfFile        string // config file name
+	settingCrashReportDir  string // where crash reports are written
 	// how often we check the conf file
 	settingCheckInterval time.Duration = 15 * time.Second
-
-	logWriterStream     *log.Logger // the first writer to which output is sent
-	logWriterFile       *log.Logger // the second writer to which output is sent
-	logFilterSpec       *filterSpec // filters for log messages
-	traceFilterSpec     *filterSpec // filters for trace messages
-	lastConfigFileCheck time.Time   // when did we last check the config file
-	currentLogFile      *os.File    // the logfile currently in use
-	currentLogFileName  string      // name of current log file
+	// check interval set via SetConfCheckInterval, negative if not set
+	confCheckIntervOverride time.Duration = -1
+	confFileDisabled        bool          // set via DisableConfFile
+
+	logWriterStream     *log.Logger    // the first writer to which output is sent
+	logWriterFile       *log.Logger    // the second writer to which output is sent
+	logFilterSpec       *filterSpec    // filters for log messages
+	traceFilterSpec     *filterSpec    // filters for trace messages
+	lastConfigFileCheck time.Time      // when did we last check the config file
+	currentLogFile      io.WriteCloser // the logfile currently in use
+	currentLogFileName  string         // name of current log file
+	currentLogRotation  int            // rotation of current log file
+	streamOutput        io.Writer      // replaces the configured log stream
+	fileOutput          string         // replaces the configured logfile
 
 	initMutex sync.RWMutex = sync.RWMutex{} // used to protect the init section
 )
@@ -226,9 +312,21 @@ func (spec *filterSpec) fromString(s string, isTraceLevels bool, globalLevelDefa
 	// efficiently in the top-level trace functions for an early exit.
 	if !isTraceLevels || globalLevel != noTraceOutput {
 		spec.filters = append(spec.filters, filter{"", globalLevel})
+		spec.allFiles = true
 	}
+	spec.updateLevelBounds()
+}
 
-	return
+// updateLevelBounds sets minLevel and maxLevel from the levels of the filters.
+func (spec *filterSpec) updateLevelBounds() {
+	for i, f := range spec.filters {
+		if i == 0 || f.Level < spec.minLevel {
+			spec.minLevel = f.Level
+		}
+		if i == 0 || f.Level > spec.maxLevel {
+			spec.maxLevel = f.Level
+		}
+	}
 }
 
 // matchfilters checks if given filename and trace level are accepted
@@ -239,6 +337,15 @@ func (spec *filterSpec) matchfilters(filename string, level int) bool {
 		return false
 	}
 
+	// Often the level alone decides, for example if only a global level was
+	// given. Then we don't need to match any patterns.
+	if level > spec.maxLevel {
+		return false
+	}
+	if spec.allFiles && level <= spec.minLevel {
+		return true
+	}
+
 	// If at least one filter matches.
 	for _, filter := range spec.filters {
 		if matched, loggit := filter.match(filename, level); matched {
@@ -249,6 +356,26 @@ func (spec *filterSpec) matchfilters(filename string, level int) bool {
 	return false
 }
 
+// tracePrefixes holds the level decorations of the common trace levels, so
+// that they don't need to be formatted for every trace message.
+var tracePrefixes = [...]string{"(0)", "(1)", "(2)", "(3)", "(4)", "(5)", "(6)", "(7)", "(8)", "(9)"}
+
+// tracePrefix returns the addition to the level decoration of trace messages,
+// which shows the trace level.
+func tracePrefix(traceLevel int) string {
+	if traceLevel >= 0 && traceLevel < len(tracePrefixes) {
+		return tracePrefixes[traceLevel]
+	}
+	return "(" + strconv.Itoa(traceLevel) + ")"
+}
+
+// traceEnabled is the gate for all trace functions: It returns false if trace
+// output is disabled for all files, in which case they can return right away.
+// The caller needs to hold at least the read lock on initMutex.
+func traceEnabled() bool {
+	return len(traceFilterSpec.filters) > 0
+}
+
 // match checks if given filename and level are matched by
 // this filter. Returns two bools: One to indicate whether a filename match was
 // made, and the second to indicate whether the message should be logged
@@ -256,7 +383,7 @@ func (spec *filterSpec) matchfilters(filename string, level int) bool {
 func (f filter) match(filename string, level int) (bool, bool) {
 	var match bool
 	if f.Pattern != "" {
-		match, _ = filepath.Match(f.Pattern, filepath.Base(filename))
+		match = matchFilePattern(f.Pattern, filename)
 	} else {
 		match = true
 	}
@@ -279,32 +406,64 @@ func updateIfNeeded(oldVal string, newVal string, priority bool) string {
 }
 
 // updateConfigFromFile reads a configuration from the specified config file.
-// It merges the supplied config with the new values.
-func updateConfigFromFile(config *rlogConfig) {
+// It merges the supplied config with the new values. Errors in the file are
+// handled as RLOG_CONF_ERRORS demands. A notice about them, which should be
+// logged, is returned, or an empty string if there is none.
+func updateConfigFromFile(config *rlogConfig) string {
 	lastConfigFileCheck = time.Now()
+	if confFileDisabled {
+		return ""
+	}
 
 	settingConfFile = config.confFile
 	// If no config file was specified we will default to a known location.
 	if settingConfFile == "" {
-		execName := filepath.Base(os.Args[0])
-		settingConfFile = fmt.Sprintf("/etc/rlog/%s.conf", execName)
+		settingConfFile = defaultConfFile()
 	}
 
-	// Scan over the config file, line by line
+	// Read the config file, line by line
 	file, err := os.Open(settingConfFile)
 	if err != nil {
 		// Any error while attempting to open the logfile ignored. In many
 		// cases there won't even be a config file, so we should not produce
 		// any noise.
-		return
+		lastGoodConfLines = nil
+		return ""
 	}
-	defer file.Close()
-
+	var lines []string
 	scanner := bufio.NewScanner(file)
-	i := 0
 	for scanner.Scan() {
-		i++
-		line := strings.TrimSpace(scanner.Text())
+		lines = append(lines, scanner.Text())
+	}
+	file.Close()
+
+	fileConfig := *config
+	errs := applyConfigLines(&fileConfig, lines)
+	if len(errs) == 0 {
+		lastGoodConfFile = settingConfFile
+		lastGoodConfLines = lines
+		*config = fileConfig
+		return ""
+	}
+	return handleConfigErrors(config, fileConfig, errs)
+}
+
+// defaultConfDir is the directory in which the default config file is looked
+// for.
+var defaultConfDir = "/etc/rlog"
+
+// defaultConfFile returns the name of the config file that is used if none
+// was specified.
+func defaultConfFile() string {
+	return fmt.Sprintf("%s/%s.conf", defaultConfDir, filepath.Base(os.Args[0]))
+}
+
+// applyConfigLines merges the settings in the lines of a config file into the
+// config. A list of errors in the lines is returned.
+func applyConfigLines(config *rlogConfig, lines []string) []confFileError {
+	var errs []confFileError
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
 		if line == "" || line[0] == '#' {
 			continue
 		}
@@ -313,12 +472,15 @@ func updateConfigFromFile(config *rlogConfig) {
 			continue
 		}
 		if len(tokens) != 2 {
-			rlogIssue("Malformed line in config file %s:%d. Ignored.",
-				settingConfFile, i)
+			errs = append(errs, confFileError{i + 1, "Malformed line"})
 			continue
 		}
 		name := strings.TrimSpace(tokens[0])
 		val := strings.TrimSpace(tokens[1])
+		if name == "" {
+			errs = append(errs, confFileError{i + 1, "Malformed line"})
+			continue
+		}
 
 		// If the name starts with a '!' then it should overwrite whatever we
 		// currently have in the config already.
@@ -346,11 +508,103 @@ func updateConfigFromFile(config *rlogConfig) {
 			config.showCallerInfo = updateIfNeeded(config.showCallerInfo, val, priority)
 		case "RLOG_GOROUTINE_ID":
 			config.showGoroutineID = updateIfNeeded(config.showGoroutineID, val, priority)
+		case "RLOG_THREAD_ID":
+			config.showThreadID = updateIfNeeded(config.showThreadID, val, priority)
+		case "RLOG_CRASH_REPORT_DIR":
+			config.crashReportDir = updateIfNeeded(config.crashReportDir, val, priority)
+		case "RLOG_RUNTIME_STATS_INTERVAL":
+			config.statsInterv = updateIfNeeded(config.statsInterv, val, priority)
+		case "RLOG_RUNTIME_STATS_LEVEL":
+			config.statsLevel = updateIfNeeded(config.statsLevel, val, priority)
+		case "RLOG_COLLECTOR_SOCKET":
+			config.collectorSocket = updateIfNeeded(config.collectorSocket, val, priority)
+		case "RLOG_LOG_FORMAT":
+			config.logFormat = updateIfNeeded(config.logFormat, val, priority)
+		case "RLOG_COLOR":
+			config.color = updateIfNeeded(config.color, val, priority)
+		case "RLOG_TIME_UTC":
+			config.timeUTC = updateIfNeeded(config.timeUTC, val, priority)
+		case "RLOG_PRESET":
+			config.preset = updateIfNeeded(config.preset, val, priority)
+		case "RLOG_MAX_LINE_LENGTH":
+			config.maxLineLength = updateIfNeeded(config.maxLineLength, val, priority)
+		case "RLOG_FATAL_EXIT_CODE":
+			config.fatalExitCode = updateIfNeeded(config.fatalExitCode, val, priority)
+		case "RLOG_FATAL_TIMEOUT":
+			config.fatalTimeout = updateIfNeeded(config.fatalTimeout, val, priority)
+		case "RLOG_SAMPLE":
+			config.sample = updateIfNeeded(config.sample, val, priority)
+		case "RLOG_STREAM_BUFFER":
+			config.streamBuffer = updateIfNeeded(config.streamBuffer, val, priority)
+		case "RLOG_K8S_FIELDS":
+			config.k8sFields = updateIfNeeded(config.k8sFields, val, priority)
+		case "RLOG_SYSLOG":
+			config.syslog = updateIfNeeded(config.syslog, val, priority)
+		case "RLOG_SYSLOG_FACILITY":
+			config.syslogFacility = updateIfNeeded(config.syslogFacility, val, priority)
+		case "RLOG_SYSLOG_LEVELS":
+			config.syslogLevels = updateIfNeeded(config.syslogLevels, val, priority)
+		case "RLOG_FORMAT_STREAM":
+			config.formatStream = updateIfNeeded(config.formatStream, val, priority)
+		case "RLOG_FORMAT_FILE":
+			config.formatFile = updateIfNeeded(config.formatFile, val, priority)
+		case "RLOG_QUOTE":
+			config.quote = updateIfNeeded(config.quote, val, priority)
+		case "RLOG_LEVEL_RULES":
+			config.levelRules = updateIfNeeded(config.levelRules, val, priority)
+		case "RLOG_FILE_ORIGIN":
+			config.fileOrigin = updateIfNeeded(config.fileOrigin, val, priority)
+		case "RLOG_PRINT_LEVELS":
+			config.printLevels = updateIfNeeded(config.printLevels, val, priority)
+		case "RLOG_CALLER_INFO_LEVEL":
+			config.callerInfoLevel = updateIfNeeded(config.callerInfoLevel, val, priority)
+		case "RLOG_LOG_FILE_ROTATE":
+			config.logFileRotate = updateIfNeeded(config.logFileRotate, val, priority)
+		case "RLOG_STREAM_FALLBACK":
+			config.streamFallback = updateIfNeeded(config.streamFallback, val, priority)
+		case "RLOG_UPTIME_FIELDS":
+			config.uptimeFields = updateIfNeeded(config.uptimeFields, val, priority)
+		case "RLOG_SERVICE_MODE":
+			config.serviceMode = updateIfNeeded(config.serviceMode, val, priority)
+		case "RLOG_JOURNAL":
+			config.journal = updateIfNeeded(config.journal, val, priority)
+		case "RLOG_PRIORITY_PREFIX":
+			config.priorityPrefix = updateIfNeeded(config.priorityPrefix, val, priority)
+		case "RLOG_MONOTONIC":
+			config.monotonic = updateIfNeeded(config.monotonic, val, priority)
+		case "RLOG_CALLER_SKIP_PREFIXES":
+			config.callerSkip = updateIfNeeded(config.callerSkip, val, priority)
+		case "RLOG_CANONICAL_TIMES":
+			config.canonicalTimes = updateIfNeeded(config.canonicalTimes, val, priority)
+		case "RLOG_FIELD_ORDER":
+			config.fieldOrder = updateIfNeeded(config.fieldOrder, val, priority)
+		case "RLOG_IMPLICIT_TRACE":
+			config.implicitTrace = updateIfNeeded(config.implicitTrace, val, priority)
+		case "RLOG_BANNER":
+			config.banner = updateIfNeeded(config.banner, val, priority)
+		case "RLOG_MIN_FREE_DISK":
+			config.minFreeDisk = updateIfNeeded(config.minFreeDisk, val, priority)
+		case "RLOG_RECENT_ENTRIES":
+			config.recentEntries = updateIfNeeded(config.recentEntries, val, priority)
+		case "RLOG_WRITE_TIMEOUT":
+			config.writeTimeout = updateIfNeeded(config.writeTimeout, val, priority)
+		case "RLOG_GOROUTINE_CREATOR":
+			config.goCreator = updateIfNeeded(config.goCreator, val, priority)
+		case "RLOG_INTERNAL_OUTPUT":
+			config.internalOutput = updateIfNeeded(config.internalOutput, val, priority)
+		case "RLOG_INTERNAL_LEVEL":
+			config.internalLevel = updateIfNeeded(config.internalLevel, val, priority)
+		case "RLOG_MAX_BUFFER_MB":
+			config.maxBufferMB = updateIfNeeded(config.maxBufferMB, val, priority)
+		case "RLOG_SAMPLE_REPORT":
+			config.sampleReport = updateIfNeeded(config.sampleReport, val, priority)
+		case "RLOG_CALLER_WIDTH":
+			config.callerWidth = updateIfNeeded(config.callerWidth, val, priority)
 		default:
-			rlogIssue("Unknown or illegal setting name in config file %s:%d. Ignored.",
-				settingConfFile, i)
+			errs = append(errs, confFileError{i + 1, "Unknown or illegal setting name"})
 		}
 	}
+	return errs
 }
 
 // configFromEnv extracts settings for our logger from environment variables.
@@ -366,13 +620,126 @@ func configFromEnv() rlogConfig {
 		logNoTime:       os.Getenv("RLOG_LOG_NOTIME"),
 		showCallerInfo:  os.Getenv("RLOG_CALLER_INFO"),
 		showGoroutineID: os.Getenv("RLOG_GOROUTINE_ID"),
+		showThreadID:    os.Getenv("RLOG_THREAD_ID"),
 		confCheckInterv: os.Getenv("RLOG_CONF_CHECK_INTERVAL"),
+		crashReportDir:  os.Getenv("RLOG_CRASH_REPORT_DIR"),
+		statsInterv:     os.Getenv("RLOG_RUNTIME_STATS_INTERVAL"),
+		statsLevel:      os.Getenv("RLOG_RUNTIME_STATS_LEVEL"),
+		collectorSocket: os.Getenv("RLOG_COLLECTOR_SOCKET"),
+		fallbackLevel:   fallbackLogLevel(),
+		logFormat:       os.Getenv("RLOG_LOG_FORMAT"),
+		color:           os.Getenv("RLOG_COLOR"),
+		timeUTC:         os.Getenv("RLOG_TIME_UTC"),
+		preset:          os.Getenv("RLOG_PRESET"),
+		maxLineLength:   os.Getenv("RLOG_MAX_LINE_LENGTH"),
+		fatalExitCode:   os.Getenv("RLOG_FATAL_EXIT_CODE"),
+		fatalTimeout:    os.Getenv("RLOG_FATAL_TIMEOUT"),
+		sample:          os.Getenv("RLOG_SAMPLE"),
+		streamBuffer:    os.Getenv("RLOG_STREAM_BUFFER"),
+		k8sFields:       os.Getenv("RLOG_K8S_FIELDS"),
+		syslog:          os.Getenv("RLOG_SYSLOG"),
+		syslogFacility:  os.Getenv("RLOG_SYSLOG_FACILITY"),
+		syslogLevels:    os.Getenv("RLOG_SYSLOG_LEVELS"),
+		formatStream:    os.Getenv("RLOG_FORMAT_STREAM"),
+		formatFile:      os.Getenv("RLOG_FORMAT_FILE"),
+		quote:           os.Getenv("RLOG_QUOTE"),
+		levelRules:      os.Getenv("RLOG_LEVEL_RULES"),
+		fileOrigin:      os.Getenv("RLOG_FILE_ORIGIN"),
+		printLevels:     os.Getenv("RLOG_PRINT_LEVELS"),
+		callerInfoLevel: os.Getenv("RLOG_CALLER_INFO_LEVEL"),
+		logFileRotate:   os.Getenv("RLOG_LOG_FILE_ROTATE"),
+		streamFallback:  os.Getenv("RLOG_STREAM_FALLBACK"),
+		uptimeFields:    os.Getenv("RLOG_UPTIME_FIELDS"),
+		confErrors:      os.Getenv("RLOG_CONF_ERRORS"),
+		serviceMode:     os.Getenv("RLOG_SERVICE_MODE"),
+		journal:         os.Getenv("RLOG_JOURNAL"),
+		priorityPrefix:  os.Getenv("RLOG_PRIORITY_PREFIX"),
+		monotonic:       os.Getenv("RLOG_MONOTONIC"),
+		callerSkip:      os.Getenv("RLOG_CALLER_SKIP_PREFIXES"),
+		canonicalTimes:  os.Getenv("RLOG_CANONICAL_TIMES"),
+		fieldOrder:      os.Getenv("RLOG_FIELD_ORDER"),
+		implicitTrace:   os.Getenv("RLOG_IMPLICIT_TRACE"),
+		banner:          os.Getenv("RLOG_BANNER"),
+		minFreeDisk:     os.Getenv("RLOG_MIN_FREE_DISK"),
+		recentEntries:   os.Getenv("RLOG_RECENT_ENTRIES"),
+		writeTimeout:    os.Getenv("RLOG_WRITE_TIMEOUT"),
+		goCreator:       os.Getenv("RLOG_GOROUTINE_CREATOR"),
+		internalOutput:  os.Getenv("RLOG_INTERNAL_OUTPUT"),
+		internalLevel:   os.Getenv("RLOG_INTERNAL_LEVEL"),
+		maxBufferMB:     os.Getenv("RLOG_MAX_BUFFER_MB"),
+		sampleReport:    os.Getenv("RLOG_SAMPLE_REPORT"),
+		callerWidth:     os.Getenv("RLOG_CALLER_WIDTH"),
+	}
+}
+
+// Translation of log level names commonly used by other tools and platforms
+// to our own log level names. Only used for the generic LOG_LEVEL variable.
+var genericLevelNames = map[string]string{
+	"WARNING": "WARN",
+	"ERR":     "ERROR",
+	"FATAL":   "CRITICAL",
+	"CRIT":    "CRITICAL",
+	"TRACE":   "DEBUG",
+}
+
+// fallbackLogLevel returns the log level specified via the generic LOG_LEVEL
+// or DEBUG environment variables, which are often set by PaaS platforms and
+// other tooling. These are only used if RLOG_LOG_LEVEL isn't set.
+func fallbackLogLevel() string {
+	level := strings.ToUpper(strings.TrimSpace(os.Getenv("LOG_LEVEL")))
+	if name, ok := genericLevelNames[level]; ok {
+		level = name
+	}
+	if level == "" && isTrueBoolString(os.Getenv("DEBUG")) {
+		level = "DEBUG"
 	}
+	return level
 }
 
-// init loads configuration from the environment variables and the
-// configuration file when the module is imorted.
-func init() {
+// Configuration from the environment variables and the configuration file is
+// not loaded when the module is imported, but only when rlog is first used.
+// This allows programs, and especially tests, to set the environment
+// variables before that happens.
+var (
+	initDone uint32    // set to 1 once the configuration was loaded
+	initOnce sync.Once // used to load the configuration on first use
+)
+
+// ensureInitialized loads the configuration, unless that has happened already.
+// This must not be called while holding initMutex.
+func ensureInitialized() {
+	if atomic.LoadUint32(&initDone) == 0 {
+		initOnce.Do(func() {
+			if atomic.LoadUint32(&initDone) == 0 {
+				UpdateEnv()
+			}
+		})
+	}
+}
+
+// Reset discards all settings that were made programmatically, for example
+// with SetOutput, SetSampling, SetExitFunc, SetFatalPanics, SetClock,
+// SetTestMode, AddFilter, SetStreamFormatter or SetFileFormatter, and then
+// loads the configuration from the environment variables and the config file
+// again. This is mostly useful for tests.
+func Reset() {
+	initMutex.Lock()
+	settingSampleRate = 0
+	settingSampleLimit = 0
+	sampleFromConfig = false
+	confCheckIntervOverride = -1
+	confFileDisabled = false
+	settingTestMode = false
+	settingBackend = nil
+	streamOutput = nil
+	fileOutput = ""
+	addedFilters = nil
+	settingStreamFormatter = nil
+	settingFileFormatter = nil
+	initMutex.Unlock()
+	SetExitFunc(nil)
+	SetFatalPanics(false)
+	SetClock(nil)
 	UpdateEnv()
 }
 
@@ -382,43 +749,43 @@ func getTimeFormat(config rlogConfig) string {
 	settingDateTimeFormat = ""
 	logNoTime := isTrueBoolString(config.logNoTime)
 	if !logNoTime {
-		// Store the format string for date/time logging. Allowed values are
-		// all the constants specified in
-		// https://golang.org/src/time/format.go.
-		var f string
-		switch strings.ToUpper(config.logTimeFormat) {
-		case "ANSIC":
-			f = time.ANSIC
-		case "UNIXDATE":
-			f = time.UnixDate
-		case "RUBYDATE":
-			f = time.RubyDate
-		case "RFC822":
-			f = time.RFC822
-		case "RFC822Z":
-			f = time.RFC822Z
-		case "RFC1123":
-			f = time.RFC1123
-		case "RFC1123Z":
-			f = time.RFC1123Z
-		case "RFC3339":
-			f = time.RFC3339
-		case "RFC3339NANO":
-			f = time.RFC3339Nano
-		case "KITCHEN":
-			f = time.Kitchen
-		default:
-			if config.logTimeFormat != "" {
-				f = config.logTimeFormat
-			} else {
-				f = time.RFC3339
-			}
-		}
-		settingDateTimeFormat = f + " "
+		settingDateTimeFormat = timeLayout(config.logTimeFormat) + " "
 	}
 	return settingDateTimeFormat
 }
 
+// timeLayout returns the layout for a time format. Allowed values are the
+// names of all the constants specified in https://golang.org/src/time/format.go
+// or a layout of its own.
+func timeLayout(format string) string {
+	switch strings.ToUpper(format) {
+	case "ANSIC":
+		return time.ANSIC
+	case "UNIXDATE":
+		return time.UnixDate
+	case "RUBYDATE":
+		return time.RubyDate
+	case "RFC822":
+		return time.RFC822
+	case "RFC822Z":
+		return time.RFC822Z
+	case "RFC1123":
+		return time.RFC1123
+	case "RFC1123Z":
+		return time.RFC1123Z
+	case "RFC3339":
+		return time.RFC3339
+	case "RFC3339NANO":
+		return time.RFC3339Nano
+	case "KITCHEN":
+		return time.Kitchen
+	case "":
+		return time.RFC3339
+	default:
+		return format
+	}
+}
+
 // initialize translates config items into initialized data structures,
 // config values and freshly created or opened config files, if necessary.
 // This function prepares everything for the fast and efficient processing of
@@ -431,14 +798,31 @@ func initialize(config rlogConfig, reInitEnvVars bool) {
 	var err error
 
 	initMutex.Lock()
+	// This runs after the lock was released, so that the notified functions
+	// may log.
+	defer notifyConfigChange()
 	defer initMutex.Unlock()
+	defer updateEmergencyFiles()
+	atomic.StoreUint32(&initDone, 1)
 
 	if reInitEnvVars {
 		configFromEnvVars = config
 	}
 
 	// Read and merge configuration from the config file
-	updateConfigFromFile(&config)
+	confNotice := updateConfigFromFile(&config)
+
+	// The generic log level variables are only used if neither the
+	// environment nor the config file specified our own log level.
+	if config.logLevel == "" {
+		config.logLevel = config.fallbackLevel
+	}
+
+	// A preset provides defaults for anything that wasn't set explicitly.
+	applyPreset(&config)
+	appliedConfig = config
+	updateInternalOutput(config.internalOutput, config.internalLevel)
+	setMaxBufferBytes(parseMaxBufferMB(config.maxBufferMB))
 
 	var checkTime int
 	checkTime, err = strconv.Atoi(config.confCheckInterv)
@@ -450,18 +834,95 @@ func initialize(config rlogConfig, reInitEnvVars bool) {
 				config.confCheckInterv)
 		}
 	}
-	settingShowCallerInfo = isTrueBoolString(config.showCallerInfo)
+	if confCheckIntervOverride >= 0 {
+		settingCheckInterval = confCheckIntervOverride
+	}
+	settingShowCallerInfo, settingCallerInfoRules = parseCallerInfo(config.showCallerInfo)
+	settingCallerWidth, settingCallerAlignRight = parseCallerWidth(config.callerWidth)
+	settingCallerInfoLevel = levelNone
+	if config.callerInfoLevel != "" {
+		level, ok := levelNumbers[strings.ToUpper(config.callerInfoLevel)]
+		if ok && level != levelTrace {
+			settingCallerInfoLevel = level
+		} else {
+			rlogIssue("Unknown caller info level '%s'. Ignored.", config.callerInfoLevel)
+		}
+	}
 	settingShowGoroutineID = isTrueBoolString(config.showGoroutineID)
+	settingShowThreadID = isTrueBoolString(config.showThreadID)
+	settingColor = isTrueBoolString(config.color)
+	settingTimeUTC = isTrueBoolString(config.timeUTC)
+	settingMonotonic = isTrueBoolString(config.monotonic)
+	settingCanonicalTimes = isTrueBoolString(config.canonicalTimes)
+	settingGlobalFields = nil
+	if isTrueBoolString(config.k8sFields) {
+		settingGlobalFields = kubernetesFields()
+	}
+	settingLogFormat = getLogFormat(config)
+	settingQuote = getQuoteStyle(config)
+	settingSortFields = getFieldOrder(config)
+	settingLevelRules = parseLevelRules(config.levelRules)
+	settingCallerSkipPrefixes = parseSkipPrefixes(config.callerSkip)
+	settingFileOrigin = isTrueBoolString(config.fileOrigin)
+	settingPrintLevels = isTrueBoolString(config.printLevels)
+	settingUptimeFields = config.uptimeFields == "" || isTrueBoolString(config.uptimeFields)
+	settingStreamFormat = parseOutputFormat(config.formatStream)
+	settingFileFormat = parseOutputFormat(config.formatFile)
+	settingFatalExitCode = defaultFatalExitCode
+	if config.fatalExitCode != "" {
+		code, err := strconv.Atoi(config.fatalExitCode)
+		if err != nil {
+			rlogIssue("Cannot parse fatal exit code value '%s'. Using default.",
+				config.fatalExitCode)
+		} else {
+			settingFatalExitCode = code
+		}
+	}
+	settingFatalTimeout = defaultFatalTimeout
+	if config.fatalTimeout != "" {
+		secs, err := strconv.Atoi(config.fatalTimeout)
+		if err != nil || secs < 0 {
+			rlogIssue("Cannot parse fatal timeout value '%s'. Using default.",
+				config.fatalTimeout)
+		} else {
+			settingFatalTimeout = time.Duration(secs) * time.Second
+		}
+	}
+	settingMaxLineLength = 0
+	if config.maxLineLength != "" {
+		maxLen, err := strconv.Atoi(config.maxLineLength)
+		if err != nil || maxLen < 0 {
+			rlogIssue("Cannot parse max line length value '%s'. Ignored.",
+				config.maxLineLength)
+		} else {
+			settingMaxLineLength = maxLen
+		}
+	}
+	settingCrashReportDir = config.crashReportDir
+	recentEntries.enable(recentEntriesToKeep(config.recentEntries), recentEntriesBytes())
+	updateWriteTimeouts(config.writeTimeout)
+	settingGoroutineCreatorLevel = parseGoroutineCreatorLevel(config.goCreator)
+	updateRuntimeStats(config)
+	updateSampling(config)
+	updateSampleReport(config.sampleReport)
+	collectorClient.connect(config.collectorSocket)
+	syslogClient.connect(config.syslog, config.syslogFacility, config.syslogLevels)
 
 	// initialize filters for trace (by default no trace output) and log levels
 	// (by default INFO level).
-	newTraceFilterSpec := new(filterSpec)
-	newTraceFilterSpec.fromString(config.traceLevel, true, noTraceOutput)
-	traceFilterSpec = newTraceFilterSpec
-
 	newLogFilterSpec := new(filterSpec)
 	newLogFilterSpec.fromString(config.logLevel, false, levelInfo)
-	logFilterSpec = newLogFilterSpec
+	configuredLogFilters = newLogFilterSpec
+	logFilterSpec = withAddedFilters(newLogFilterSpec)
+
+	// Unless a trace level was set, it may follow from the log level.
+	traceLevel := config.traceLevel
+	if traceLevel == "" && config.implicitTrace != "" {
+		traceLevel = implicitTraceLevel(config.implicitTrace, newLogFilterSpec)
+	}
+	newTraceFilterSpec := new(filterSpec)
+	newTraceFilterSpec.fromString(traceLevel, true, noTraceOutput)
+	traceFilterSpec = newTraceFilterSpec
 
 	// Evaluate the specified date/time format
 	settingDateTimeFormat = getTimeFormat(config)
@@ -471,53 +932,161 @@ func initialize(config rlogConfig, reInitEnvVars bool) {
 	// By default (if flag is not set) we want to log date and time.
 	// Note that in our log writers we disable date/time loggin, since we will
 	// take care of producing this ourselves.
-	if config.logStream == "STDOUT" {
-		logWriterStream = log.New(os.Stdout, "", 0)
-	} else if config.logStream == "NONE" {
+	settingStreamName = "stderr"
+	streamNotice := ""
+	namedWriter := namedWriters[strings.ToUpper(config.logStream)]
+	inService := serviceMode(config.serviceMode)
+	settingStreamJournal = false
+	if streamOutput != nil {
+		settingStreamJournal = streamJournal(config.journal, streamOutput)
+		logWriterStream = log.New(newStreamWriter(streamOutput, config.streamBuffer), "", 0)
+	} else if namedWriter != nil {
+		settingStreamName = strings.ToLower(config.logStream)
+		settingStreamJournal = streamJournal(config.journal, namedWriter)
+		logWriterStream = log.New(newStreamWriter(namedWriter, config.streamBuffer), "", 0)
+	} else if config.logStream == "NONE" || inService {
+		// A service has no console, so its output goes to the logfile.
+		if inService {
+			settingStreamName = ""
+		}
+		newStreamWriter(nil, "")
 		logWriterStream = nil
 	} else {
-		logWriterStream = log.New(os.Stderr, "", 0)
-	}
-
-	// ... but if requested we'll also create and/or append to a logfile
-	var newLogFile *os.File
-	if currentLogFileName != config.logFile { // something changed
-		if config.logFile == "" {
-			// no more log output to a file
-			logWriterFile = nil
+		stream := os.Stderr
+		if config.logStream == "STDOUT" {
+			settingStreamName = "stdout"
+			stream = os.Stdout
+		}
+		if isTrueBoolString(config.streamFallback) {
+			settingStreamName, stream, streamNotice = fallbackStream(settingStreamName, stream)
+		}
+		if stream != nil {
+			settingStreamJournal = streamJournal(config.journal, stream)
+			logWriterStream = log.New(newStreamWriter(stream, config.streamBuffer), "", 0)
 		} else {
-			// Check if the logfile was changed or was set for the first
-			// time. Only then do we need to open/create a new file.
-			// We also do this if for some reason we don't have a log writer
-			// yet.
-			if currentLogFileName != config.logFile || logWriterFile == nil {
-				newLogFile, err = os.OpenFile(config.logFile,
-					os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-				if err == nil {
-					logWriterFile = log.New(newLogFile, "", 0)
-				} else {
-					rlogIssue("Unable to open log file: %s", err)
-					return
-				}
-			}
+			newStreamWriter(nil, "")
+			logWriterStream = nil
+		}
+	}
+	settingPriorityPrefix = priorityPrefix(config.priorityPrefix)
+
+	// ... but if requested we'll also create and/or append to a logfile. The
+	// logfile is opened again with every initialization, so that a logfile
+	// that was removed or moved away (by logrotate, for example) is created
+	// again once the configuration is re-read. Compressed logfiles are only
+	// opened again if their name changed, since every opening starts a new
+	// compressed stream.
+	if fileOutput != "" {
+		config.logFile = fileOutput
+	}
+	if inService && config.logFile == "" {
+		config.logFile = serviceLogFile()
+	}
+	updateMinFreeDisk(config)
+	if config.logFile == "" {
+		// no more log output to a file
+		logWriterFile = nil
+		closeLogFile()
+	} else if !keepLogFiles(config.logFile, getRotation(config)) {
+		newLogFile, err := openLogFiles(config.logFile, getRotation(config))
+		if err != nil {
+			rlogIssue("Unable to open log file: %s", err)
+			return
 		}
+		logWriterFile = log.New(newLogFile, "", 0)
 
 		// Close the old logfile, since we are now writing to a new file
-		if currentLogFileName != "" {
-			currentLogFile.Close()
-			currentLogFileName = config.logFile
-			currentLogFile = newLogFile
-		}
+		closeLogFile()
+		currentLogFileName = config.logFile
+		currentLogRotation = getRotation(config)
+		currentLogFile = newLogFile
+	}
+
+	// A fallback of the log stream and errors in the config file are noted,
+	// but only once.
+	if streamNotice != "" && streamNotice != lastStreamNotice {
+		writeNotice(levelWarn, streamNotice, nil)
+	}
+	lastStreamNotice = streamNotice
+	if confNotice != "" && confNotice != lastConfNotice {
+		writeNotice(levelWarn, confNotice, nil)
+	}
+	lastConfNotice = confNotice
+
+	if isTrueBoolString(config.banner) && !bannerWritten &&
+		(logWriterStream != nil || logWriterFile != nil) {
+		writeBanner()
 	}
 }
 
 // SetConfFile enables the programmatic setting of a new config file path.
-// Any config values specified in that file will be immediately applied.
+// Any config values specified in that file will be immediately applied. This
+// also enables the config file again, in case DisableConfFile was called.
 func SetConfFile(confFileName string) {
+	ensureInitialized()
+	initMutex.Lock()
+	confFileDisabled = false
+	initMutex.Unlock()
 	configFromEnvVars.confFile = confFileName
 	initialize(configFromEnvVars, false)
 }
 
+// SetConfCheckInterval sets how often rlog checks whether the config file has
+// changed. This takes precedence over RLOG_CONF_CHECK_INTERVAL. An interval of
+// 0 switches off the regular checks, so that the config file is only read
+// when the configuration is updated in some other way.
+func SetConfCheckInterval(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	// The override is set first, so that it already applies if this call
+	// triggers the initial configuration.
+	initMutex.Lock()
+	confCheckIntervOverride = d
+	settingCheckInterval = d
+	initMutex.Unlock()
+	ensureInitialized()
+}
+
+// DisableConfFile stops rlog from ever looking for or reading a config file,
+// for example in sandboxed environments, where accessing /etc is undesirable.
+// Any values that came from the config file are dropped, so that only the
+// configuration from the environment variables remains.
+func DisableConfFile() {
+	// The flag is set first, so that the config file is not read even if this
+	// call triggers the initial configuration.
+	initMutex.Lock()
+	confFileDisabled = true
+	initMutex.Unlock()
+	if atomic.LoadUint32(&initDone) == 0 {
+		ensureInitialized()
+		return
+	}
+	initialize(configFromEnvVars, false)
+}
+
+// ApplyVerbosity sets log and trace levels according to a verbosity count, as
+// it is typically given to command line programs via repeated '-v' flags: 0 is
+// the default INFO level, 1 is DEBUG and every count above that enables one
+// more trace level, so 2 means DEBUG with trace level 1, 3 means DEBUG with
+// trace level 2, and so on. A negative count results in WARN level.
+// The levels are applied as if they were set via environment variables.
+func ApplyVerbosity(n int) {
+	ensureInitialized()
+	switch {
+	case n < 0:
+		configFromEnvVars.logLevel = "WARN"
+		configFromEnvVars.traceLevel = ""
+	case n == 0:
+		configFromEnvVars.logLevel = "INFO"
+		configFromEnvVars.traceLevel = ""
+	default:
+		configFromEnvVars.logLevel = "DEBUG"
+		configFromEnvVars.traceLevel = strconv.Itoa(n - 1)
+	}
+	initialize(configFromEnvVars, false)
+}
+
 // UpdateEnv extracts settings for our logger from environment variables and
 // calls the actual initialization function with that configuration.
 func UpdateEnv() {
@@ -528,18 +1097,65 @@ func UpdateEnv() {
 	initialize(config, true)
 }
 
+// ReinitializeFromEnv re-reads all RLOG_* environment variables and applies
+// them, together with the config file, just like during startup. Values that
+// are marked with a '!' in the config file still take precedence. This is the
+// same as UpdateEnv, but the name makes it clearer for agents that modify the
+// environment of a running process and then need to trigger a refresh.
+func ReinitializeFromEnv() {
+	UpdateEnv()
+}
+
 // SetOutput re-wires the log output to a new io.Writer. By default rlog
 // logs to os.Stderr, but this function can be used to direct the output
 // somewhere else. If output to two destinations was specified via environment
 // variables then this will change it back to just one output.
 func SetOutput(writer io.Writer) {
+	ensureInitialized()
+	initMutex.Lock()
 	// Use the stored date/time flag settings
+	flushStream()
+	currentStreamBuffer = nil
 	logWriterStream = log.New(writer, "", 0)
 	logWriterFile = nil
-	if currentLogFile != nil {
-		currentLogFile.Close()
-		currentLogFileName = ""
+	closeLogFile()
+	rawStream = writer
+	updateEmergencyFiles()
+	initMutex.Unlock()
+	notifyConfigChange()
+}
+
+// SetStreamOutput redirects only the log stream to a new io.Writer, for
+// example into a widget of a GUI, while the configured logfile keeps working.
+// Unlike with SetOutput, this remains in effect when the configuration is
+// updated. Setting nil goes back to the configured log stream.
+func SetStreamOutput(writer io.Writer) {
+	ensureInitialized()
+	initMutex.Lock()
+	streamOutput = writer
+	initMutex.Unlock()
+	initialize(configFromEnvVars, false)
+}
+
+// SetFileOutput makes rlog write to the logfile with the given name, instead
+// of the one set with RLOG_LOG_FILE, while the log stream is not affected.
+// This remains in effect when the configuration is updated. An empty name goes
+// back to the configured logfile.
+func SetFileOutput(fileName string) error {
+	ensureInitialized()
+	if fileName != "" {
+		// Find out early whether we can write to the file
+		f, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		f.Close()
 	}
+	initMutex.Lock()
+	fileOutput = fileName
+	initMutex.Unlock()
+	initialize(configFromEnvVars, false)
+	return nil
 }
 
 // isTrueBoolString tests a string to see if it represents a 'true' value.
@@ -559,97 +1175,286 @@ func isTrueBoolString(str string) bool {
 // rlogIssue is used by rlog itself to report issues or problems. This is mostly
 // independent of the standard logging settings, since a problem may have
 // occurred while trying to establish the standard settings. So, where can rlog
-// itself report any problems? For now, we just write those out to stderr.
+// itself report any problems? By default, we just write those out to stderr,
+// but they can be sent elsewhere with RLOG_INTERNAL_OUTPUT or
+// SetInternalOutput.
 func rlogIssue(prefix string, a ...interface{}) {
 	fmtStr := fmt.Sprintf("rlog - %s\n", prefix)
-	fmt.Fprintf(os.Stderr, fmtStr, a...)
+	writeInternal(levelWarn, fmt.Sprintf(fmtStr, a...))
+	recentIssues.add(time.Now().Format(time.RFC3339) + " " + fmt.Sprintf(prefix, a...))
 }
 
 // basicLog is called by all the 'level' log functions.
 // It checks what is configured to be included in the log message, decorates it
 // accordingly and assembles the entire line. It then uses the standard log
-// package to finally output the message.
-func basicLog(logLevel int, traceLevel int, isLocked bool, format string, prefixAddition string, a ...interface{}) {
-	now := time.Now()
+// package to finally output the message. The logger is nil for the package
+// level log functions.
+func basicLog(l *Logger, logLevel int, traceLevel int, isLocked bool, format string, prefixAddition string, a ...interface{}) {
+	now := currentTime()
 
-	// In some cases the caller already got this lock for us
+	// In some cases the caller already got this lock for us, which also means
+	// that it took care of the initialization.
 	if !isLocked {
+		ensureInitialized()
 		initMutex.RLock()
 		defer initMutex.RUnlock()
 	}
 
-	// Check if it's time to load updated information from the config file
-	if settingCheckInterval > 0 && now.Sub(lastConfigFileCheck) > settingCheckInterval {
+	checkConfigFile()
+
+	// Extract information about the caller of the log function, which is
+	// needed for filtering and possibly also for the output.
+	caller := getCaller(3)
+	logEntry(now, l, logLevel, traceLevel, caller, format, prefixAddition, a...)
+}
+
+// checkConfigFile loads updated information from the config file, if it's
+// time to do so. The caller needs to hold the read lock on initMutex, which
+// may be released meanwhile.
+func checkConfigFile() {
+	if settingCheckInterval > 0 && time.Since(lastConfigFileCheck) > settingCheckInterval {
 		// This unlock always happens, since initMutex is locked at this point,
 		// either by this function or the caller Initialize needs to be able to
 		initMutex.RUnlock()
 		// Get the full lock, so we need to release ours.
-		initialize(configFromEnvVars, false)
+		reloadConfig()
 		// Take our reader lock again. This is fine, since only the check
 		// interval related items were read earlier.
 		initMutex.RLock()
 	}
+}
 
-	// Extract information about the caller of the log function, if requested.
-	var callingFuncName string
-	var moduleAndFileName string
-	pc, fullFilePath, line, ok := runtime.Caller(2)
-	if ok {
-		callingFuncName = runtime.FuncForPC(pc).Name()
-		// We only want to print or examine file and package name, so use the
-		// last two elements of the full path. The path package deals with
-		// different path formats on different systems, so we use that instead
-		// of just string-split.
-		dirPath, fileName := path.Split(fullFilePath)
-		var moduleName string
-		if dirPath != "" {
-			dirPath = dirPath[:len(dirPath)-1]
-			_, moduleName = path.Split(dirPath)
-		}
-		moduleAndFileName = moduleName + "/" + fileName
+// callerData describes the location in the code from which a log function was
+// called.
+type callerData struct {
+	funcName          string // name of the calling function
+	moduleAndFileName string // last directory and name of the calling file
+	line              int    // line number in the calling file
+}
+
+// getCaller extracts information about the caller of a log function. The skip
+// parameter has the same meaning as for runtime.Caller(). The caller needs to
+// hold at least the read lock on initMutex.
+func getCaller(skip int) callerData {
+	if len(settingCallerSkipPrefixes) > 0 {
+		return getCallerSkipping(skip)
+	}
+	// This is what runtime.Caller does, but with a cache for the result.
+	var pcs [1]uintptr
+	if runtime.Callers(skip+1, pcs[:]) == 0 {
+		return callerData{}
 	}
+	return cachedCaller(pcs[0])
+}
 
-	// Perform tests to see if we should log this message.
-	var allowLog bool
-	if traceLevel == notATrace {
-		if logFilterSpec.matchfilters(moduleAndFileName, logLevel) {
-			allowLog = true
-		}
-	} else {
-		if traceFilterSpec.matchfilters(moduleAndFileName, traceLevel) {
-			allowLog = true
-		}
+// newCallerData returns the caller information for a location in the code.
+func newCallerData(funcName string, fullFilePath string, line int) callerData {
+	// We only want to print or examine file and package name, so use the
+	// last two elements of the full path. The path package deals with
+	// different path formats on different systems, so we use that instead
+	// of just string-split.
+	dirPath, fileName := path.Split(fullFilePath)
+	var moduleName string
+	if dirPath != "" {
+		dirPath = dirPath[:len(dirPath)-1]
+		_, moduleName = path.Split(dirPath)
 	}
+	return callerData{
+		funcName:          funcName,
+		moduleAndFileName: moduleName + "/" + fileName,
+		line:              line,
+	}
+}
+
+// logEntry checks whether a message from the given caller should be logged
+// and, if so, assembles and writes the log entry. The caller needs to hold at
+// least the read lock on initMutex.
+func logEntry(now time.Time, l *Logger, logLevel int, traceLevel int, caller callerData, format string, prefixAddition string, a ...interface{}) {
+	// Perform tests to see if we should log this message.
+	logLevel, allowLog := filterEntry(l, logLevel, traceLevel, &caller)
 	if !allowLog {
+		countSuppressed(caller.moduleAndFileName, logLevel, traceLevel)
+		return
+	}
+	logFiltered(now, l, logLevel, traceLevel, caller, format, prefixAddition, a...)
+}
+
+// logFiltered logs a message that passed the filters, with the level that
+// filterEntry returned, unless it is sampled out. The caller needs to hold at
+// least the read lock on initMutex.
+func logFiltered(now time.Time, l *Logger, logLevel int, traceLevel int, caller callerData, format string, prefixAddition string, a ...interface{}) {
+	if !sampleIn(now, l, logLevel) {
+		countSampledOut(&caller)
 		return
 	}
+	countMessage(&loggedCounts, caller.moduleAndFileName, logLevel, traceLevel)
+	emitEntry(now, l, logLevel, traceLevel, caller, format, prefixAddition, a...)
+}
+
+// filterEntry returns the level with which a message is logged, after
+// applying the level rules, and whether it passes the filters. The caller
+// needs to hold at least the read lock on initMutex.
+func filterEntry(l *Logger, logLevel int, traceLevel int, caller *callerData) (int, bool) {
+	name := callerName(l, caller)
+	if traceLevel != notATrace {
+		return logLevel, traceFilterSpec.matchfilters(name, traceLevel)
+	}
+	if len(settingLevelRules) > 0 {
+		logLevel = applyLevelRules(name, logLevel)
+	}
+	return logLevel, logFilterSpec.matchfilters(name, logLevel)
+}
 
-	callerInfo := ""
-	if settingShowCallerInfo {
+// emitEntry assembles and writes a log entry that passed the filters. This is
+// kept apart from logEntry, so that the caller info only ends up on the heap
+// for entries that are actually logged. The caller needs to hold at least the
+// read lock on initMutex.
+func emitEntry(now time.Time, l *Logger, logLevel int, traceLevel int, caller callerData, format string, prefixAddition string, a ...interface{}) {
+	record := logRecord{
+		time:            now,
+		level:           logLevel,
+		levelDecoration: levelStrings[logLevel] + prefixAddition,
+		traceLevel:      traceLevel,
+		timeFormat:      settingDateTimeFormat,
+	}
+	if l != nil && l.hasTimeFormat {
+		record.timeFormat = l.timeFormat
+	}
+	if l != nil {
+		record.topic = l.topic
+		record.writeTimeout = l.writeTimeout
+	}
+	record.fields = resolveLazyFields(entryFields(l))
+	// An entry passed to Emit already has the fields that are added here.
+	emitted := l != nil && l.emitted
+	if logLevel == levelCrit && settingUptimeFields && !emitted {
+		n := len(record.fields)
+		record.fields = append(record.fields[:n:n], uptimeFields(now)...)
+	}
+	if logLevel == levelTrace && settingGoroutineCreatorLevel != notATrace &&
+		traceLevel >= settingGoroutineCreatorLevel && !emitted {
+		if site := goroutineCreator(); site != "" {
+			n := len(record.fields)
+			record.fields = append(record.fields[:n:n],
+				field{key: "goroutine_creator", kind: fieldString, str: site})
+		}
+	}
+	showCallerInfo := settingShowCallerInfo
+	if len(settingCallerInfoRules) > 0 {
+		showCallerInfo = showCallerInfoFor(callerName(l, &caller))
+	}
+	showCallerInfo = showCallerInfo || logLevel <= settingCallerInfoLevel ||
+		(l != nil && l.withCaller)
+	// There is nothing to show for an emitted entry without a caller.
+	if emitted && caller.moduleAndFileName == "" {
+		showCallerInfo = false
+	}
+	if showCallerInfo && settingTestMode {
+		stable := stableCaller(caller)
+		record.caller = &stable
+	} else if showCallerInfo {
+		record.caller = &caller
 		if settingShowGoroutineID {
-			callerInfo = fmt.Sprintf("[%d:%d %s:%d (%s)] ", os.Getpid(),
-				getGID(), moduleAndFileName, line, callingFuncName)
-		} else {
-			callerInfo = fmt.Sprintf("[%d %s:%d (%s)] ", os.Getpid(),
-				moduleAndFileName, line, callingFuncName)
+			record.goroutineID = getGID()
+		}
+		if settingShowThreadID {
+			record.threadID = threadID()
 		}
 	}
 
-	// Assemble the actual log line
-	var msg string
+	// Assemble the actual log message
+	if settingCanonicalTimes {
+		a = canonicalArgs(a)
+	}
 	if format != "" {
-		msg = fmt.Sprintf(format, a...)
+		var err error
+		format, a, err = errorArgs(format, a)
+		record.msg = fmt.Sprintf(format, a...)
+		if err != nil {
+			n := len(record.fields)
+			record.fields = append(record.fields[:n:n], errorFields(errorKey(record.fields), err)...)
+		}
 	} else {
-		msg = fmt.Sprintln(a...)
+		record.msg = fmt.Sprintln(a...)
+	}
+	if settingBackend != nil {
+		settingBackend(&record, traceLevel)
+	} else {
+		writeRecord(&record)
+	}
+	if len(hooks) > 0 && !emitted {
+		runHooks(&record, traceLevel, &caller)
+	}
+
+	if logLevel == levelCrit && settingCrashReportDir != "" && (l == nil || !l.panicReport) {
+		writeCrashReport(now, record.msg)
+	}
+}
+
+// outputLines holds the fully assembled lines of a log entry for the outputs.
+// Output to the stream and the logfile may differ from the log line (for
+// example by using colors or a different format), as may the lines for
+// outputs that have their own clock.
+type outputLines struct {
+	log       string // the line that is kept in memory
+	stream    string
+	file      string // empty if the entry is not written to the logfile
+	collector string
+	tee       string
+}
+
+// sameLines returns the lines for an entry that is written the same way to
+// all outputs.
+func sameLines(line string) outputLines {
+	return outputLines{log: line, stream: line, file: line, collector: line, tee: line}
+}
+
+// writeOutputs sends the lines of a log entry to all configured outputs. A
+// timeout replaces the configured write timeouts, if it is not 0. The caller
+// needs to hold at least the read lock on initMutex.
+func writeOutputs(lines outputLines, timeout time.Duration) {
+	recentEntries.add(lines.log)
+	if settingHasWriteTimeouts || timeout > 0 {
+		writeOutputsWithin(lines, timeout)
+		return
 	}
-	levelDecoration := levelStrings[logLevel] + prefixAddition
-	logLine := fmt.Sprintf("%s%-9s: %s%s",
-		now.Format(settingDateTimeFormat), levelDecoration, callerInfo, msg)
+	collectorClient.send(lines.collector)
+	writeTees(lines.tee)
 	if logWriterStream != nil {
-		logWriterStream.Print(logLine)
+		logWriterStream.Print(lines.stream)
 	}
-	if logWriterFile != nil {
-		logWriterFile.Print(logLine)
+	if logWriterFile != nil && lines.file != "" {
+		logWriterFile.Print(lines.file)
+	}
+}
+
+// writeOutputsWithin is like writeOutputs, but abandons writes to outputs that
+// don't complete within their write timeout. The caller needs to hold at least
+// the read lock on initMutex.
+func writeOutputsWithin(lines outputLines, timeout time.Duration) {
+	if line, d := lines.collector, writeTimeout(sinkCollector, timeout); d > 0 {
+		writeWithin(sinkCollector, d, func() { collectorClient.send(line) })
+	} else {
+		collectorClient.send(line)
+	}
+	if line, d := lines.tee, writeTimeout(sinkTee, timeout); d > 0 {
+		writeWithin(sinkTee, d, func() { writeTees(line) })
+	} else {
+		writeTees(line)
+	}
+	if w, line := logWriterStream, lines.stream; w != nil {
+		if d := writeTimeout(sinkStream, timeout); d > 0 {
+			writeWithin(sinkStream, d, func() { w.Print(line) })
+		} else {
+			w.Print(line)
+		}
+	}
+	if w, line := logWriterFile, lines.file; w != nil && line != "" {
+		if d := writeTimeout(sinkFile, timeout); d > 0 {
+			writeWithin(sinkFile, d, func() { w.Print(line) })
+		} else {
+			w.Print(line)
+		}
 	}
 }
 
@@ -673,11 +1478,12 @@ func getGID() uint64 {
 func Trace(traceLevel int, a ...interface{}) {
 	// There are possibly many trace messages. If trace logging isn't enabled
 	// then we want to get out of here as quickly as possible.
+	ensureInitialized()
 	initMutex.RLock()
 	defer initMutex.RUnlock()
-	if len(traceFilterSpec.filters) > 0 {
-		prefixAddition := fmt.Sprintf("(%d)", traceLevel)
-		basicLog(levelTrace, traceLevel, true, "", prefixAddition, a...)
+	if traceEnabled() {
+		prefixAddition := tracePrefix(traceLevel)
+		basicLog(nil, levelTrace, traceLevel, true, "", prefixAddition, a...)
 	}
 }
 
@@ -685,79 +1491,131 @@ func Trace(traceLevel int, a ...interface{}) {
 func Tracef(traceLevel int, format string, a ...interface{}) {
 	// There are possibly many trace messages. If trace logging isn't enabled
 	// then we want to get out of here as quickly as possible.
+	ensureInitialized()
 	initMutex.RLock()
 	defer initMutex.RUnlock()
-	if len(traceFilterSpec.filters) > 0 {
-		prefixAddition := fmt.Sprintf("(%d)", traceLevel)
-		basicLog(levelTrace, traceLevel, true, format, prefixAddition, a...)
+	if traceEnabled() {
+		prefixAddition := tracePrefix(traceLevel)
+		basicLog(nil, levelTrace, traceLevel, true, format, prefixAddition, a...)
+	}
+}
+
+// TraceStack prints a trace message, followed by the stack of the current
+// goroutine. This helps to find out which path through the code led to the
+// trace message. The stack is only formatted if the message is logged.
+func TraceStack(traceLevel int, a ...interface{}) {
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	if traceEnabled() {
+		prefixAddition := tracePrefix(traceLevel)
+		basicLog(nil, levelTrace, traceLevel, true, "%s%s", prefixAddition,
+			fmt.Sprintln(a...), newLazyStack(2))
 	}
 }
 
 // Debug prints a message if RLOG_LEVEL is set to DEBUG.
 func Debug(a ...interface{}) {
-	basicLog(levelDebug, notATrace, false, "", "", a...)
+	basicLog(nil, levelDebug, notATrace, false, "", "", a...)
 }
 
 // Debugf prints a message if RLOG_LEVEL is set to DEBUG, with formatting.
 func Debugf(format string, a ...interface{}) {
-	basicLog(levelDebug, notATrace, false, format, "", a...)
+	basicLog(nil, levelDebug, notATrace, false, format, "", a...)
 }
 
 // Info prints a message if RLOG_LEVEL is set to INFO or lower.
 func Info(a ...interface{}) {
-	basicLog(levelInfo, notATrace, false, "", "", a...)
+	basicLog(nil, levelInfo, notATrace, false, "", "", a...)
 }
 
 // Infof prints a message if RLOG_LEVEL is set to INFO or lower, with
 // formatting.
 func Infof(format string, a ...interface{}) {
-	basicLog(levelInfo, notATrace, false, format, "", a...)
+	basicLog(nil, levelInfo, notATrace, false, format, "", a...)
 }
 
-// Println prints a message if RLOG_LEVEL is set to INFO or lower.
+// Print prints a message if RLOG_LEVEL is set to INFO or lower. If
+// RLOG_PRINT_LEVELS is switched on and the message starts with a level name,
+// such as "ERROR:", then it is logged with that level instead.
+// Print shouldn't be used except for backward compatibility
+// with standard log package, directly using Info is preferred way.
+func Print(a ...interface{}) {
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	basicLog(nil, printArgsLevel(a), notATrace, true, "", "", a...)
+}
+
+// Println prints a message if RLOG_LEVEL is set to INFO or lower. A level
+// name at the start of the message is handled as for Print.
 // Println shouldn't be used except for backward compatibility
 // with standard log package, directly using Info is preferred way.
 func Println(a ...interface{}) {
-	basicLog(levelInfo, notATrace, false, "", "", a...)
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	basicLog(nil, printArgsLevel(a), notATrace, true, "", "", a...)
 }
 
 // Printf prints a message if RLOG_LEVEL is set to INFO or lower, with
-// formatting.
+// formatting. A level name at the start of the format string is handled as
+// for Print.
 // Printf shouldn't be used except for backward compatibility
 // with standard log package, directly using Infof is preferred way.
 func Printf(format string, a ...interface{}) {
-	basicLog(levelInfo, notATrace, false, format, "", a...)
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	basicLog(nil, printLevel(format), notATrace, true, format, "", a...)
 }
 
 // Warn prints a message if RLOG_LEVEL is set to WARN or lower.
 func Warn(a ...interface{}) {
-	basicLog(levelWarn, notATrace, false, "", "", a...)
+	basicLog(nil, levelWarn, notATrace, false, "", "", a...)
 }
 
 // Warnf prints a message if RLOG_LEVEL is set to WARN or lower, with
 // formatting.
 func Warnf(format string, a ...interface{}) {
-	basicLog(levelWarn, notATrace, false, format, "", a...)
+	basicLog(nil, levelWarn, notATrace, false, format, "", a...)
 }
 
 // Error prints a message if RLOG_LEVEL is set to ERROR or lower.
 func Error(a ...interface{}) {
-	basicLog(levelErr, notATrace, false, "", "", a...)
+	basicLog(nil, levelErr, notATrace, false, "", "", a...)
 }
 
 // Errorf prints a message if RLOG_LEVEL is set to ERROR or lower, with
 // formatting.
 func Errorf(format string, a ...interface{}) {
-	basicLog(levelErr, notATrace, false, format, "", a...)
+	basicLog(nil, levelErr, notATrace, false, format, "", a...)
+}
+
+// Fatal prints a message at CRITICAL level and then exits the program. See
+// SetExitFunc and RLOG_FATAL_EXIT_CODE for how the exit can be configured, and
+// RLOG_FATAL_TIMEOUT for how long it waits for the output to be written.
+func Fatal(a ...interface{}) {
+	e := startFatal()
+	basicLog(nil, levelCrit, notATrace, false, "", "", a...)
+	e.finish()
+}
+
+// Fatalf prints a message at CRITICAL level, with formatting, and then exits
+// the program.
+func Fatalf(format string, a ...interface{}) {
+	e := startFatal()
+	basicLog(nil, levelCrit, notATrace, false, format, "", a...)
+	e.finish()
 }
 
 // Critical prints a message if RLOG_LEVEL is set to CRITICAL or lower.
 func Critical(a ...interface{}) {
-	basicLog(levelCrit, notATrace, false, "", "", a...)
+	basicLog(nil, levelCrit, notATrace, false, "", "", a...)
 }
 
 // Criticalf prints a message if RLOG_LEVEL is set to CRITICAL or lower, with
 // formatting.
 func Criticalf(format string, a ...interface{}) {
-	basicLog(levelCrit, notATrace, false, format, "", a...)
+	basicLog(nil, levelCrit, notATrace, false, format, "", a...)
 }
@@ -17,11 +17,15 @@ package rlog
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"runtime"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -58,6 +62,7 @@ func setup() rlogConfig {
 		logStream:      "NONE",
 		logNoTime:      "true",
 		showCallerInfo: "false",
+		uptimeFields:   "no",
 	}
 }
 
@@ -316,6 +321,58 @@ func TestLogLevelsFiltered(t *testing.T) {
 	fileMatch(t, checkLines, "")
 }
 
+// TestMatchFilters checks the filter decisions, including those that are made
+// by the level alone.
+func TestMatchFilters(t *testing.T) {
+	tests := []struct {
+		spec     string
+		trace    bool
+		file     string
+		level    int
+		expected bool
+	}{
+		{"WARN", false, "a.go", levelErr, true},
+		{"WARN", false, "a.go", levelInfo, false},
+		{"a.go=DEBUG,ERROR", false, "a.go", levelDebug, true},
+		{"a.go=DEBUG,ERROR", false, "b.go", levelDebug, false},
+		{"a.go=DEBUG,ERROR", false, "b.go", levelErr, true},
+		{"a.go=CRITICAL,DEBUG", false, "a.go", levelErr, false},
+		{"a.go=2", true, "a.go", 1, true},
+		{"a.go=2", true, "b.go", 1, false},
+		{"a.go=2", true, "a.go", 3, false},
+		{"a.go=2,1", true, "b.go", 1, true},
+		{"agent/*=DEBUG", false, "agent/client.go", levelDebug, true},
+		{"agent/*=DEBUG", false, "server/client.go", levelDebug, false},
+		{"*/client.go=DEBUG", false, "server/client.go", levelDebug, true},
+		{"client.go=DEBUG", false, "server/client.go", levelDebug, true},
+	}
+	for _, test := range tests {
+		spec := new(filterSpec)
+		if test.trace {
+			spec.fromString(test.spec, true, noTraceOutput)
+		} else {
+			spec.fromString(test.spec, false, levelInfo)
+		}
+		if spec.matchfilters(test.file, test.level) != test.expected {
+			t.Errorf("Incorrect decision for %s at level %d with '%s'",
+				test.file, test.level, test.spec)
+		}
+	}
+}
+
+// BenchmarkFilteredDebug measures the cost of a message that is suppressed by
+// the log level.
+func BenchmarkFilteredDebug(b *testing.B) {
+	conf := setup()
+	defer cleanup()
+	conf.logLevel = "client.go=DEBUG,ip*=WARN,INFO"
+	initialize(conf, true)
+
+	for i := 0; i < b.N; i++ {
+		Debug("Test Debug")
+	}
+}
+
 // writeLogfile is a small utility function for the creation of unique config
 // files for these tests.
 func writeLogfile(lines []string) string {
@@ -409,3 +466,253 @@ func TestRaceConditions(t *testing.T) {
 		}(conf, i)
 	}
 }
+
+// TestRaceConditionsComplete checks that the goroutines started by
+// TestRaceConditions all complete, so that concurrent initializations and log
+// calls don't deadlock. This also keeps them from interfering with the tests
+// that follow.
+func TestRaceConditionsComplete(t *testing.T) {
+	// They are done once the number of goroutines doesn't go down anymore.
+	deadline := time.Now().Add(time.Minute)
+	last, stable := runtime.NumGoroutine(), 0
+	for stable < 5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines still running", runtime.NumGoroutine())
+		}
+		time.Sleep(50 * time.Millisecond)
+		n := runtime.NumGoroutine()
+		if n == last && n < 100 {
+			stable++
+		} else {
+			stable = 0
+		}
+		last = n
+	}
+}
+
+// TestFallbackLogLevel checks that the generic LOG_LEVEL and DEBUG variables
+// are used if RLOG_LOG_LEVEL isn't set.
+func TestFallbackLogLevel(t *testing.T) {
+	defer os.Unsetenv("LOG_LEVEL")
+	defer os.Unsetenv("DEBUG")
+
+	checks := []struct {
+		logLevel string
+		debug    string
+		should   string
+	}{
+		{"", "", ""},
+		{"warning", "", "WARN"},
+		{"Error", "1", "ERROR"},
+		{"", "yes", "DEBUG"},
+		{"", "false", ""},
+	}
+	for _, c := range checks {
+		os.Setenv("LOG_LEVEL", c.logLevel)
+		os.Setenv("DEBUG", c.debug)
+		if is := fallbackLogLevel(); is != c.should {
+			t.Fatalf("Incorrect fallback level for LOG_LEVEL='%s', DEBUG='%s'. Should: '%s', is: '%s'",
+				c.logLevel, c.debug, c.should, is)
+		}
+	}
+
+	// The fallback is only used if no log level was set otherwise
+	conf := setup()
+	defer cleanup()
+	conf.fallbackLevel = "ERROR"
+	initialize(conf, true)
+	checkLogFilter(t, "", levelErr)
+	conf.logLevel = "DEBUG"
+	initialize(conf, true)
+	checkLogFilter(t, "", levelDebug)
+}
+
+// TestApplyVerbosity checks the translation of verbosity counts into log and
+// trace levels.
+func TestApplyVerbosity(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	ApplyVerbosity(0)
+	Debug("Test Debug 0")
+	Info("Test Info 0")
+	ApplyVerbosity(1)
+	Debug("Test Debug 1")
+	Trace(1, "Trace 1")
+	ApplyVerbosity(3)
+	Trace(2, "Trace 2")
+	Trace(3, "Trace 3")
+
+	checkLines := []string{
+		"INFO     : Test Info 0",
+		"DEBUG    : Test Debug 1",
+		"TRACE(2) : Trace 2",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestReset checks that Reset reads the environment variables again and
+// discards settings that were made programmatically.
+func TestReset(t *testing.T) {
+	setup()
+	defer cleanup()
+	for k, v := range map[string]string{
+		"RLOG_LOG_FILE":    logfile,
+		"RLOG_LOG_STREAM":  "NONE",
+		"RLOG_LOG_NOTIME":  "yes",
+		"RLOG_LOG_LEVEL":   "DEBUG",
+		"RLOG_TRACE_LEVEL": "",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	SetSampling(LevelDebug, 1000)
+	Reset()
+	Debug("Test Debug")
+	Info("Test Info")
+
+	checkLines := []string{
+		"DEBUG    : Test Debug",
+		"INFO     : Test Info",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestReinitializeFromEnv checks that changed environment variables are picked
+// up, unless the config file insists on its own value.
+func TestReinitializeFromEnv(t *testing.T) {
+	setup()
+	defer cleanup()
+	os.Setenv("RLOG_LOG_STREAM", "NONE")
+	defer os.Unsetenv("RLOG_LOG_STREAM")
+	defer os.Unsetenv("RLOG_LOG_LEVEL")
+
+	os.Setenv("RLOG_LOG_LEVEL", "WARN")
+	ReinitializeFromEnv()
+	checkLogFilter(t, "", levelWarn)
+
+	os.Setenv("RLOG_LOG_LEVEL", "DEBUG")
+	ReinitializeFromEnv()
+	checkLogFilter(t, "", levelDebug)
+
+	confFile := writeLogfile([]string{"!RLOG_LOG_LEVEL=ERROR"})
+	defer os.Remove(confFile)
+	os.Setenv("RLOG_CONF_FILE", confFile)
+	defer os.Unsetenv("RLOG_CONF_FILE")
+	ReinitializeFromEnv()
+	checkLogFilter(t, "", levelErr)
+}
+
+// TestDisableConfFile checks that the config file can be switched off and on
+// again and that the check interval can be set programmatically.
+func TestDisableConfFile(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer func() {
+		confFileDisabled = false
+		confCheckIntervOverride = -1
+	}()
+
+	conf.confFile = writeLogfile([]string{"RLOG_LOG_LEVEL=DEBUG"})
+	defer os.Remove(conf.confFile)
+	initialize(conf, true)
+	checkLogFilter(t, "", levelDebug)
+
+	DisableConfFile()
+	checkLogFilter(t, "", levelInfo)
+	initialize(conf, true)
+	checkLogFilter(t, "", levelInfo)
+
+	SetConfFile(conf.confFile)
+	checkLogFilter(t, "", levelDebug)
+
+	SetConfCheckInterval(time.Second)
+	conf.confCheckInterv = "60"
+	initialize(conf, true)
+	if settingCheckInterval != time.Second {
+		t.Fatalf("Incorrect check interval: %v", settingCheckInterval)
+	}
+}
+
+// TestDisableConfFileBeforeInit checks that the default config file is never
+// read if DisableConfFile is the first call into rlog.
+func TestDisableConfFileBeforeInit(t *testing.T) {
+	setup()
+	defer cleanup()
+	dir, err := ioutil.TempDir("", "rlog-conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	oldDir := defaultConfDir
+	defaultConfDir = dir
+	defer func() {
+		defaultConfDir = oldDir
+		confFileDisabled = false
+		Reset()
+	}()
+	confFile := defaultConfFile()
+	if err := ioutil.WriteFile(confFile, []byte("RLOG_LOG_LEVEL=DEBUG\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("RLOG_LOG_STREAM", "NONE")
+	defer os.Unsetenv("RLOG_LOG_STREAM")
+
+	// Pretend that rlog was never used before.
+	lastGoodConfLines = nil
+	atomic.StoreUint32(&initDone, 0)
+	initOnce = sync.Once{}
+
+	DisableConfFile()
+	if atomic.LoadUint32(&initDone) == 0 {
+		t.Fatal("Configuration was not loaded")
+	}
+	checkLogFilter(t, "", levelInfo)
+	if lastGoodConfLines != nil {
+		t.Fatalf("Default config file was read: %v", lastGoodConfLines)
+	}
+}
+
+// TestSetStreamAndFileOutput checks that log stream and logfile can be
+// redirected separately and that this survives updates of the configuration.
+func TestSetStreamAndFileOutput(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer func() {
+		streamOutput = nil
+		fileOutput = ""
+	}()
+	otherLogfile := logfile + ".other"
+	defer os.Remove(otherLogfile)
+	initialize(conf, true)
+
+	var buf bytes.Buffer
+	SetStreamOutput(&buf)
+	Info("Test Info 1")
+	initialize(conf, true)
+	Info("Test Info 2")
+
+	if err := SetFileOutput("/nonexistent/dir/rlog.log"); err == nil {
+		t.Fatal("Should not be able to set unwritable logfile")
+	}
+	if err := SetFileOutput(otherLogfile); err != nil {
+		t.Fatal(err)
+	}
+	Info("Test Info 3")
+	SetFileOutput("")
+	SetStreamOutput(nil)
+	Info("Test Info 4")
+
+	should := "INFO     : Test Info 1\nINFO     : Test Info 2\nINFO     : Test Info 3\n"
+	if buf.String() != should {
+		t.Fatalf("Incorrect stream output.\nSHOULD: %s\nIS:     %s\n", should, buf.String())
+	}
+	checkLines := []string{
+		"INFO     : Test Info 1",
+		"INFO     : Test Info 2",
+		"INFO     : Test Info 4",
+	}
+	fileMatch(t, checkLines, "")
+}
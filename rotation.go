@@ -0,0 +1,173 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The supported rotation periods for the logfile.
+const (
+	rotateNone = iota
+	rotateDaily
+	rotateWeekly
+)
+
+// getRotation returns the rotation period that was configured.
+func getRotation(config rlogConfig) int {
+	switch strings.ToUpper(config.logFileRotate) {
+	case "", "NO", "NONE", "0":
+		return rotateNone
+	case "DAILY", "DAY":
+		return rotateDaily
+	case "WEEKLY", "WEEK":
+		return rotateWeekly
+	default:
+		rlogIssue("Unknown logfile rotation '%s'. Not rotating.", config.logFileRotate)
+		return rotateNone
+	}
+}
+
+// rotatedName returns the name of the logfile for the period that contains
+// the given time. The date (or ISO week) is added to the name before the
+// extension, for example "app-20240613.log" or "app-2024-W24.log" for
+// "app.log".
+func rotatedName(fileName string, period int, t time.Time) string {
+	var stamp string
+	if period == rotateWeekly {
+		year, week := t.ISOWeek()
+		stamp = fmt.Sprintf("%04d-W%02d", year, week)
+	} else {
+		stamp = t.Format("20060102")
+	}
+	dir, base := filepath.Split(fileName)
+	ext := ""
+	if base == "" {
+		return fileName + stamp
+	}
+	if i := strings.Index(base[1:], "."); i >= 0 {
+		base, ext = base[:i+1], base[i+1:]
+	}
+	return dir + base + "-" + stamp + ext
+}
+
+// rotatingFile writes to a logfile that is replaced by a new one at the start
+// of every period. The configured name of the logfile is kept as symlink to
+// the current file, so that tools which tail the log always find it.
+type rotatingFile struct {
+	mutex    sync.Mutex
+	fileName string         // the configured name, used for the symlink
+	period   int            // rotateDaily or rotateWeekly
+	current  string         // name of the file currently written to
+	w        io.WriteCloser // the file currently written to, nil if closed
+}
+
+// openRotatingLogFile opens the logfile for the current period.
+func openRotatingLogFile(fileName string, period int) (io.WriteCloser, error) {
+	if _, base := filepath.Split(fileName); base == "" {
+		return nil, fmt.Errorf("cannot rotate '%s', since it is not a file name", fileName)
+	}
+	rf := &rotatingFile{fileName: fileName, period: period}
+	if err := rf.rotate(rotatedName(fileName, period, rotationTime())); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// rotationTime returns the current time in the time zone of the log output.
+func rotationTime() time.Time {
+	if settingTimeUTC {
+		return currentTime().UTC()
+	}
+	return currentTime()
+}
+
+// rotate closes the current file and opens the one with the given name. The
+// symlink is then updated to point to the new file. The caller needs to hold
+// the mutex, unless the rotatingFile is not in use yet.
+func (rf *rotatingFile) rotate(name string) error {
+	w, err := openLogFile(name)
+	if err != nil {
+		return err
+	}
+	if rf.w != nil {
+		rf.w.Close()
+	}
+	rf.w = w
+	rf.current = name
+
+	// The symlink is replaced atomically, but a regular file with the
+	// configured name is never touched.
+	if fi, err := os.Lstat(rf.fileName); err == nil && fi.Mode()&os.ModeSymlink == 0 {
+		rlogIssue("Not creating symlink to current logfile, since %s exists.", rf.fileName)
+		return nil
+	}
+	tmpName := rf.fileName + ".tmp"
+	os.Remove(tmpName)
+	if err := os.Symlink(filepath.Base(name), tmpName); err != nil {
+		rlogIssue("Unable to create symlink to current logfile: %s", err)
+		return nil
+	}
+	if err := os.Rename(tmpName, rf.fileName); err != nil {
+		os.Remove(tmpName)
+		rlogIssue("Unable to create symlink to current logfile: %s", err)
+	}
+	return nil
+}
+
+// Write writes to the file for the current period, starting a new file if the
+// period has changed.
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mutex.Lock()
+	defer rf.mutex.Unlock()
+	if rf.w == nil {
+		return 0, os.ErrClosed
+	}
+	if name := rotatedName(rf.fileName, rf.period, rotationTime()); name != rf.current {
+		if err := rf.rotate(name); err != nil {
+			rlogIssue("Unable to open log file: %s", err)
+		}
+	}
+	return rf.w.Write(p)
+}
+
+// Sync commits the current file to stable storage.
+func (rf *rotatingFile) Sync() error {
+	rf.mutex.Lock()
+	defer rf.mutex.Unlock()
+	if f, ok := rf.w.(interface{ Sync() error }); ok {
+		return f.Sync()
+	}
+	return nil
+}
+
+// Close closes the current file.
+func (rf *rotatingFile) Close() error {
+	rf.mutex.Lock()
+	defer rf.mutex.Unlock()
+	if rf.w == nil {
+		return os.ErrClosed
+	}
+	err := rf.w.Close()
+	rf.w = nil
+	return err
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRotatedName checks the names of the logfiles for each period.
+func TestRotatedName(t *testing.T) {
+	now := time.Date(2024, 6, 13, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		fileName string
+		period   int
+		expected string
+	}{
+		{"/var/log/app.log", rotateDaily, "/var/log/app-20240613.log"},
+		{"/var/log/app.log", rotateWeekly, "/var/log/app-2024-W24.log"},
+		{"app.log.gz", rotateDaily, "app-20240613.log.gz"},
+		{"/tmp/.app", rotateDaily, "/tmp/.app-20240613"},
+		{"app", rotateWeekly, "app-2024-W24"},
+		{"/var/log/", rotateDaily, "/var/log/20240613"},
+	}
+	for _, test := range tests {
+		if name := rotatedName(test.fileName, test.period, now); name != test.expected {
+			t.Errorf("Incorrect name for '%s': %s, expected %s", test.fileName, name, test.expected)
+		}
+	}
+}
+
+// TestLogFileRotate checks that a new logfile is started every day, and that
+// the configured name is a symlink to the current one.
+func TestLogFileRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rlog-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer SetClock(nil)
+	now := time.Date(2024, 6, 13, 23, 59, 0, 0, time.UTC)
+	SetClock(func() time.Time { return now })
+
+	conf := setup()
+	conf.logFile = filepath.Join(dir, "app.log")
+	conf.logFileRotate = "daily"
+	conf.timeUTC = "yes"
+	initialize(conf, true)
+	Info("Test Info 1")
+	now = now.Add(2 * time.Minute)
+	Info("Test Info 2")
+	initialize(setup(), true)
+	defer cleanup()
+
+	for name, expected := range map[string]string{
+		"app-20240613.log": "INFO     : Test Info 1\n",
+		"app-20240614.log": "INFO     : Test Info 2\n",
+		"app.log":          "INFO     : Test Info 2\n",
+	} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(content) != expected {
+			t.Errorf("Incorrect content of %s: '%s' (%v)", name, content, err)
+		}
+	}
+	if target, err := os.Readlink(conf.logFile); err != nil || target != "app-20240614.log" {
+		t.Errorf("Incorrect symlink: %s (%v)", target, err)
+	}
+}
+
+// TestLogFileRotateDirectory checks that a logfile name which ends in a path
+// separator is rejected, rather than taking down the process.
+func TestLogFileRotateDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rlog-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := setup()
+	defer cleanup()
+	conf.logFile = dir + "/"
+	conf.logFileRotate = "daily"
+	initialize(conf, true)
+	defer initialize(setup(), true)
+	if currentLogFileName == conf.logFile {
+		t.Fatal("Logfile should not have been opened")
+	}
+	Info("Test Info")
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The file name under which runtime stats messages are matched against the
+// log level filters. This allows per-file log levels for those messages.
+const runtimeStatsFileName = "rlog/runtimestats.go"
+
+var (
+	settingStatsInterval time.Duration // how often runtime stats are logged
+	settingStatsLevel    int           // level at which runtime stats are logged
+	statsStop            chan struct{} // closed to stop the stats goroutine
+)
+
+// updateRuntimeStats evaluates the runtime stats settings and starts, stops or
+// restarts the goroutine that periodically logs the runtime stats. The caller
+// needs to hold the write lock on initMutex.
+func updateRuntimeStats(config rlogConfig) {
+	var interval time.Duration
+	if config.statsInterv != "" {
+		secs, err := strconv.Atoi(config.statsInterv)
+		if err != nil || secs < 0 {
+			rlogIssue("Cannot parse runtime stats interval value '%s'. Ignored.",
+				config.statsInterv)
+		} else {
+			interval = time.Duration(secs) * time.Second
+		}
+	}
+
+	level := levelInfo
+	if config.statsLevel != "" {
+		l, ok := levelNumbers[strings.ToUpper(config.statsLevel)]
+		if !ok || l == levelTrace || l == levelNone {
+			rlogIssue("Illegal runtime stats log level '%s'. Using INFO.",
+				config.statsLevel)
+		} else {
+			level = l
+		}
+	}
+	settingStatsLevel = level
+
+	if interval == settingStatsInterval {
+		return
+	}
+	if statsStop != nil {
+		close(statsStop)
+		statsStop = nil
+	}
+	settingStatsInterval = interval
+	if interval > 0 {
+		statsStop = make(chan struct{})
+		go runtimeStatsLoop(interval, statsStop)
+	}
+}
+
+// runtimeStatsLoop logs the runtime stats in the specified interval, until the
+// stop channel is closed.
+func runtimeStatsLoop(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			logRuntimeStats(currentTime())
+		}
+	}
+}
+
+// logRuntimeStats writes a single log message with the current runtime stats,
+// if the log level filters allow it.
+func logRuntimeStats(now time.Time) {
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+
+	if !logFilterSpec.matchfilters(runtimeStatsFileName, settingStatsLevel) {
+		return
+	}
+	writeMessage(now, settingStatsLevel, runtimeStats())
+}
+
+// runtimeStats collects the runtime statistics and formats them as a message.
+func runtimeStats() string {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	var lastPause time.Duration
+	if ms.NumGC > 0 {
+		lastPause = time.Duration(ms.PauseNs[(ms.NumGC+255)%256])
+	}
+
+	// Open file descriptors can only be determined where /proc is available.
+	openFDs := "n/a"
+	if fds, err := ioutil.ReadDir("/proc/self/fd"); err == nil {
+		openFDs = strconv.Itoa(len(fds))
+	}
+
+	return fmt.Sprintf("Runtime stats: goroutines=%d heap_alloc=%d heap_sys=%d "+
+		"heap_objects=%d num_gc=%d last_gc_pause=%s total_gc_pause=%s open_fds=%s\n",
+		runtime.NumGoroutine(), ms.HeapAlloc, ms.HeapSys, ms.HeapObjects,
+		ms.NumGC, lastPause, time.Duration(ms.PauseTotalNs), openFDs)
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRuntimeStats checks that runtime stats are logged at the configured
+// level and are subject to the normal log level filters.
+func TestRuntimeStats(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.logLevel = "WARN"
+	conf.statsLevel = "info"
+	initialize(conf, true)
+	logRuntimeStats(time.Now())
+
+	conf.statsLevel = "error"
+	initialize(conf, true)
+	logRuntimeStats(time.Now())
+
+	content, err := ioutil.ReadFile(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "ERROR    : Runtime stats: goroutines=") {
+		t.Fatalf("Unexpected runtime stats output: %s", content)
+	}
+}
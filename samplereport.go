@@ -0,0 +1,138 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	settingSampleReport time.Duration // how often sampling reports are logged
+	sampleReportStop    chan struct{} // closed to stop the report goroutine
+)
+
+// callsite identifies the place in the code from which messages are logged.
+type callsite struct {
+	file string
+	line int
+}
+
+// sampledOutCounts holds a *uint64 counter for every callsite, which counts
+// the messages that were dropped by sampling or rate limiting since the last
+// report.
+var sampledOutCounts sync.Map
+
+// updateSampleReport evaluates RLOG_SAMPLE_REPORT, which is the interval of
+// the sampling reports, and starts, stops or restarts the goroutine that
+// logs them. The caller needs to hold the write lock on initMutex.
+func updateSampleReport(spec string) {
+	var interval time.Duration
+	if spec != "" {
+		var err error
+		interval, err = time.ParseDuration(strings.ToLower(spec))
+		if err != nil || interval < 0 {
+			rlogIssue("Cannot parse sample report interval '%s'. Ignored.", spec)
+			interval = 0
+		}
+	}
+	if interval == settingSampleReport {
+		return
+	}
+	if sampleReportStop != nil {
+		close(sampleReportStop)
+		sampleReportStop = nil
+	}
+	settingSampleReport = interval
+	if interval > 0 {
+		sampleReportStop = make(chan struct{})
+		go sampleReportLoop(interval, sampleReportStop)
+	}
+}
+
+// countSampledOut counts a message that was dropped by sampling, if sampling
+// reports are enabled. The caller needs to hold at least the read lock on
+// initMutex.
+func countSampledOut(caller *callerData) {
+	if settingSampleReport == 0 {
+		return
+	}
+	key := callsite{caller.moduleAndFileName, caller.line}
+	c, ok := sampledOutCounts.Load(key)
+	if !ok {
+		c, _ = sampledOutCounts.LoadOrStore(key, new(uint64))
+	}
+	atomic.AddUint64(c.(*uint64), 1)
+}
+
+// sampleReportLoop logs the sampling reports in the specified interval, until
+// the stop channel is closed.
+func sampleReportLoop(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			logSampleReport(currentTime(), interval)
+		}
+	}
+}
+
+// logSampleReport writes an entry for every callsite from which messages were
+// dropped by sampling since the last report, with the number of them and the
+// length of the time window, for example:
+//
+//     Messages dropped by sampling suppressed=1523 callsite=app/poller.go:88 window=10s
+//
+// The counters are reset.
+func logSampleReport(now time.Time, window time.Duration) {
+	type report struct {
+		callsite string
+		count    uint64
+	}
+	var reports []report
+	sampledOutCounts.Range(func(k, v interface{}) bool {
+		if n := atomic.SwapUint64(v.(*uint64), 0); n > 0 {
+			key := k.(callsite)
+			reports = append(reports, report{key.file + ":" + strconv.Itoa(key.line), n})
+		}
+		return true
+	})
+	sort.Slice(reports, func(i, j int) bool { return reports[i].callsite < reports[j].callsite })
+
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	for _, r := range reports {
+		writeRecord(&logRecord{
+			time:            now,
+			level:           levelInfo,
+			levelDecoration: levelStrings[levelInfo],
+			timeFormat:      settingDateTimeFormat,
+			msg:             "Messages dropped by sampling\n",
+			fields: []field{
+				{key: "suppressed", kind: fieldUint, num: int64(r.count)},
+				{key: "callsite", kind: fieldString, str: r.callsite},
+				{key: "window", kind: fieldDuration, num: int64(window)},
+			},
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestSampleReport checks that the messages dropped by rate limiting are
+// reported per callsite.
+func TestSampleReport(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer SetClock(nil)
+	conf.sample = "INFO:2/s"
+	conf.sampleReport = "1h"
+	initialize(conf, true)
+	defer updateSampleReport("")
+
+	tm := time.Date(2020, 2, 29, 12, 30, 0, 0, time.UTC)
+	SetClock(func() time.Time { return tm })
+	_, file, line, _ := runtime.Caller(0)
+	for i := 1; i <= 5; i++ {
+		Infof("Test Info %d", i)
+	}
+	logSampleReport(tm, time.Hour)
+	logSampleReport(tm, time.Hour)
+
+	checkLines := []string{
+		"INFO     : Test Info 1",
+		"INFO     : Test Info 2",
+		fmt.Sprintf("INFO     : Messages dropped by sampling suppressed=3 callsite=%s:%d window=1h0m0s",
+			newCallerData("", file, 0).moduleAndFileName, line+2),
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	settingSampleLevel int = levelNone // least severe level that is not sampled
+	settingSampleRate  int             // keep one in this many messages
+	settingSampleLimit int             // per second, logged before sampling
+	sampleFromConfig   bool            // whether settings came from RLOG_SAMPLE
+	sampleCounters     [levelTrace + 1]uint64
+	sampleWindow       rateWindow
+)
+
+// rateWindow counts the messages within the current one second window.
+type rateWindow struct {
+	mutex  sync.Mutex
+	second int64 // the second (Unix time) we are currently counting
+	count  int   // number of messages in that second so far
+}
+
+// next counts a message at the given time and returns the number of messages
+// in the current second, including this one.
+func (w *rateWindow) next(now time.Time) int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if s := now.Unix(); s != w.second {
+		w.second = s
+		w.count = 0
+	}
+	w.count++
+	return w.count
+}
+
+// SetSampling enables sampling for all messages at the given level or of
+// lower severity (trace messages included): Only one in every n of those
+// messages is logged. Messages logged through a Logger with a sample key are
+// either all logged or all dropped, depending on the key. A rate of 1 or less
+// switches sampling off. Note that sampling configured via RLOG_SAMPLE takes
+// precedence.
+func SetSampling(level Level, n int) {
+	initMutex.Lock()
+	defer initMutex.Unlock()
+	settingSampleLevel = int(level)
+	settingSampleRate = n
+	settingSampleLimit = 0
+}
+
+// parseSampleSpec parses a sampling specification of the form
+// "<level>[:<limit>/s][:1/<n>]", for example "INFO:100/s:1/10". This means:
+// For messages at INFO level or of lower severity, the first 100 messages in
+// every second are logged and after that only one in every 10 messages. If the
+// rate limit isn't specified then just one in every n messages is logged. If
+// the sampling part isn't specified then no messages are logged once the rate
+// limit is reached.
+func parseSampleSpec(spec string) (level int, limit int, rate int, err error) {
+	tokens := strings.Split(spec, ":")
+	level, ok := levelNumbers[strings.ToUpper(strings.TrimSpace(tokens[0]))]
+	if !ok || level == levelNone {
+		return 0, 0, 0, fmt.Errorf("illegal log level '%s'", tokens[0])
+	}
+	if len(tokens) < 2 || len(tokens) > 3 {
+		return 0, 0, 0, fmt.Errorf("malformed sampling specification '%s'", spec)
+	}
+	for _, t := range tokens[1:] {
+		t = strings.TrimSpace(t)
+		switch {
+		case strings.HasSuffix(t, "/s"):
+			limit, err = strconv.Atoi(strings.TrimSuffix(t, "/s"))
+			if err != nil || limit <= 0 {
+				return 0, 0, 0, fmt.Errorf("illegal rate limit '%s'", t)
+			}
+		case strings.HasPrefix(t, "1/"):
+			rate, err = strconv.Atoi(strings.TrimPrefix(t, "1/"))
+			if err != nil || rate <= 0 {
+				return 0, 0, 0, fmt.Errorf("illegal sampling rate '%s'", t)
+			}
+		default:
+			return 0, 0, 0, fmt.Errorf("malformed sampling specification '%s'", spec)
+		}
+	}
+	return level, limit, rate, nil
+}
+
+// updateSampling applies the sampling settings from the configuration. If
+// RLOG_SAMPLE isn't set then any sampling configured via SetSampling remains
+// in effect. The caller needs to hold the write lock on initMutex.
+func updateSampling(config rlogConfig) {
+	if config.sample == "" {
+		if sampleFromConfig {
+			// The setting was removed, so sampling is switched off again
+			settingSampleRate = 0
+			settingSampleLimit = 0
+			sampleFromConfig = false
+		}
+		return
+	}
+	level, limit, rate, err := parseSampleSpec(config.sample)
+	if err != nil {
+		rlogIssue("Cannot parse sampling setting: %s. Ignored.", err)
+		return
+	}
+	settingSampleLevel = level
+	settingSampleLimit = limit
+	settingSampleRate = rate
+	sampleFromConfig = true
+}
+
+// sampleIn decides whether a message at the given level, which already
+// passed the filters, should be logged. The rate limit applies to all
+// messages, while the sample key of a Logger, if any, replaces counting the
+// messages for the sampling. The caller needs to hold at least the read lock
+// on initMutex.
+func sampleIn(now time.Time, l *Logger, logLevel int) bool {
+	if (settingSampleRate <= 1 && settingSampleLimit == 0) || logLevel < settingSampleLevel {
+		return true
+	}
+	keyed := l != nil && l.sampleKey != ""
+	if settingSampleLimit > 0 {
+		n := sampleWindow.next(now)
+		if n <= settingSampleLimit {
+			return true
+		}
+		if keyed {
+			return settingSampleRate > 0 && sampleKeyIn(l.sampleKey, settingSampleRate)
+		}
+		return settingSampleRate > 0 && (n-settingSampleLimit)%settingSampleRate == 0
+	}
+	if keyed {
+		return sampleKeyIn(l.sampleKey, settingSampleRate)
+	}
+	count := atomic.AddUint64(&sampleCounters[logLevel], 1)
+	return count%uint64(settingSampleRate) == 1
+}
+
+// sampleKeyIn determines whether messages with the given sample key are
+// logged. Since this is based on a hash of the key, the decision is the same
+// for every message with that key, even across processes.
+func sampleKeyIn(key string, rate int) bool {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()%uint32(rate) == 0
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSampling checks that unkeyed messages are thinned out, while messages
+// with a sample key are either all logged or all dropped.
+func TestSampling(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	SetSampling(LevelInfo, 3)
+	defer SetSampling(LevelCritical, 0)
+	sampleCounters = [levelTrace + 1]uint64{}
+
+	// Find a key that is sampled in and one that is sampled out
+	var keyIn, keyOut string
+	for i := 0; keyIn == "" || keyOut == ""; i++ {
+		key := string(rune('a' + i))
+		if sampleKeyIn(key, 3) {
+			keyIn = key
+		} else {
+			keyOut = key
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		Infof("Unkeyed %d", i)
+	}
+	for i := 0; i < 2; i++ {
+		WithSampleKey(keyIn).Infof("Keyed in %d", i)
+		WithSampleKey(keyOut).Infof("Keyed out %d", i)
+	}
+	Warn("Not sampled")
+
+	checkLines := []string{
+		"INFO     : Unkeyed 0",
+		"INFO     : Unkeyed 3",
+		"INFO     : Keyed in 0",
+		"INFO     : Keyed in 1",
+		"WARN     : Not sampled",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestSampleSpec checks the parsing of sampling specifications.
+func TestSampleSpec(t *testing.T) {
+	checks := []struct {
+		spec               string
+		level, limit, rate int
+		shouldFail         bool
+	}{
+		{"INFO:100/s:1/10", levelInfo, 100, 10, false},
+		{"debug:1/5", levelDebug, 0, 5, false},
+		{"WARN:20/s", levelWarn, 20, 0, false},
+		{"INFO", 0, 0, 0, true},
+		{"FOO:1/10", 0, 0, 0, true},
+		{"INFO:x/s", 0, 0, 0, true},
+		{"INFO:10", 0, 0, 0, true},
+	}
+	for _, c := range checks {
+		level, limit, rate, err := parseSampleSpec(c.spec)
+		if c.shouldFail {
+			if err == nil {
+				t.Fatalf("Sampling spec '%s' should not be accepted", c.spec)
+			}
+			continue
+		}
+		if err != nil || level != c.level || limit != c.limit || rate != c.rate {
+			t.Fatalf("Incorrect parsing of '%s': %d %d %d %v", c.spec, level, limit, rate, err)
+		}
+	}
+}
+
+// TestRateLimit checks that only the configured number of messages per second
+// is logged, plus every n-th message after that.
+func TestRateLimit(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.sample = "INFO:2/s:1/3"
+	initialize(conf, true)
+
+	// Make sure that we are not close to the end of the current second
+	for time.Now().Nanosecond() > 500000000 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 6; i++ {
+		Infof("Info %d", i)
+	}
+	Warn("Not limited")
+
+	checkLines := []string{
+		"INFO     : Info 0",
+		"INFO     : Info 1",
+		"INFO     : Info 4",
+		"WARN     : Not limited",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestRateLimitSampleKey checks that the rate limit applies to messages that
+// are logged through a Logger with a sample key as well.
+func TestRateLimitSampleKey(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.sample = "INFO:2/s"
+	initialize(conf, true)
+	sampleWindow = rateWindow{}
+
+	// Make sure that we are not close to the end of the current second
+	for time.Now().Nanosecond() > 500000000 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	l := WithSampleKey("req-1")
+	for i := 0; i < 4; i++ {
+		l.Infof("Info %d", i)
+	}
+
+	checkLines := []string{
+		"INFO     : Info 0",
+		"INFO     : Info 1",
+	}
+	fileMatch(t, checkLines, "")
+}
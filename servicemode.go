@@ -0,0 +1,45 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// serviceMode decides, based on the value of RLOG_SERVICE_MODE, whether the
+// process runs without a console, as a Windows service does. Then nothing
+// is written to stderr or stdout. By default this is detected automatically.
+func serviceMode(mode string) bool {
+	switch strings.ToLower(mode) {
+	case "", "auto":
+		return runningAsService()
+	default:
+		return isTrueBoolString(mode)
+	}
+}
+
+// serviceLogFile returns the name of the logfile that is used in service
+// mode, if no logfile was configured: A file named after the executable in
+// the directory for temporary files.
+func serviceLogFile() string {
+	name := "rlog"
+	if exe, err := os.Executable(); err == nil {
+		name = strings.TrimSuffix(filepath.Base(exe), filepath.Ext(exe))
+	}
+	return filepath.Join(os.TempDir(), name+".log")
+}
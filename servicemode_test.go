@@ -0,0 +1,56 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+// TestServiceMode checks that in service mode nothing is written to the
+// stream and the logfile is used, with a default name if none is configured.
+func TestServiceMode(t *testing.T) {
+	if runtime.GOOS != "windows" && serviceMode("auto") {
+		t.Errorf("Service mode detected outside of Windows")
+	}
+	if serviceMode("no") || !serviceMode("yes") {
+		t.Errorf("Service mode not set explicitly")
+	}
+
+	conf := setup()
+	defer cleanup()
+	conf.logStream = "STDERR"
+	conf.serviceMode = "yes"
+	initialize(conf, true)
+	Info("Test Info 1")
+	if logWriterStream != nil {
+		t.Errorf("Stream used in service mode")
+	}
+	checkLines := []string{
+		"INFO     : Test Info 1",
+	}
+	fileMatch(t, checkLines, "")
+
+	conf.logFile = ""
+	initialize(conf, true)
+	defer os.Remove(serviceLogFile())
+	if currentLogFileName != serviceLogFile() {
+		t.Errorf("Wrong default logfile in service mode: %s", currentLogFileName)
+	}
+	conf.logFile = logfile
+	initialize(conf, true)
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"context"
+	"time"
+)
+
+// shutdownTimeout is how long ShutdownContext waits for the outputs to be
+// flushed and closed, once its context was cancelled.
+const shutdownTimeout = 5 * time.Second
+
+// Shutdown writes all log output that is still held in a buffer, commits the
+// logfile to storage and closes it, and closes the connections to syslog and
+// to a collector. Messages that are logged afterwards still go to the log
+// stream. Files and connections are opened again if the configuration is
+// changed or re-read. If the context expires before everything was closed
+// then Shutdown returns the error of the context and the remaining work
+// continues in the background.
+func Shutdown(ctx context.Context) error {
+	ensureInitialized()
+	done := make(chan struct{})
+	go func() {
+		closeOutputs()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ShutdownContext calls Shutdown once the context is cancelled, allowing up to
+// five seconds for it to complete. The returned channel is closed when this
+// is done, so a server can wire the logging into its shutdown sequence:
+//
+//     <-rlog.ShutdownContext(ctx)
+func ShutdownContext(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		Shutdown(shutdownCtx)
+		cancel()
+		close(done)
+	}()
+	return done
+}
+
+// closeOutputs flushes and closes all outputs, except for the log stream,
+// which is owned by the program.
+func closeOutputs() {
+	StopCollector()
+	initMutex.Lock()
+	defer initMutex.Unlock()
+	flushStream()
+	syncLogFile()
+	logWriterFile = nil
+	closeLogFile()
+	collectorClient.connect("")
+	syslogClient.connect("", "", "")
+}
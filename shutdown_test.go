@@ -0,0 +1,41 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"context"
+	"testing"
+)
+
+// TestShutdownContext checks that the logfile is closed once the context is
+// cancelled.
+func TestShutdownContext(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := ShutdownContext(ctx)
+	Info("Before shutdown")
+	cancel()
+	<-done
+	Info("After shutdown")
+
+	if currentLogFile != nil || logWriterFile != nil {
+		t.Fatal("Logfile still open after shutdown")
+	}
+	fileMatch(t, []string{"INFO     : Before shutdown"}, "")
+}
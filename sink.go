@@ -0,0 +1,237 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// How many entries may wait for a slow sink, before it is dropped.
+	sinkQueueSize = 1000
+	// How long removing a sink waits for the entries still queued for it.
+	sinkDrainTimeout = time.Second
+)
+
+// Sink is a destination for log entries in addition to the configured
+// outputs, such as a network connection or a message queue. It receives every
+// entry that is logged by the program, together with its formatted line. If
+// WriteEntry returns an error, then the sink is removed. Sinks are told apart
+// by their value, so they need to be comparable, such as pointers.
+type Sink interface {
+	WriteEntry(e Entry, line []byte) error
+}
+
+// WriterSink returns a Sink that writes the lines of the entries to w.
+func WriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+// writerSink is a Sink that writes the lines to a writer.
+type writerSink struct {
+	w io.Writer
+}
+
+// WriteEntry writes the line.
+func (s *writerSink) WriteEntry(_ Entry, line []byte) error {
+	_, err := s.w.Write(line)
+	return err
+}
+
+// SinkOption is a property of a sink that is added with AddSink.
+type SinkOption func(s *sink)
+
+// SinkLevel makes a sink only receive entries with the given level or a more
+// severe one. Trace messages have LevelTrace.
+func SinkLevel(level Level) SinkOption {
+	return func(s *sink) { s.level = level }
+}
+
+// SinkFormatter makes a sink receive lines that are formatted with the
+// Formatter, instead of in the general output format.
+func SinkFormatter(f Formatter) SinkOption {
+	return func(s *sink) { s.formatter = f }
+}
+
+// sinkEntry is an entry that waits to be written to a sink.
+type sinkEntry struct {
+	entry Entry
+	line  []byte
+}
+
+// sink is a Sink that was added with AddSink. The entries are queued and
+// written by a goroutine of their own, so that a slow sink doesn't hold up the
+// log calls.
+type sink struct {
+	s         Sink
+	level     Level
+	formatter Formatter
+	mutex     sync.Mutex     // protects queue and closed
+	queue     chan sinkEntry // the entries still to be written
+	closed    bool           // set once the queue was closed
+	done      chan struct{}  // closed once run has ended
+}
+
+// sinks holds the current list of sinks as []*sink. It is replaced, rather
+// than modified, so that it can be read without locking.
+var sinks atomic.Value
+
+// sinksMutex serializes changes of the list of sinks.
+var sinksMutex sync.Mutex
+
+// AddSink sends all following log entries to the sink as well, until it is
+// removed with RemoveSink. By default the sink receives every entry in the
+// general output format, which can be changed with the options SinkLevel and
+// SinkFormatter:
+//
+//     rlog.AddSink(rlog.WriterSink(conn), rlog.SinkLevel(rlog.LevelWarn))
+//
+// The entries are queued and written by a goroutine of their own. If the sink
+// can't keep up, so that too many entries are waiting for it or their size
+// exceeds the memory budget set with RLOG_MAX_BUFFER_MB, or if writing to it
+// fails, then nothing more is written to it. The configured outputs are
+// not affected by this. Adding a sink that was added already has no effect.
+func AddSink(s Sink, opts ...SinkOption) {
+	sk := &sink{s: s, level: LevelTrace, queue: make(chan sinkEntry, sinkQueueSize),
+		done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(sk)
+	}
+	sinksMutex.Lock()
+	defer sinksMutex.Unlock()
+	list, _ := sinks.Load().([]*sink)
+	for _, other := range list {
+		if other.s == s {
+			return
+		}
+	}
+	go sk.run()
+	sinks.Store(append(list[:len(list):len(list)], sk))
+}
+
+// RemoveSink stops sending log entries to a sink that was added with AddSink.
+// It waits a moment for the entries that are still waiting to be written.
+func RemoveSink(s Sink) {
+	list, _ := sinks.Load().([]*sink)
+	for _, sk := range list {
+		if sk.s == s {
+			dropSink(sk)
+			select {
+			case <-sk.done:
+			case <-time.After(sinkDrainTimeout):
+			}
+		}
+	}
+}
+
+// dropSink removes a sink from the list and closes its queue.
+func dropSink(sk *sink) {
+	sinksMutex.Lock()
+	list, _ := sinks.Load().([]*sink)
+	newList := make([]*sink, 0, len(list))
+	for _, other := range list {
+		if other != sk {
+			newList = append(newList, other)
+		}
+	}
+	sinks.Store(newList)
+	sinksMutex.Unlock()
+	sk.close()
+}
+
+// run writes the queued entries to the sink, until the queue is closed. After
+// a failed write the sink is removed and the remaining entries are discarded.
+func (sk *sink) run() {
+	defer close(sk.done)
+	failed := false
+	for e := range sk.queue {
+		if !failed {
+			if err := sk.s.WriteEntry(e.entry, e.line); err != nil {
+				failed = true
+				rlogIssue("Unable to write to sink: %s. Removed.", err)
+				dropSink(sk)
+			}
+		}
+		releaseQueued(len(e.line))
+	}
+}
+
+// close closes the queue, so that run ends once the queued entries are
+// written.
+func (sk *sink) close() {
+	sk.mutex.Lock()
+	defer sk.mutex.Unlock()
+	if !sk.closed {
+		sk.closed = true
+		close(sk.queue)
+	}
+}
+
+// discard empties the closed queue, so that the entries in it no longer count
+// against the memory budget, even if the sink is stuck.
+func (sk *sink) discard() {
+	for e := range sk.queue {
+		releaseQueued(len(e.line))
+	}
+}
+
+// writeSinks queues a log record for all sinks that want it. The line is
+// used for sinks without a Formatter of their own. A sink whose queue is full,
+// or for which the line would exceed the memory budget of the queues, is
+// dropped, since it can't keep up. The caller needs to hold at least the
+// read lock on initMutex.
+func writeSinks(r *logRecord, line string) {
+	list, _ := sinks.Load().([]*sink)
+	if len(list) == 0 {
+		return
+	}
+	var entry *Entry
+	for _, sk := range list {
+		if r.level > int(sk.level) {
+			continue
+		}
+		if entry == nil {
+			e := recordEntry(r)
+			entry = &e
+		}
+		se := sinkEntry{entry: *entry, line: []byte(line)}
+		if sk.formatter != nil {
+			se.line = []byte(formatEntry(sk.formatter, r))
+		}
+		sk.mutex.Lock()
+		full := false
+		if !sk.closed {
+			full = true
+			if reserveQueued(len(se.line)) {
+				select {
+				case sk.queue <- se:
+					full = false
+				default:
+					releaseQueued(len(se.line))
+				}
+			}
+		}
+		sk.mutex.Unlock()
+		if full {
+			dropSink(sk)
+			sk.discard()
+			rlogIssue("Sink can't keep up with the log entries. Dropped.")
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingSink keeps the entries and lines written to it.
+type recordingSink struct {
+	mutex   sync.Mutex
+	entries []Entry
+	lines   []string
+	err     error
+}
+
+func (s *recordingSink) WriteEntry(e Entry, line []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, e)
+	s.lines = append(s.lines, string(line))
+	return nil
+}
+
+func (s *recordingSink) messages() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var msgs []string
+	for _, e := range s.entries {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+// TestSink checks that a sink receives the entries of its level or a more
+// severe one, and all entries that are queued before it is removed.
+func TestSink(t *testing.T) {
+	var buf bytes.Buffer
+	SetTestMode(true)
+	SetStreamOutput(&buf)
+	defer Reset()
+
+	s := &recordingSink{}
+	AddSink(s, SinkLevel(LevelWarn))
+	AddSink(s)
+	Info("Test 1")
+	Warn("Test 2")
+	Error("Test 3")
+	RemoveSink(s)
+	Error("Test 4")
+
+	msgs := s.messages()
+	if strings.Join(msgs, ",") != "Test 2,Test 3" {
+		t.Errorf("Incorrect messages in sink: %v", msgs)
+	}
+	if len(s.lines) == 2 && !strings.Contains(s.lines[0], "WARN") {
+		t.Errorf("Expected the line in the general output format, got: %q", s.lines[0])
+	}
+	if !strings.Contains(buf.String(), "Test 4") {
+		t.Errorf("Expected the log stream not to be affected, got: %q", buf.String())
+	}
+}
+
+// TestSinkFormatter checks that a sink with a formatter of its own receives
+// the lines in that format.
+func TestSinkFormatter(t *testing.T) {
+	SetTestMode(true)
+	SetStreamOutput(&bytes.Buffer{})
+	defer Reset()
+
+	s := &recordingSink{}
+	AddSink(s, SinkFormatter(levelFormatter))
+	Info("Test 1")
+	RemoveSink(s)
+
+	if len(s.lines) != 1 || s.lines[0] != "INFO Test 1\n" {
+		t.Errorf("Incorrect lines in sink: %q", s.lines)
+	}
+}
+
+// TestSinkWriteError checks that a sink is removed once writing to it fails,
+// and that the problem is reported on the internal output.
+func TestSinkWriteError(t *testing.T) {
+	var internal bytes.Buffer
+	SetTestMode(true)
+	SetStreamOutput(&bytes.Buffer{})
+	SetInternalOutput(&internal)
+	defer SetInternalOutput(nil)
+	defer Reset()
+
+	s := &recordingSink{err: errors.New("connection closed")}
+	AddSink(s)
+	Info("Test 1")
+	RemoveSink(s)
+
+	list, _ := sinks.Load().([]*sink)
+	if len(list) != 0 {
+		t.Errorf("Expected the failed sink to be removed, got %d sinks", len(list))
+	}
+	if !strings.Contains(internal.String(), "Unable to write to sink: connection closed") {
+		t.Errorf("Expected a notice about the failed sink, got: %q", internal.String())
+	}
+}
+
+// TestWriterSink checks that WriterSink writes the lines to the writer.
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	s := WriterSink(&buf)
+	if err := s.WriteEntry(Entry{}, []byte("a line\n")); err != nil {
+		t.Fatalf("WriteEntry failed: %s", err)
+	}
+	if buf.String() != "a line\n" {
+		t.Errorf("Incorrect output: %q", buf.String())
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestSetOutputClock checks that an output with its own clock gets the time
+// stamps from it, while the other outputs keep using the default clock.
+func TestSetOutputClock(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer SetClock(nil)
+	var stream, tee bytes.Buffer
+	streamOutput = &stream
+	defer func() { streamOutput = nil }()
+	restore := TeeTo(&tee)
+	defer restore()
+	conf.logNoTime = "false"
+	conf.logTimeFormat = "RFC3339"
+	conf.timeUTC = "yes"
+	initialize(conf, true)
+
+	tm := time.Date(2020, 2, 29, 12, 30, 0, 0, time.UTC)
+	SetClock(func() time.Time { return tm })
+	if err := SetOutputClock("file", func() time.Time { return tm.Add(time.Hour) }); err != nil {
+		t.Fatal(err)
+	}
+	defer SetOutputClock("file", nil)
+	Info("Test Info")
+	restore()
+
+	if s := stream.String(); s != "2020-02-29T12:30:00Z INFO     : Test Info\n" {
+		t.Errorf("Incorrect stream output: %q", s)
+	}
+	if s := tee.String(); s != "2020-02-29T12:30:00Z INFO     : Test Info\n" {
+		t.Errorf("Incorrect tee output: %q", s)
+	}
+	fileMatch(t, []string{"2020-02-29T13:30:00Z INFO     : Test Info"}, "")
+
+	if err := SetOutputClock("audit", time.Now); err == nil {
+		t.Error("No error for unknown output")
+	}
+}
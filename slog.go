@@ -0,0 +1,198 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package rlog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SlogHandler is a slog.Handler, which writes records via rlog. This allows
+// code that uses log/slog to produce output that is configured and formatted
+// like all other rlog output. Attributes become fields. Records are filtered
+// with the log level filters for the file from which they were logged.
+type SlogHandler struct {
+	fields []field // from WithAttrs
+	prefix string  // from WithGroup, prepended to attribute keys
+}
+
+// NewSlogHandler returns a slog.Handler that writes records via rlog:
+//
+//     logger := slog.New(rlog.NewSlogHandler())
+func NewSlogHandler() *SlogHandler {
+	return &SlogHandler{}
+}
+
+// Enabled reports whether rlog logs messages at the given level in any file.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	return len(logFilterSpec.filters) > 0 && levelFromSlog(level) <= logFilterSpec.maxLevel
+}
+
+// Handle writes a record via rlog. The time stamp is taken from the rlog
+// clock, so that it is consistent with other rlog output.
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	var caller callerData
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		caller = newCallerData(frame.Function, frame.File, frame.Line)
+	}
+	fields := h.fields[:len(h.fields):len(h.fields)]
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.prefix, a)
+		return true
+	})
+
+	now := currentTime()
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	logEntry(now, &Logger{fields: fields}, levelFromSlog(r.Level), notATrace,
+		caller, "%s\n", "", r.Message)
+	return nil
+}
+
+// WithAttrs returns a handler that attaches the given attributes to every
+// record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := h.fields[:len(h.fields):len(h.fields)]
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, h.prefix, a)
+	}
+	return &SlogHandler{fields: fields, prefix: h.prefix}
+}
+
+// WithGroup returns a handler that qualifies the keys of all following
+// attributes with the group name, as in "group.key".
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{fields: h.fields, prefix: h.prefix + name + "."}
+}
+
+// appendSlogAttr converts an attribute into fields. The members of groups
+// become fields of their own.
+func appendSlogAttr(fields []field, prefix string, a slog.Attr) []field {
+	v := a.Value.Resolve()
+	key := prefix + a.Key
+	switch v.Kind() {
+	case slog.KindGroup:
+		if a.Key != "" {
+			prefix = key + "."
+		}
+		for _, ga := range v.Group() {
+			fields = appendSlogAttr(fields, prefix, ga)
+		}
+		return fields
+	case slog.KindString:
+		return append(fields, field{key: key, kind: fieldString, str: v.String()})
+	case slog.KindInt64:
+		return append(fields, intField(key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, field{key: key, kind: fieldUint, num: int64(v.Uint64())})
+	case slog.KindFloat64:
+		return append(fields, field{key: key, kind: fieldFloat, fl: v.Float64()})
+	case slog.KindBool:
+		f := field{key: key, kind: fieldBool}
+		if v.Bool() {
+			f.num = 1
+		}
+		return append(fields, f)
+	case slog.KindDuration:
+		return append(fields, field{key: key, kind: fieldDuration, num: int64(v.Duration())})
+	case slog.KindTime:
+		return append(fields, field{key: key, kind: fieldString,
+			str: v.Time().Format(time.RFC3339Nano)})
+	default:
+		return append(fields, anyField(key, v.Any()))
+	}
+}
+
+// levelFromSlog returns the rlog level for a slog level. Levels above ERROR
+// are CRITICAL and levels below DEBUG are DEBUG as well.
+func levelFromSlog(level slog.Level) int {
+	switch {
+	case level > slog.LevelError:
+		return levelCrit
+	case level > slog.LevelWarn:
+		return levelErr
+	case level > slog.LevelInfo:
+		return levelWarn
+	case level > slog.LevelDebug:
+		return levelInfo
+	default:
+		return levelDebug
+	}
+}
+
+// slogLevel returns the slog level for an rlog level. CRITICAL is above
+// slog's ERROR and trace messages are below slog's DEBUG.
+func slogLevel(level int) slog.Level {
+	switch level {
+	case levelCrit:
+		return slog.LevelError + 4
+	case levelErr:
+		return slog.LevelError
+	case levelWarn:
+		return slog.LevelWarn
+	case levelInfo:
+		return slog.LevelInfo
+	case levelDebug:
+		return slog.LevelDebug
+	default:
+		return slog.LevelDebug - 4
+	}
+}
+
+// SetSlogBackend makes rlog pass all log entries to the given slog.Handler,
+// instead of writing them to its own outputs. The rlog functions can then be
+// used in programs that have an existing slog pipeline, while rlog still
+// decides which messages are logged. Fields become attributes and trace
+// messages have a "trace" attribute with their trace level. Setting nil goes
+// back to rlog's own outputs. Never pass a SlogHandler, which would write the
+// entries back to rlog.
+func SetSlogBackend(handler slog.Handler) {
+	ensureInitialized()
+	initMutex.Lock()
+	defer initMutex.Unlock()
+	if handler == nil {
+		settingBackend = nil
+		return
+	}
+	settingBackend = func(r *logRecord, traceLevel int) {
+		sr := slog.NewRecord(r.time, slogLevel(r.level),
+			strings.TrimSuffix(r.msg, "\n"), 0)
+		if traceLevel != notATrace {
+			sr.AddAttrs(slog.Int("trace", traceLevel))
+		}
+		for i := range r.fields {
+			sr.AddAttrs(slog.Any(r.fields[i].key, fieldValue(&r.fields[i])))
+		}
+		ctx := context.Background()
+		if handler.Enabled(ctx, sr.Level) {
+			handler.Handle(ctx, sr)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package rlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+)
+
+// TestSlogHandler checks that slog records are written via rlog.
+func TestSlogHandler(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	logger := slog.New(NewSlogHandler()).With("svc", "shop")
+	logger.Info("Test Info", "n", 3, slog.Group("req", "id", "ab12", "ok", true))
+	logger.Debug("Test Debug")
+	logger.WithGroup("db").Error("Test Error", "took", 1500000)
+
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("DEBUG should not be enabled")
+	}
+
+	checkLines := []string{
+		"INFO     : Test Info svc=shop n=3 req.id=ab12 req.ok=true",
+		"ERROR    : Test Error svc=shop db.took=1500000",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestSlogBackend checks that rlog entries can be passed to a slog.Handler.
+func TestSlogBackend(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.traceLevel = "2"
+	initialize(conf, true)
+
+	var buf bytes.Buffer
+	SetSlogBackend(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug - 4,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	defer SetSlogBackend(nil)
+
+	Info("Test Info")
+	Ev().Level(LevelCritical).Int("n", 1).Msg("Test Critical")
+	Trace(2, "Trace 2")
+	Debug("Test Debug")
+
+	should := "level=INFO msg=\"Test Info\"\n" +
+		"level=ERROR+4 msg=\"Test Critical\" n=1\n" +
+		"level=DEBUG-4 msg=\"Trace 2\" trace=2\n"
+	if buf.String() != should {
+		t.Fatalf("Incorrect slog output.\nSHOULD: %s\nIS:     %s\n", should, buf.String())
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+)
+
+// HandleStackDumpSignal installs a handler for the specified signals (for
+// example syscall.SIGQUIT or syscall.SIGUSR1). Whenever one of those signals
+// is received, the stacks of all goroutines are written to the log. In
+// contrast to the default behaviour of the Go runtime for SIGQUIT, the process
+// is not terminated and the stacks are written through rlog, so that they end
+// up time stamped in the same outputs as all other log messages. The dump is
+// always written, regardless of the configured log level.
+func HandleStackDumpSignal(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		return
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+	go func() {
+		for sig := range c {
+			logStackDump(fmt.Sprintf("signal '%s'", sig))
+		}
+	}()
+}
+
+// allStacks returns the stack traces of all goroutines. The buffer is grown
+// until it is large enough to hold the complete dump.
+func allStacks() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// logStackDump writes the stacks of all goroutines to the log. Each goroutine
+// is written as a separate log entry, so that the individual stacks can be
+// found with the usual tools.
+func logStackDump(reason string) {
+	now := currentTime()
+	stacks := strings.Split(strings.TrimSpace(string(allStacks())), "\n\n")
+
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+
+	writeMessage(now, levelInfo,
+		fmt.Sprintf("Stack dump of %d goroutines, triggered by %s\n",
+			len(stacks), reason))
+	for _, s := range stacks {
+		writeMessage(now, levelInfo, s+"\n")
+	}
+}
+
+// maxLazyStackDepth is the maximum number of frames recorded by a lazyStack.
+const maxLazyStackDepth = 64
+
+// lazyStack records the program counters of a goroutine's stack, which are
+// only resolved to function names, files and lines once the stack is
+// formatted. This makes it cheap to capture if the stack isn't needed in the
+// end.
+type lazyStack struct {
+	pcs []uintptr
+}
+
+// newLazyStack captures the stack of the current goroutine. The skip parameter
+// has the same meaning as for runtime.Callers().
+func newLazyStack(skip int) *lazyStack {
+	pcs := make([]uintptr, maxLazyStackDepth)
+	n := runtime.Callers(skip+1, pcs)
+	return &lazyStack{pcs: pcs[:n]}
+}
+
+// String formats the stack in the same way as a goroutine dump.
+func (ls *lazyStack) String() string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(ls.pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s()\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// TestStackDump checks that a stack dump is written to the log even if the
+// log level would normally suppress INFO messages.
+func TestStackDump(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.logLevel = "ERROR"
+	initialize(conf, true)
+
+	logStackDump("test")
+
+	content, err := ioutil.ReadFile(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(content)
+	if !strings.HasPrefix(out, "INFO     : Stack dump of ") {
+		t.Fatalf("Missing stack dump header in output: %s", out)
+	}
+	if !strings.Contains(out, "TestStackDump") {
+		t.Fatalf("Stack of current goroutine not found in output: %s", out)
+	}
+}
+
+// TestTraceStack checks that TraceStack adds the stack of the calling
+// goroutine, starting with the calling function.
+func TestTraceStack(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.traceLevel = "1"
+	initialize(conf, true)
+
+	TraceStack(1, "Trace with stack")
+	TraceStack(2, "Not shown")
+
+	content, err := ioutil.ReadFile(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	should := "TRACE(1) : Trace with stack\ngithub.com/romana/rlog.TestTraceStack()\n\t"
+	if !strings.HasPrefix(string(content), should) {
+		t.Fatalf("Incorrect trace with stack.\nSHOULD: %s\nIS:     %s\n", should, content)
+	}
+	if strings.Contains(string(content), "Not shown") {
+		t.Fatal("Trace message with higher level was logged")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamBufferSize is the size of the buffer for the log stream, if buffering
+// was enabled with RLOG_STREAM_BUFFER, unless RLOG_MAX_BUFFER_MB limits it.
+const streamBufferSize = 64 * 1024
+
+// Flush policies for the buffered log stream.
+const (
+	flushLine     = iota // flush after every complete line
+	flushSize            // flush only when the buffer is full
+	flushInterval        // flush when full, or at the latest after an interval
+)
+
+// currentStreamBuffer is the buffered writer for the log stream, or nil if the
+// log stream is not buffered.
+var currentStreamBuffer *streamBuffer
+
+// streamBuffer buffers the output to the log stream. Writing each log line
+// individually to a pipe can be expensive for programs that produce a lot of
+// output, since every line costs a system call. This combines many lines into
+// a single write.
+type streamBuffer struct {
+	mutex      sync.Mutex
+	buf        *bufio.Writer
+	policy     int
+	interval   time.Duration
+	flushTimer *time.Timer // pending flush, nil if nothing is pending
+}
+
+// parseStreamBuffer interprets the value of RLOG_STREAM_BUFFER. It returns
+// false if the output should not be buffered.
+func parseStreamBuffer(spec string) (policy int, interval time.Duration, ok bool) {
+	switch strings.ToUpper(spec) {
+	case "", "NONE", "NO", "0":
+		return 0, 0, false
+	case "LINE":
+		return flushLine, 0, true
+	case "SIZE":
+		return flushSize, 0, true
+	}
+	interval, err := time.ParseDuration(strings.ToLower(spec))
+	if err != nil || interval <= 0 {
+		rlogIssue("Cannot parse stream buffer value '%s'. Output is not buffered.",
+			spec)
+		return 0, 0, false
+	}
+	return flushInterval, interval, true
+}
+
+// newStreamWriter returns the writer to use for the log stream, which is
+// either the stream itself or a buffer in front of it. It also makes this the
+// current stream buffer. The caller needs to hold the write lock on initMutex.
+func newStreamWriter(w io.Writer, spec string) io.Writer {
+	flushStream()
+	currentStreamBuffer = nil
+	rawStream = w
+	policy, interval, ok := parseStreamBuffer(spec)
+	if !ok {
+		return w
+	}
+	currentStreamBuffer = &streamBuffer{
+		buf:      bufio.NewWriterSize(w, streamBufferBytes()),
+		policy:   policy,
+		interval: interval,
+	}
+	return currentStreamBuffer
+}
+
+// Flush writes all log output that is still held in a buffer and commits the
+// logfile to storage. Programs that enable buffering of the log stream should
+// call this before they exit.
+func Flush() {
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	flushStream()
+	syncLogFile()
+}
+
+// flushStream writes all output that is still held in the buffer of the log
+// stream. The caller needs to hold at least the read lock on initMutex.
+func flushStream() {
+	if currentStreamBuffer != nil {
+		currentStreamBuffer.flush()
+	}
+}
+
+// Write adds the data to the buffer and flushes it as the policy demands.
+func (b *streamBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	n, err := b.buf.Write(p)
+	if err != nil {
+		return n, err
+	}
+	switch b.policy {
+	case flushLine:
+		if len(p) > 0 && p[len(p)-1] == '\n' {
+			err = b.buf.Flush()
+		}
+	case flushInterval:
+		if b.flushTimer == nil && b.buf.Buffered() > 0 {
+			b.flushTimer = time.AfterFunc(b.interval, b.flush)
+		}
+	}
+	return n, err
+}
+
+// flush writes all buffered output to the stream.
+func (b *streamBuffer) flush() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.flushTimer != nil {
+		b.flushTimer.Stop()
+		b.flushTimer = nil
+	}
+	b.buf.Flush()
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer that can be used from several goroutines.
+type lockedBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+// TestStreamBuffer checks the flush policies of the buffered log stream.
+func TestStreamBuffer(t *testing.T) {
+	defer func() { currentStreamBuffer = nil }()
+
+	for _, spec := range []string{"", "none", "foo", "-1s"} {
+		out := &lockedBuffer{}
+		if w := newStreamWriter(out, spec); w != out {
+			t.Fatalf("Stream should not be buffered for '%s'", spec)
+		}
+	}
+
+	out := &lockedBuffer{}
+	w := newStreamWriter(out, "line")
+	w.Write([]byte("partial "))
+	if out.String() != "" {
+		t.Fatalf("Partial line should be buffered, but got: %s", out)
+	}
+	w.Write([]byte("line\n"))
+	if out.String() != "partial line\n" {
+		t.Fatalf("Line should have been written, but got: %s", out)
+	}
+
+	out = &lockedBuffer{}
+	w = newStreamWriter(out, "SIZE")
+	w.Write([]byte("line 1\n"))
+	w.Write([]byte("line 2\n"))
+	if out.String() != "" {
+		t.Fatalf("Output should be buffered, but got: %s", out)
+	}
+	flushStream()
+	if out.String() != "line 1\nline 2\n" {
+		t.Fatalf("Output should have been flushed, but got: %s", out)
+	}
+
+	out = &lockedBuffer{}
+	w = newStreamWriter(out, "10ms")
+	w.Write([]byte("line 1\n"))
+	if out.String() != "" {
+		t.Fatalf("Output should be buffered, but got: %s", out)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if out.String() != "line 1\n" {
+		t.Fatalf("Output should have been flushed, but got: %s", out)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"os"
+)
+
+// lastStreamNotice is the last notice about a fallback of the log stream that
+// was logged, so that it is logged only once. Protected by initMutex.
+var lastStreamNotice string
+
+// streamUsable returns false if the stream is closed or redirected to the null
+// device, so that anything written to it is lost.
+func streamUsable(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(fi, null) {
+		return false
+	}
+	return true
+}
+
+// fallbackStream checks whether the chosen log stream is usable. If it is not
+// then the other one of stdout and stderr is used instead, or, if that isn't
+// usable either, no stream at all. The returned stream is nil in that case.
+// The name of the stream that is used and a notice about the fallback, if
+// there was one, are returned as well.
+func fallbackStream(name string, stream *os.File) (string, *os.File, string) {
+	if streamUsable(stream) {
+		return name, stream, ""
+	}
+	otherName, other := "stdout", os.Stdout
+	if name == "stdout" {
+		otherName, other = "stderr", os.Stderr
+	}
+	if streamUsable(other) {
+		return otherName, other, fmt.Sprintf("rlog: %s is closed or discarded, logging to %s instead\n",
+			name, otherName)
+	}
+	return "", nil, fmt.Sprintf("rlog: %s and %s are closed or discarded, logging to the logfile only\n",
+		name, otherName)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestStreamFallback checks that output goes to stdout if stderr is discarded,
+// and that this is noted once.
+func TestStreamFallback(t *testing.T) {
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("No null device:", err)
+	}
+	defer null.Close()
+	out, err := ioutil.TempFile("", "rlog-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = out, null
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		initialize(setup(), true)
+		cleanup()
+	}()
+
+	conf := setup()
+	defer cleanup()
+	conf.logStream = ""
+	conf.streamFallback = "yes"
+	initialize(conf, true)
+	initialize(conf, false)
+	Info("Test Info")
+
+	os.Stdout = null
+	initialize(conf, false)
+	Info("Test Info")
+
+	content, _ := ioutil.ReadFile(out.Name())
+	expected := "WARN     : rlog: stderr is closed or discarded, logging to stdout instead\n" +
+		"INFO     : Test Info\n"
+	if string(content) != expected {
+		t.Errorf("Incorrect stream output: '%s'", content)
+	}
+	checkLines := []string{
+		"WARN     : rlog: stderr is closed or discarded, logging to stdout instead",
+		"INFO     : Test Info",
+		"WARN     : rlog: stderr and stdout are closed or discarded, logging to the logfile only",
+		"INFO     : Test Info",
+	}
+	fileMatch(t, checkLines, "")
+	if logWriterStream != nil {
+		t.Error("Log stream should be disabled")
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// suppressedKey identifies the messages of one level from one file.
+type suppressedKey struct {
+	file       string
+	level      int
+	traceLevel int
+}
+
+// suppressedCounts holds a *uint64 counter for every suppressedKey.
+var suppressedCounts sync.Map
+
+// loggedCounts holds a *uint64 counter for every suppressedKey, which counts
+// the messages that passed the filters.
+var loggedCounts sync.Map
+
+// countSuppressed counts a message that was not logged because of the log or
+// trace level filters.
+func countSuppressed(file string, level int, traceLevel int) {
+	countMessage(&suppressedCounts, file, level, traceLevel)
+}
+
+// countMessage increases the counter for the file and level in the map.
+func countMessage(counts *sync.Map, file string, level int, traceLevel int) {
+	key := suppressedKey{file, level, traceLevel}
+	c, ok := counts.Load(key)
+	if !ok {
+		c, _ = counts.LoadOrStore(key, new(uint64))
+	}
+	atomic.AddUint64(c.(*uint64), 1)
+}
+
+// SuppressedCounts returns how many messages have been suppressed by the log
+// and trace level filters so far, by file and level, for example:
+//
+//     {"rlog/example.go": {"DEBUG": 1200000, "TRACE(3)": 512}}
+//
+// This shows how much a filter actually suppresses, which helps to tune the
+// filters knowingly. Trace messages are only counted if trace output is
+// enabled for at least one file. The counts can be published as metrics, for
+// example with expvar:
+//
+//     expvar.Publish("rlog_suppressed", expvar.Func(func() interface{} {
+//         return rlog.SuppressedCounts()
+//     }))
+func SuppressedCounts() map[string]map[string]uint64 {
+	counts := make(map[string]map[string]uint64)
+	suppressedCounts.Range(func(k, v interface{}) bool {
+		key := k.(suppressedKey)
+		level := levelStrings[key.level]
+		if key.traceLevel != notATrace {
+			level = fmt.Sprintf("%s(%d)", level, key.traceLevel)
+		}
+		if counts[key.file] == nil {
+			counts[key.file] = make(map[string]uint64)
+		}
+		counts[key.file][level] = atomic.LoadUint64(v.(*uint64))
+		return true
+	})
+	return counts
+}
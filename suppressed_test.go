@@ -0,0 +1,47 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSuppressedCounts checks that suppressed messages are counted by file
+// and level.
+func TestSuppressedCounts(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.traceLevel = "suppressed_*.go=1"
+	initialize(conf, true)
+
+	for i := 0; i < 3; i++ {
+		Debug("Test Debug")
+		Trace(2, "Trace 2")
+	}
+	Trace(1, "Trace 1")
+	Info("Test Info")
+
+	var counts map[string]uint64
+	for file, c := range SuppressedCounts() {
+		if strings.HasSuffix(file, "/suppressed_test.go") {
+			counts = c
+		}
+	}
+	if len(counts) != 2 || counts["DEBUG"] != 3 || counts["TRACE(2)"] != 3 {
+		t.Fatalf("Incorrect suppressed counts: %v", counts)
+	}
+}
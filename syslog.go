@@ -0,0 +1,254 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package rlog
+
+import (
+	"log/syslog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Syslog facilities by name, as used in RLOG_SYSLOG_FACILITY.
+var syslogFacilities = map[string]syslog.Priority{
+	"KERN":     syslog.LOG_KERN,
+	"USER":     syslog.LOG_USER,
+	"MAIL":     syslog.LOG_MAIL,
+	"DAEMON":   syslog.LOG_DAEMON,
+	"AUTH":     syslog.LOG_AUTH,
+	"SYSLOG":   syslog.LOG_SYSLOG,
+	"LPR":      syslog.LOG_LPR,
+	"NEWS":     syslog.LOG_NEWS,
+	"UUCP":     syslog.LOG_UUCP,
+	"CRON":     syslog.LOG_CRON,
+	"AUTHPRIV": syslog.LOG_AUTHPRIV,
+	"FTP":      syslog.LOG_FTP,
+	"LOCAL0":   syslog.LOG_LOCAL0,
+	"LOCAL1":   syslog.LOG_LOCAL1,
+	"LOCAL2":   syslog.LOG_LOCAL2,
+	"LOCAL3":   syslog.LOG_LOCAL3,
+	"LOCAL4":   syslog.LOG_LOCAL4,
+	"LOCAL5":   syslog.LOG_LOCAL5,
+	"LOCAL6":   syslog.LOG_LOCAL6,
+	"LOCAL7":   syslog.LOG_LOCAL7,
+}
+
+// Syslog severities by name, as used in RLOG_SYSLOG_LEVELS.
+var syslogSeverities = map[string]syslog.Priority{
+	"EMERG":   syslog.LOG_EMERG,
+	"ALERT":   syslog.LOG_ALERT,
+	"CRIT":    syslog.LOG_CRIT,
+	"ERR":     syslog.LOG_ERR,
+	"WARNING": syslog.LOG_WARNING,
+	"NOTICE":  syslog.LOG_NOTICE,
+	"INFO":    syslog.LOG_INFO,
+	"DEBUG":   syslog.LOG_DEBUG,
+}
+
+// defaultSyslogSeverities maps our log levels to syslog severities, unless
+// something else is configured.
+var defaultSyslogSeverities = map[int]syslog.Priority{
+	levelCrit:  syslog.LOG_CRIT,
+	levelErr:   syslog.LOG_ERR,
+	levelWarn:  syslog.LOG_WARNING,
+	levelInfo:  syslog.LOG_INFO,
+	levelDebug: syslog.LOG_DEBUG,
+	levelTrace: syslog.LOG_DEBUG,
+}
+
+// syslogSender writes log entries to syslog.
+type syslogSender struct {
+	mutex      sync.Mutex
+	address    string                  // where we are (supposed to be) connected
+	facility   syslog.Priority         // facility we are connected with
+	severities map[int]syslog.Priority // severity for each log level
+	writer     *syslog.Writer          // nil if not connected
+	dialing    bool                    // a connection attempt is in progress
+	generation int                     // changes with address and facility
+}
+
+// syslogClient is used to write log entries to syslog, if RLOG_SYSLOG is set.
+var syslogClient = &syslogSender{}
+
+// syslogDial connects to syslog. Tests replace this.
+var syslogDial = syslog.Dial
+
+// parseSyslogLevels returns the severity for each log level, with the
+// defaults overridden by a spec like "TRACE=DEBUG,CRITICAL=ALERT".
+func parseSyslogLevels(spec string) map[int]syslog.Priority {
+	severities := make(map[int]syslog.Priority, len(defaultSyslogSeverities))
+	for level, severity := range defaultSyslogSeverities {
+		severities[level] = severity
+	}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		tokens := strings.SplitN(item, "=", 2)
+		if len(tokens) != 2 {
+			rlogIssue("Malformed syslog level mapping '%s'. Ignored.", item)
+			continue
+		}
+		level, levelOk := levelNumbers[strings.ToUpper(strings.TrimSpace(tokens[0]))]
+		severity, severityOk := syslogSeverities[strings.ToUpper(strings.TrimSpace(tokens[1]))]
+		if !levelOk || level == levelNone || !severityOk {
+			rlogIssue("Unknown level or severity in syslog level mapping '%s'. Ignored.",
+				item)
+			continue
+		}
+		severities[level] = severity
+	}
+	return severities
+}
+
+// connect establishes the connection to syslog. The address is "local" (or
+// anything that evaluates to 'true') for the local syslog daemon, or a network
+// address in the form "udp:host:port" or "tcp:host:port". Nothing is done if
+// we are already connected with the same address and facility. An empty
+// address closes any existing connection.
+// This is called during every initialization, while initMutex is held, so
+// the connection is established in the background: an unreachable syslog
+// server must not stall the logging. Until it is connected, entries are not
+// sent to syslog.
+func (ss *syslogSender) connect(address string, facilityName string, levels string) {
+	facility := syslog.LOG_USER
+	if facilityName != "" {
+		f, ok := syslogFacilities[strings.ToUpper(facilityName)]
+		if ok {
+			facility = f
+		} else {
+			rlogIssue("Unknown syslog facility '%s'. Using USER.", facilityName)
+		}
+	}
+	if strings.ToUpper(address) == "LOCAL" || isTrueBoolString(address) {
+		address = "local"
+	} else if strings.ToUpper(address) == "NO" || address == "0" {
+		address = ""
+	}
+
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	ss.severities = parseSyslogLevels(levels)
+	if address == ss.address && facility == ss.facility {
+		// Only a connection that was lost, or never came about, is
+		// established again.
+		if ss.writer != nil || ss.dialing {
+			return
+		}
+	} else {
+		if ss.writer != nil {
+			ss.writer.Close()
+			ss.writer = nil
+		}
+		ss.address = address
+		ss.facility = facility
+		ss.generation++
+		ss.dialing = false
+	}
+	if address == "" {
+		return
+	}
+	network, raddr := "", ""
+	if address != "local" {
+		tokens := strings.SplitN(address, ":", 2)
+		if len(tokens) != 2 {
+			rlogIssue("Malformed syslog address '%s'. Ignored.", address)
+			return
+		}
+		network, raddr = tokens[0], tokens[1]
+	}
+	ss.dialing = true
+	go ss.dial(ss.generation, network, raddr, facility)
+}
+
+// dial connects to syslog and then starts using the connection, unless the
+// address or facility were changed in the meantime.
+func (ss *syslogSender) dial(generation int, network string, raddr string, facility syslog.Priority) {
+	tag := filepath.Base(os.Args[0])
+	w, err := syslogDial(network, raddr, facility|syslog.LOG_INFO, tag)
+
+	ss.mutex.Lock()
+	if generation != ss.generation {
+		ss.mutex.Unlock()
+		if w != nil {
+			w.Close()
+		}
+		return
+	}
+	ss.dialing = false
+	ss.writer = w
+	ss.mutex.Unlock()
+	if err != nil {
+		rlogIssue("Unable to connect to syslog: %s", err)
+	}
+}
+
+// send writes a log entry to syslog with the severity for its level, if we
+// are connected.
+func (ss *syslogSender) send(level int, logLine string) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	if ss.writer == nil {
+		return
+	}
+	var err error
+	switch ss.severities[level] {
+	case syslog.LOG_EMERG:
+		err = ss.writer.Emerg(logLine)
+	case syslog.LOG_ALERT:
+		err = ss.writer.Alert(logLine)
+	case syslog.LOG_CRIT:
+		err = ss.writer.Crit(logLine)
+	case syslog.LOG_ERR:
+		err = ss.writer.Err(logLine)
+	case syslog.LOG_WARNING:
+		err = ss.writer.Warning(logLine)
+	case syslog.LOG_NOTICE:
+		err = ss.writer.Notice(logLine)
+	case syslog.LOG_INFO:
+		err = ss.writer.Info(logLine)
+	default:
+		err = ss.writer.Debug(logLine)
+	}
+	if err != nil {
+		// The connection is dropped, so that it is established again with
+		// the next check of the configuration.
+		ss.writer.Close()
+		ss.writer = nil
+		rlogIssue("Unable to write to syslog: %s", err)
+	}
+}
+
+// status describes the connection to syslog, for the debug page.
+func (ss *syslogSender) status() string {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	switch {
+	case ss.address == "":
+		return "off"
+	case ss.dialing:
+		return ss.address + " (connecting)"
+	case ss.writer == nil:
+		return ss.address + " (not connected)"
+	default:
+		return ss.address
+	}
+}
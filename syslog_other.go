@@ -0,0 +1,45 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build windows || plan9
+// +build windows plan9
+
+package rlog
+
+import (
+	"strings"
+)
+
+// syslogSender is a placeholder on platforms without syslog.
+type syslogSender struct{}
+
+// syslogClient would write log entries to syslog, which is not available on
+// this platform.
+var syslogClient = &syslogSender{}
+
+// connect complains if syslog output was requested.
+func (ss *syslogSender) connect(address string, facilityName string, levels string) {
+	if address != "" && address != "0" && !strings.EqualFold(address, "NO") {
+		rlogIssue("Syslog is not supported on this platform.")
+	}
+}
+
+// send does nothing, since there is no syslog.
+func (ss *syslogSender) send(level int, logLine string) {}
+
+// status describes the connection to syslog, for the debug page.
+func (ss *syslogSender) status() string {
+	return "not supported"
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package rlog
+
+import (
+	"log/syslog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForSyslog waits until the connection to syslog was established in the
+// background.
+func waitForSyslog(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		syslogClient.mutex.Lock()
+		connected := syslogClient.writer != nil
+		syslogClient.mutex.Unlock()
+		if connected {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Not connected to syslog")
+}
+
+// TestSyslog checks that entries are sent to syslog with the configured
+// facility and the mapped severities.
+func TestSyslog(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	conf.syslog = "udp:" + pc.LocalAddr().String()
+	conf.syslogFacility = "local3"
+	conf.syslogLevels = "CRITICAL=ALERT, TRACE=INFO"
+	initialize(conf, true)
+	defer syslogClient.connect("", "", "")
+	waitForSyslog(t)
+
+	Info("Test Info")
+	Critical("Test Critical")
+
+	// The priority is facility * 8 + severity: LOCAL3 is 19, INFO is 6 and
+	// ALERT is 1.
+	for _, should := range []string{"<158>", "<153>"} {
+		buf := make([]byte, 1024)
+		pc.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg := string(buf[:n]); !strings.HasPrefix(msg, should) {
+			t.Fatalf("Incorrect syslog message, should start with %s: %s", should, msg)
+		}
+	}
+
+	severities := parseSyslogLevels("TRACE=DEBUG,FOO=ERR,INFO=BAR,WARN")
+	if len(severities) != len(defaultSyslogSeverities) ||
+		severities[levelWarn] != defaultSyslogSeverities[levelWarn] {
+		t.Fatalf("Incorrect syslog severities: %v", severities)
+	}
+}
+
+// TestSyslogDialBlocks checks that a syslog server which can't be reached
+// doesn't stall the initialization, and that it is not dialed again with
+// every initialization while a connection attempt is still in progress.
+func TestSyslogDialBlocks(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	release := make(chan struct{})
+	dials := make(chan string, 10)
+	syslogDial = func(network, raddr string, priority syslog.Priority, tag string) (*syslog.Writer, error) {
+		dials <- raddr
+		<-release
+		return nil, net.UnknownNetworkError(network)
+	}
+	defer func() { syslogDial = syslog.Dial }()
+	defer syslogClient.connect("", "", "")
+
+	conf.syslog = "tcp:192.0.2.1:514"
+	done := make(chan struct{})
+	go func() {
+		initialize(conf, true)
+		initialize(conf, true)
+		Info("Test Info")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Initialization blocked while connecting to syslog")
+	}
+	if status := syslogClient.status(); status != conf.syslog+" (connecting)" {
+		t.Fatalf("Incorrect syslog status: %s", status)
+	}
+	close(release)
+	<-dials
+	if len(dials) != 0 {
+		t.Fatal("Syslog was dialed more than once")
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// How many log lines may wait for a slow tee, before it is dropped.
+	teeQueueSize = 1000
+	// How long restoring a tee waits for the lines still queued for it.
+	teeDrainTimeout = time.Second
+)
+
+// tee is a writer that temporarily receives all log entries. The entries are
+// queued and written by a goroutine of their own, so that a slow writer, such
+// as a stuck network connection, doesn't hold up the log calls.
+type tee struct {
+	mutex  sync.Mutex  // protects queue and closed
+	queue  chan string // the lines still to be written
+	closed bool        // set once the queue was closed
+	done   chan struct{}
+	w      io.Writer
+}
+
+// tees holds the current list of tees as []*tee. It is replaced, rather than
+// modified, so that it can be read without locking.
+var tees atomic.Value
+
+// teesMutex serializes changes of the list of tees.
+var teesMutex sync.Mutex
+
+// TeeTo makes all following log entries also go to the given writer, in the
+// general output format, until the returned function is called. This allows
+// to show the live log, for example in the session of an admin tool:
+//
+//     restore := rlog.TeeTo(conn)
+//     defer restore()
+//
+// If writing to the writer fails, for example because the connection was
+// closed, then nothing more is written to it. The same happens if it can't
+// keep up with the log entries, so that too many of them are waiting for it,
+// or their size exceeds the memory budget set with RLOG_MAX_BUFFER_MB.
+// The configured outputs are not affected by this. The returned function
+// waits a moment for the entries that are still waiting to be written.
+func TeeTo(w io.Writer) (restore func()) {
+	t := &tee{w: w, queue: make(chan string, teeQueueSize), done: make(chan struct{})}
+	go t.run()
+	teesMutex.Lock()
+	list, _ := tees.Load().([]*tee)
+	tees.Store(append(list[:len(list):len(list)], t))
+	teesMutex.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			removeTee(t)
+			t.close()
+			select {
+			case <-t.done:
+			case <-time.After(teeDrainTimeout):
+			}
+		})
+	}
+}
+
+// run writes the queued lines to the writer, until the queue is closed.
+// After a failed write the remaining lines are discarded.
+func (t *tee) run() {
+	defer close(t.done)
+	failed := false
+	for line := range t.queue {
+		if !failed {
+			if _, err := io.WriteString(t.w, line); err != nil {
+				failed = true
+			}
+		}
+		releaseQueued(len(line))
+	}
+}
+
+// close closes the queue, so that run ends once the queued lines are
+// written.
+func (t *tee) close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.closed {
+		t.closed = true
+		close(t.queue)
+	}
+}
+
+// discard empties the closed queue, so that the lines in it no longer count
+// against the memory budget, even if the writer is stuck.
+func (t *tee) discard() {
+	for line := range t.queue {
+		releaseQueued(len(line))
+	}
+}
+
+// removeTee removes a tee from the list.
+func removeTee(t *tee) {
+	teesMutex.Lock()
+	defer teesMutex.Unlock()
+	list, _ := tees.Load().([]*tee)
+	newList := make([]*tee, 0, len(list))
+	for _, other := range list {
+		if other != t {
+			newList = append(newList, other)
+		}
+	}
+	tees.Store(newList)
+}
+
+// writeTees queues a log line for all tees. A tee whose queue is full, or for
+// which the line would exceed the memory budget of the queues, is dropped,
+// since it can't keep up.
+func writeTees(line string) {
+	list, _ := tees.Load().([]*tee)
+	for _, t := range list {
+		t.mutex.Lock()
+		full := false
+		if !t.closed {
+			full = true
+			if reserveQueued(len(line)) {
+				select {
+				case t.queue <- line:
+					full = false
+				default:
+					releaseQueued(len(line))
+				}
+			}
+			if full {
+				t.closed = true
+				close(t.queue)
+			}
+		}
+		t.mutex.Unlock()
+		if full {
+			removeTee(t)
+			t.discard()
+			rlogIssue("Tee can't keep up with the log entries. Dropped.")
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingWriter fails every write and counts the attempts.
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("connection closed")
+}
+
+// TestTeeTo checks that a tee receives the entries until it is restored, and
+// nothing more after a failed write.
+func TestTeeTo(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	Info("Test Info 1")
+	var buf bytes.Buffer
+	restore := TeeTo(&buf)
+	failing := &failingWriter{}
+	restoreFailing := TeeTo(failing)
+	Info("Test Info 2")
+	Debug("Test Debug 1")
+	Warn("Test Warn 1")
+	restore()
+	restore()
+	restoreFailing()
+	Info("Test Info 3")
+
+	if buf.String() != "INFO     : Test Info 2\nWARN     : Test Warn 1\n" {
+		t.Errorf("Wrong tee output: %q", buf.String())
+	}
+	if failing.writes != 1 {
+		t.Errorf("Failing tee written %d times", failing.writes)
+	}
+	checkLines := []string{
+		"INFO     : Test Info 1",
+		"INFO     : Test Info 2",
+		"WARN     : Test Warn 1",
+		"INFO     : Test Info 3",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// TestTeeToSlow checks that a tee which blocks doesn't hold up the log calls,
+// but is dropped once too many entries are waiting for it.
+func TestTeeToSlow(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	initialize(conf, true)
+
+	w := blockingWriter{release: make(chan struct{})}
+	restore := TeeTo(w)
+	defer restore()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < teeQueueSize+10; i++ {
+			Info("Test Info")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Log calls blocked by a slow tee")
+	}
+	if list, _ := tees.Load().([]*tee); len(list) != 0 {
+		t.Fatalf("Slow tee was not dropped: %d tees", len(list))
+	}
+	close(w.release)
+}
+
+// TestTeeToBudget checks that a tee is dropped once the lines waiting for it
+// exceed the memory budget of the queues, long before its queue is full, and
+// that the lines no longer count against the budget afterwards.
+func TestTeeToBudget(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.maxBufferMB = "0.01"
+	initialize(conf, true)
+
+	w := blockingWriter{release: make(chan struct{})}
+	restore := TeeTo(w)
+	defer restore()
+	for i := 0; i < teeQueueSize/4; i++ {
+		Info("Test Info")
+		if list, _ := tees.Load().([]*tee); len(list) == 0 {
+			break
+		}
+	}
+	if list, _ := tees.Load().([]*tee); len(list) != 0 {
+		t.Fatalf("Tee was not dropped when exceeding the budget")
+	}
+	if n := atomic.LoadInt64(&queuedBytes); n > int64(queueBytes()) {
+		t.Errorf("Queued lines exceed the budget: %d bytes", n)
+	}
+	close(w.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt64(&queuedBytes) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt64(&queuedBytes); n != 0 {
+		t.Errorf("Dropped tee still counts against the budget: %d bytes", n)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The placeholders that may be used in output templates.
+var templatePlaceholders = map[string]bool{
+	"time":      true,
+	"level":     true,
+	"msg":       true,
+	"fields":    true,
+	"caller":    true,
+	"func":      true,
+	"pid":       true,
+	"goroutine": true,
+	"thread":    true,
+}
+
+// outputFormat is the format of a single output, if it differs from the
+// general output format.
+type outputFormat struct {
+	kind     int            // one of the output formats, or formatTemplate
+	template []templatePart // for formatTemplate only
+}
+
+// templatePart is either literal text or a placeholder in a template.
+type templatePart struct {
+	text        string
+	placeholder string
+}
+
+// parseOutputFormat interprets the format setting of an output, which is
+// "text", "json", "docker", "glog", "logfmt" or a template like "{time} {level} {msg}". It
+// returns nil if the output should use the general output format.
+func parseOutputFormat(spec string) *outputFormat {
+	switch strings.ToUpper(strings.TrimSpace(spec)) {
+	case "":
+		return nil
+	case "TEXT":
+		return &outputFormat{kind: formatText}
+	case "JSON":
+		return &outputFormat{kind: formatJSON}
+	case "DOCKER":
+		return &outputFormat{kind: formatDocker}
+	case "GLOG":
+		return &outputFormat{kind: formatGlog}
+	case "LOGFMT":
+		return &outputFormat{kind: formatLogfmt}
+	}
+	if !strings.Contains(spec, "{") {
+		rlogIssue("Unknown output format '%s'. Ignored.", spec)
+		return nil
+	}
+	return &outputFormat{kind: formatTemplate, template: parseTemplate(spec)}
+}
+
+// parseTemplate splits a template into literal text and placeholders. Unknown
+// placeholders are kept as literal text.
+func parseTemplate(spec string) []templatePart {
+	var parts []templatePart
+	for spec != "" {
+		start := strings.Index(spec, "{")
+		end := strings.Index(spec, "}")
+		if start < 0 || end < start {
+			parts = append(parts, templatePart{text: spec})
+			break
+		}
+		if start > 0 {
+			parts = append(parts, templatePart{text: spec[:start]})
+		}
+		name := spec[start+1 : end]
+		if templatePlaceholders[name] {
+			parts = append(parts, templatePart{placeholder: name})
+		} else {
+			rlogIssue("Unknown placeholder '{%s}' in output template. Ignored.", name)
+			parts = append(parts, templatePart{text: spec[start : end+1]})
+		}
+		spec = spec[end+1:]
+	}
+	return parts
+}
+
+// format formats a record for an output. Colors are only used for the level
+// in text and template output.
+func (of *outputFormat) format(r *logRecord, withColor bool) string {
+	switch of.kind {
+	case formatJSON:
+		return formatRecordJSON(r)
+	case formatDocker:
+		return formatRecordDocker(r)
+	case formatGlog:
+		return formatRecordGlog(r)
+	case formatLogfmt:
+		return formatRecordLogfmt(r)
+	case formatTemplate:
+		return formatRecordTemplate(r, of.template, withColor)
+	default:
+		return formatRecordText(r, withColor)
+	}
+}
+
+// formatRecordTemplate formats a record according to a template.
+func formatRecordTemplate(r *logRecord, template []templatePart, withColor bool) string {
+	var b strings.Builder
+	for _, p := range template {
+		switch p.placeholder {
+		case "":
+			b.WriteString(p.text)
+		case "time":
+			if r.timeFormat != "" {
+				b.WriteString(recordTime(r).Format(strings.TrimSuffix(r.timeFormat, " ")))
+			}
+		case "level":
+			if withColor {
+				b.WriteString(levelColors[r.level] + r.levelDecoration + colorReset)
+			} else {
+				b.WriteString(r.levelDecoration)
+			}
+		case "msg":
+			b.WriteString(quoteMessage(r.msg))
+		case "fields":
+			b.WriteString(formatFieldsText(r.fields))
+		case "caller":
+			if r.caller != nil {
+				b.WriteString(r.caller.moduleAndFileName)
+				if !settingTestMode {
+					b.WriteString(":" + strconv.Itoa(r.caller.line))
+				}
+			}
+		case "func":
+			if r.caller != nil {
+				b.WriteString(r.caller.funcName)
+			}
+		case "pid":
+			if !settingTestMode {
+				b.WriteString(strconv.Itoa(os.Getpid()))
+			}
+		case "goroutine":
+			if r.goroutineID != 0 {
+				b.WriteString(strconv.FormatUint(r.goroutineID, 10))
+			}
+		case "thread":
+			if r.threadID != 0 {
+				b.WriteString(strconv.Itoa(r.threadID))
+			}
+		}
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
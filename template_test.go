@@ -0,0 +1,45 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestOutputFormats checks that the logfile can have its own format.
+func TestOutputFormats(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	conf.formatFile = "{level} | {msg} | {fields}{foo}"
+	initialize(conf, true)
+	Ev().Str("user", "joe").Msg("Test Info")
+
+	conf.formatFile = "json"
+	initialize(conf, true)
+	Warn("Test Warn")
+
+	conf.formatFile = "unknown"
+	initialize(conf, true)
+	Error("Test Error")
+
+	checkLines := []string{
+		"INFO | Test Info | user=joe{foo}",
+		`{"level":"WARN","msg":"Test Warn"}`,
+		"ERROR    : Test Error",
+	}
+	fileMatch(t, checkLines, "")
+}
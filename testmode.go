@@ -0,0 +1,61 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// testModeTime is the time stamp of all log entries in test mode.
+var testModeTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// settingTestMode is set if output should be deterministic. Protected by
+// initMutex.
+var settingTestMode bool
+
+// SetTestMode switches the deterministic output mode for tests on or off. In
+// test mode, the complete log output of a test run can be compared with a
+// golden file. All time stamps are 2000-01-01T00:00:00Z, unless a different
+// clock is set with SetClock after enabling test mode, and are always shown in
+// UTC, regardless of the local time zone. Process IDs and goroutine IDs are
+// not shown. Caller info only consists of the file name and the function name
+// without the package path. Line numbers are not shown either, so that the
+// output doesn't change whenever the code is edited.
+func SetTestMode(enabled bool) {
+	ensureInitialized()
+	if enabled {
+		SetClock(func() time.Time { return testModeTime })
+	} else {
+		SetClock(nil)
+	}
+	initMutex.Lock()
+	defer initMutex.Unlock()
+	settingTestMode = enabled
+}
+
+// stableCaller returns the caller info as shown in test mode.
+func stableCaller(caller callerData) callerData {
+	funcName := caller.funcName
+	if i := strings.LastIndex(funcName, "/"); i >= 0 {
+		funcName = funcName[i+1:]
+	}
+	return callerData{
+		funcName:          funcName,
+		moduleAndFileName: filepath.Base(caller.moduleAndFileName),
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestTestMode checks that the output in test mode is deterministic.
+func TestTestMode(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.logNoTime = "false"
+	conf.logTimeFormat = "RFC3339"
+	conf.showCallerInfo = "yes"
+	conf.showGoroutineID = "yes"
+	initialize(conf, true)
+	SetTestMode(true)
+	defer SetTestMode(false)
+
+	Info("Test Info")
+	conf.logFormat = "json"
+	initialize(conf, true)
+	Info("Test Info")
+
+	checkLines := []string{
+		"2000-01-01T00:00:00Z INFO     : [testmode_test.go (rlog.TestTestMode)] Test Info",
+		`{"time":"2000-01-01T00:00:00Z","level":"INFO","caller":"testmode_test.go","func":"rlog.TestTestMode","msg":"Test Info"}`,
+	}
+	fileMatch(t, checkLines, "")
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// TestThreadID checks that the OS thread ID is shown in the caller info, if
+// requested.
+func TestThreadID(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.showCallerInfo = "yes"
+	conf.showThreadID = "yes"
+	conf.formatFile = "{thread} {msg}"
+	initialize(conf, true)
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	Info("Test Info")
+
+	expected := "Test Info"
+	if tid := threadID(); tid != 0 {
+		expected = strconv.Itoa(tid) + " " + expected
+	} else {
+		expected = " " + expected
+	}
+	fileMatch(t, []string{expected}, "")
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strings"
+)
+
+// TraceLogger logs trace messages at a fixed trace level and, optionally, with
+// a topic. This avoids repeating the trace level at every call site, so that
+// changing the trace level of a group of messages is a one-line change:
+//
+//     tl := rlog.TraceAt(3).Topic("cache")
+//     tl.Printf("Evicted %d entries", n)
+type TraceLogger struct {
+	l     *Logger
+	level int
+	topic string // prepended to the message, if set
+}
+
+// TraceAt returns a TraceLogger for the given trace level.
+func TraceAt(traceLevel int) TraceLogger {
+	return TraceLogger{level: traceLevel}
+}
+
+// TraceAt returns a TraceLogger for the given trace level, which logs via
+// this Logger.
+func (l *Logger) TraceAt(traceLevel int) TraceLogger {
+	return TraceLogger{l: l, level: traceLevel}
+}
+
+// Topic returns a TraceLogger that starts every message with the topic,
+// followed by a colon.
+func (t TraceLogger) Topic(topic string) TraceLogger {
+	t.topic = topic
+	t.l = t.l.clone()
+	t.l.topic = topic
+	return t
+}
+
+// Print logs a trace message, like Trace.
+func (t TraceLogger) Print(a ...interface{}) {
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	if traceEnabled() {
+		if t.topic != "" {
+			a = append([]interface{}{t.topic + ":"}, a...)
+		}
+		prefixAddition := tracePrefix(t.level)
+		basicLog(t.l, levelTrace, t.level, true, "", prefixAddition, a...)
+	}
+}
+
+// Printf logs a trace message with formatting, like Tracef.
+func (t TraceLogger) Printf(format string, a ...interface{}) {
+	ensureInitialized()
+	initMutex.RLock()
+	defer initMutex.RUnlock()
+	if traceEnabled() {
+		if t.topic != "" {
+			format = strings.Replace(t.topic, "%", "%%", -1) + ": " + format
+		}
+		prefixAddition := tracePrefix(t.level)
+		basicLog(t.l, levelTrace, t.level, true, format, prefixAddition, a...)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestTraceAt checks that a TraceLogger logs at its trace level and with its
+// topic.
+func TestTraceAt(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	conf.traceLevel = "2"
+	initialize(conf, true)
+
+	tl := TraceAt(2)
+	tl.Print("Trace", 2)
+	tl.Topic("50%").Printf("Trace %d", 2)
+	TraceAt(3).Print("Trace 3")
+	WithSampleKey("req-1").TraceAt(1).Topic("db").Print("Trace 1")
+
+	checkLines := []string{
+		"TRACE(2) : Trace 2",
+		"TRACE(2) : 50%: Trace 2",
+		"TRACE(1) : db: Trace 1",
+	}
+	fileMatch(t, checkLines, "")
+}
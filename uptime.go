@@ -0,0 +1,45 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"time"
+)
+
+// processStart is the time the program was started, or rather the time this
+// package was initialized, which is close enough.
+var processStart = time.Now()
+
+// settingUptimeFields determines whether CRITICAL entries get fields with the
+// start time and uptime of the process.
+var settingUptimeFields bool
+
+// uptimeFields returns the fields 'started', with the start time of the
+// process, and 'uptime', with the time it has been running at the given time.
+// In test mode, the process started at the time of all entries.
+func uptimeFields(now time.Time) []field {
+	started := processStart
+	if settingTestMode {
+		started = now
+	}
+	if settingTimeUTC || settingTestMode {
+		started = started.UTC()
+	}
+	return []field{
+		{key: "started", kind: fieldString, str: started.Format(time.RFC3339)},
+		{key: "uptime", kind: fieldDuration, num: int64(now.Sub(started).Round(time.Second))},
+	}
+}
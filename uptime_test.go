@@ -0,0 +1,46 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+	"time"
+)
+
+// TestUptimeFields checks that CRITICAL entries show the start time and
+// uptime of the process.
+func TestUptimeFields(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer SetClock(nil)
+	conf.uptimeFields = "yes"
+	conf.timeUTC = "yes"
+	initialize(conf, true)
+	SetClock(func() time.Time { return processStart.Add(90*time.Minute + 10*time.Millisecond) })
+
+	Error("Test Error")
+	Critical("Test Critical")
+	SetTestMode(true)
+	defer SetTestMode(false)
+	Critical("Test Critical")
+
+	checkLines := []string{
+		"ERROR    : Test Error",
+		"CRITICAL : Test Critical started=" + processStart.UTC().Format(time.RFC3339) + " uptime=1h30m0s",
+		"CRITICAL : Test Critical started=2000-01-01T00:00:00Z uptime=0s",
+	}
+	fileMatch(t, checkLines, "")
+}
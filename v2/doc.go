@@ -0,0 +1,31 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package rlog is the version 2 API of rlog. It offers the functionality of
+// the github.com/romana/rlog package through a small, stable set of types:
+// Loggers that are created with options, formatters, sinks and settings that
+// are applied with Configure. The log output, the configuration via
+// environment variables and the config file are the same as in the original
+// package, on which this one is built. Both can be used in the same program.
+//
+//     err := rlog.Configure(rlog.FileOutput("/var/log/myapp.log"),
+//         rlog.AddSink(rlog.WriterSink(conn), rlog.SinkLevel(rlog.LevelWarn)))
+//     log := rlog.New(rlog.WithSampleKey(requestID), rlog.WithCaller())
+//     log.Infof("Serving %s", path)
+//
+// The output formats are selected with RLOG_LOG_FORMAT and
+// RLOG_FORMAT_STREAM, unless a Formatter is set with StreamFormatter or
+// FileFormatter.
+package rlog
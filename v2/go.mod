@@ -0,0 +1,10 @@
+module github.com/romana/rlog/v2
+
+go 1.16
+
+require github.com/romana/rlog v1.0.0
+
+// v2 is built on the version of the original package in this repository.
+// That version is tagged before v2, so that the requirement above can be
+// resolved without the replace directive.
+replace github.com/romana/rlog => ../
@@ -0,0 +1,117 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io"
+	"time"
+
+	v1 "github.com/romana/rlog"
+)
+
+// Level is the severity of a log message.
+type Level = v1.Level
+
+// The log levels, from the most to the least severe.
+const (
+	LevelCritical = v1.LevelCritical
+	LevelError    = v1.LevelError
+	LevelWarn     = v1.LevelWarn
+	LevelInfo     = v1.LevelInfo
+	LevelDebug    = v1.LevelDebug
+	LevelTrace    = v1.LevelTrace
+)
+
+// Entry describes a log message, as it is passed to hooks and to Emit.
+type Entry = v1.Entry
+
+// Logger logs messages with the settings it was created with. It offers the
+// same log functions as the package level functions of the original package.
+// A nil Logger is valid and logs with the default settings.
+type Logger = v1.Logger
+
+// Formatter turns a log entry into the line that is written to an output.
+type Formatter = v1.Formatter
+
+// FormatterFunc adapts an ordinary function to the Formatter interface.
+type FormatterFunc = v1.FormatterFunc
+
+// Sink is a destination for log entries in addition to the configured
+// outputs, such as a network connection or a message queue. It receives every
+// entry, together with its formatted line. If WriteEntry returns an error,
+// then the sink is removed.
+type Sink = v1.Sink
+
+// SinkOption is a property of a sink that is added with AddSink.
+type SinkOption = v1.SinkOption
+
+// WriterSink returns a Sink that writes the lines of the entries to w.
+func WriterSink(w io.Writer) Sink {
+	return v1.WriterSink(w)
+}
+
+// SinkLevel makes a sink only receive entries with the given level or a more
+// severe one. Trace messages have LevelTrace.
+func SinkLevel(level Level) SinkOption {
+	return v1.SinkLevel(level)
+}
+
+// SinkFormatter makes a sink receive lines that are formatted with the
+// Formatter, instead of in the general output format.
+func SinkFormatter(f Formatter) SinkOption {
+	return v1.SinkFormatter(f)
+}
+
+// Option is a setting of a Logger.
+type Option func(l *Logger) *Logger
+
+// New returns a Logger with the given options.
+func New(opts ...Option) *Logger {
+	return With(nil, opts...)
+}
+
+// With returns a Logger with the given options in addition to the settings of
+// the Logger l, which itself is not changed.
+func With(l *Logger, opts ...Option) *Logger {
+	for _, opt := range opts {
+		l = opt(l)
+	}
+	return l
+}
+
+// WithSampleKey makes the Logger's messages sampled based on the key, for
+// example a request or trace ID, so that either all or none of them are kept.
+func WithSampleKey(key string) Option {
+	return func(l *Logger) *Logger { return l.WithSampleKey(key) }
+}
+
+// WithTimeFormat makes the Logger's messages show time stamps in the given
+// format, which is one of the names allowed in RLOG_TIME_FORMAT or a time
+// layout. An empty format means that there is no time stamp.
+func WithTimeFormat(format string) Option {
+	return func(l *Logger) *Logger { return l.WithTimeFormat(format) }
+}
+
+// WithCaller makes the Logger's messages always contain caller info.
+func WithCaller() Option {
+	return func(l *Logger) *Logger { return l.WithCaller() }
+}
+
+// WithWriteTimeout makes the Logger's messages be written to each output
+// within the given time, regardless of RLOG_WRITE_TIMEOUT.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(l *Logger) *Logger { return l.WithWriteTimeout(d) }
+}
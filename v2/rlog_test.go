@@ -0,0 +1,106 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNewWithOptions checks that New returns a Logger with the given options
+// and that Configure applies settings for the whole program.
+func TestNewWithOptions(t *testing.T) {
+	var buf bytes.Buffer
+	clock := func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	err := Configure(TestMode(true), Clock(clock), StreamOutput(&buf))
+	if err != nil {
+		t.Fatalf("Configure failed: %s", err)
+	}
+	defer Configure(StreamOutput(nil), Clock(nil), TestMode(false))
+
+	log := New(WithTimeFormat("2006-01-02"), WithCaller())
+	log.Infof("Test %d", 1)
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "2020-01-02 ") {
+		t.Errorf("Expected time stamp in custom format, got: %q", out)
+	}
+	if !strings.Contains(out, "INFO") || !strings.Contains(out, "Test 1") {
+		t.Errorf("Expected INFO message, got: %q", out)
+	}
+	if !strings.Contains(out, "rlog_test.go") {
+		t.Errorf("Expected caller info, got: %q", out)
+	}
+}
+
+// TestWithKeepsOriginal checks that With returns a new Logger with the
+// additional options, while New without options returns the default Logger.
+func TestWithKeepsOriginal(t *testing.T) {
+	base := New(WithCaller())
+	derived := With(base, WithSampleKey("abc"))
+	if derived == base {
+		t.Errorf("Expected With to return a new Logger")
+	}
+	if New() != nil {
+		t.Errorf("Expected New without options to return the default Logger")
+	}
+}
+
+// TestConfigureStopsAtError checks that Configure returns the error of the
+// first failing setting and doesn't apply the remaining ones.
+func TestConfigureStopsAtError(t *testing.T) {
+	applied := false
+	err := Configure(FileOutput("/nonexistent-dir/rlog.log"), func() error {
+		applied = true
+		return nil
+	})
+	if err == nil {
+		t.Errorf("Expected error for a logfile that can't be created")
+	}
+	if applied {
+		t.Errorf("Expected settings after the error not to be applied")
+	}
+	Configure(FileOutput(""))
+}
+
+// TestFormatterAndSink checks that the formatter and sink settings are passed
+// on to the original package.
+func TestFormatterAndSink(t *testing.T) {
+	var stream, sink bytes.Buffer
+	levelFormatter := FormatterFunc(func(e Entry) []byte {
+		return []byte(e.Level.String() + " " + e.Message)
+	})
+	s := WriterSink(&sink)
+	err := Configure(TestMode(true), StreamOutput(&stream), StreamFormatter(levelFormatter),
+		AddSink(s, SinkLevel(LevelWarn), SinkFormatter(levelFormatter)))
+	if err != nil {
+		t.Fatalf("Configure failed: %s", err)
+	}
+	defer Configure(StreamOutput(nil), StreamFormatter(nil), TestMode(false))
+
+	New().Info("Test 1")
+	New().Warn("Test 2")
+	Configure(RemoveSink(s))
+
+	if stream.String() != "INFO Test 1\nWARN Test 2\n" {
+		t.Errorf("Incorrect log stream: %q", stream.String())
+	}
+	if sink.String() != "WARN Test 2\n" {
+		t.Errorf("Incorrect sink output: %q", sink.String())
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"io"
+	"time"
+
+	v1 "github.com/romana/rlog"
+)
+
+// Setting changes the configuration of rlog for the whole program, in
+// addition to what is set in environment variables and the config file.
+type Setting func() error
+
+// Configure applies the settings in the given order. If one of them fails,
+// the error is returned and the remaining settings are not applied.
+func Configure(settings ...Setting) error {
+	for _, s := range settings {
+		if err := s(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamOutput sends the log stream to the writer, instead of stderr or
+// stdout, while the logfile keeps working. Nil goes back to the configured
+// log stream.
+func StreamOutput(w io.Writer) Setting {
+	return func() error {
+		v1.SetStreamOutput(w)
+		return nil
+	}
+}
+
+// FileOutput writes the log to the logfile with the given name, instead of
+// the one set with RLOG_LOG_FILE. An empty name goes back to the configured
+// logfile.
+func FileOutput(fileName string) Setting {
+	return func() error {
+		return v1.SetFileOutput(fileName)
+	}
+}
+
+// ConfFile reads the configuration from the config file with the given name,
+// instead of the one set with RLOG_CONF_FILE. Errors in the file are
+// returned.
+func ConfFile(fileName string) Setting {
+	return func() error {
+		return v1.SetConfFileE(fileName)
+	}
+}
+
+// Clock replaces the function that provides the time stamps of log entries.
+// Nil restores time.Now.
+func Clock(now func() time.Time) Setting {
+	return func() error {
+		v1.SetClock(now)
+		return nil
+	}
+}
+
+// OutputClock replaces the clock for one output, for example to use a trusted
+// time source for an audit log. The names of the outputs are "stream", "file",
+// "collector", "syslog" and "tee", as in RLOG_WRITE_TIMEOUT. The other outputs
+// keep using the clock set with Clock. Nil goes back to the default clock. An
+// error is returned for unknown output names.
+func OutputClock(output string, now func() time.Time) Setting {
+	return func() error {
+		return v1.SetOutputClock(output, now)
+	}
+}
+
+// Sampling logs only one in every n messages at the given level or of lower
+// severity.
+func Sampling(level Level, n int) Setting {
+	return func() error {
+		v1.SetSampling(level, n)
+		return nil
+	}
+}
+
+// Hook calls the function for every message with the given level or a more
+// severe one.
+func Hook(level Level, fn func(Entry)) Setting {
+	return func() error {
+		v1.AddHook(level, fn)
+		return nil
+	}
+}
+
+// StreamFormatter formats the entries that are written to the log stream with
+// the Formatter, instead of in the format set with RLOG_LOG_FORMAT or
+// RLOG_FORMAT_STREAM. Nil goes back to the configured format.
+func StreamFormatter(f Formatter) Setting {
+	return func() error {
+		v1.SetStreamFormatter(f)
+		return nil
+	}
+}
+
+// FileFormatter formats the entries that are written to the logfile with the
+// Formatter, instead of in the format set with RLOG_LOG_FORMAT or
+// RLOG_FORMAT_FILE. Nil goes back to the configured format.
+func FileFormatter(f Formatter) Setting {
+	return func() error {
+		v1.SetFileFormatter(f)
+		return nil
+	}
+}
+
+// AddSink sends all following log entries to the sink as well, until it is
+// removed with RemoveSink. By default the sink receives every entry in the
+// general output format, which can be changed with the options SinkLevel and
+// SinkFormatter:
+//
+//     err := rlog.Configure(rlog.AddSink(rlog.WriterSink(conn),
+//         rlog.SinkLevel(rlog.LevelWarn), rlog.SinkFormatter(jsonFormatter)))
+//
+// The entries are queued and written by a goroutine of their own. If the sink
+// can't keep up, or if writing to it fails, then nothing more is written to
+// it. Adding a sink that was added already has no effect.
+func AddSink(s Sink, opts ...SinkOption) Setting {
+	return func() error {
+		v1.AddSink(s, opts...)
+		return nil
+	}
+}
+
+// RemoveSink stops sending log entries to a sink that was added with AddSink.
+// It waits a moment for the entries that are still waiting to be written.
+func RemoveSink(s Sink) Setting {
+	return func() error {
+		v1.RemoveSink(s)
+		return nil
+	}
+}
+
+// InternalOutput sends the notices about problems of rlog itself to the
+// writer. Nil goes back to the output set with RLOG_INTERNAL_OUTPUT.
+func InternalOutput(w io.Writer) Setting {
+	return func() error {
+		v1.SetInternalOutput(w)
+		return nil
+	}
+}
+
+// TestMode switches the test mode on or off, in which the output doesn't
+// change from one run to the next.
+func TestMode(enabled bool) Setting {
+	return func() error {
+		v1.SetTestMode(enabled)
+		return nil
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// namedWriters holds the writers registered with RegisterWriter, by their
+// name in upper case. Protected by initMutex.
+var namedWriters = map[string]io.Writer{}
+
+// RegisterWriter registers a writer under a name, which can then be used as
+// value of RLOG_LOG_STREAM, in the environment or in the config file. This
+// allows switching the log output to destinations provided by the program
+// without a restart. Names are not case sensitive and "stdout", "stderr" and
+// "none" are reserved. Registering nil removes a writer.
+func RegisterWriter(name string, writer io.Writer) error {
+	key := strings.ToUpper(name)
+	switch key {
+	case "", "STDOUT", "STDERR", "NONE":
+		return fmt.Errorf("rlog: illegal writer name '%s'", name)
+	}
+	ensureInitialized()
+	initMutex.Lock()
+	if writer == nil {
+		delete(namedWriters, key)
+	} else {
+		namedWriters[key] = writer
+	}
+	initMutex.Unlock()
+	initialize(configFromEnvVars, false)
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+)
+
+// TestRegisterWriter checks that registered writers can be selected as log
+// stream.
+func TestRegisterWriter(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+	defer RegisterWriter("ringbuffer", nil)
+	conf.logStream = "RINGBUFFER"
+	initialize(conf, true)
+
+	if err := RegisterWriter("stdout", &lockedBuffer{}); err == nil {
+		t.Fatal("Should not be able to register reserved name")
+	}
+	buf := &lockedBuffer{}
+	if err := RegisterWriter("ringBuffer", buf); err != nil {
+		t.Fatal(err)
+	}
+	Info("Test Info")
+
+	if buf.String() != "INFO     : Test Info\n" {
+		t.Fatalf("Incorrect output of registered writer: %s", buf)
+	}
+}
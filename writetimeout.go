@@ -0,0 +1,175 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// The outputs that can have a write timeout.
+const (
+	sinkStream = iota
+	sinkFile
+	sinkCollector
+	sinkSyslog
+	sinkTee
+	numSinks
+)
+
+// sinkNames are the names of the outputs, as used in RLOG_WRITE_TIMEOUT.
+var sinkNames = [numSinks]string{"stream", "file", "collector", "syslog", "tee"}
+
+var (
+	// settingWriteTimeouts is the write timeout for each output, 0 if writes
+	// may take as long as they take.
+	settingWriteTimeouts [numSinks]time.Duration
+	// settingHasWriteTimeouts is set if any output has a write timeout.
+	settingHasWriteTimeouts bool
+)
+
+// sinkState tracks the writes to an output that have a timeout.
+type sinkState struct {
+	mutex   sync.Mutex
+	stuck   int    // number of abandoned writes that are still in progress
+	dropped uint64 // number of entries that were not written in time
+}
+
+// sinkStates holds the state of every output.
+var sinkStates [numSinks]sinkState
+
+// parseWriteTimeouts interprets the value of RLOG_WRITE_TIMEOUT, which is a
+// comma separated list of durations. A duration without the name of an
+// output, such as "200ms", applies to all outputs. With a name, such as
+// "file=2s", it only applies to that output.
+func parseWriteTimeouts(spec string) (timeouts [numSinks]time.Duration) {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value := "", item
+		if i := strings.Index(item, "="); i >= 0 {
+			name, value = strings.ToLower(strings.TrimSpace(item[:i])), strings.TrimSpace(item[i+1:])
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			rlogIssue("Cannot parse write timeout '%s'. Ignored.", item)
+			continue
+		}
+		if name == "" {
+			for sink := range timeouts {
+				timeouts[sink] = d
+			}
+			continue
+		}
+		found := false
+		for sink, sinkName := range sinkNames {
+			if name == sinkName {
+				timeouts[sink] = d
+				found = true
+			}
+		}
+		if !found {
+			rlogIssue("Unknown output '%s' in write timeout. Ignored.", name)
+		}
+	}
+	return timeouts
+}
+
+// updateWriteTimeouts applies the value of RLOG_WRITE_TIMEOUT. The caller needs
+// to hold the write lock on initMutex.
+func updateWriteTimeouts(spec string) {
+	settingWriteTimeouts = parseWriteTimeouts(spec)
+	settingHasWriteTimeouts = false
+	for _, d := range settingWriteTimeouts {
+		if d > 0 {
+			settingHasWriteTimeouts = true
+		}
+	}
+}
+
+// writeTimeout returns the timeout for a write to an output. The timeout of
+// the Logger, if it has one, takes precedence over the configured one. The
+// caller needs to hold at least the read lock on initMutex.
+func writeTimeout(sink int, loggerTimeout time.Duration) time.Duration {
+	if loggerTimeout > 0 {
+		return loggerTimeout
+	}
+	return settingWriteTimeouts[sink]
+}
+
+// writeWithin calls the write function for an output, but waits for it at
+// most for the given time. If it takes longer then the entry is counted as
+// dropped and the write is abandoned: It may still complete later, but the
+// caller doesn't wait for it. While an abandoned write is in progress, new
+// entries for the output are dropped right away, so that the stuck output
+// doesn't pile up goroutines.
+func writeWithin(sink int, timeout time.Duration, write func()) {
+	s := &sinkStates[sink]
+	s.mutex.Lock()
+	if s.stuck > 0 {
+		s.dropped++
+		s.mutex.Unlock()
+		return
+	}
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	abandoned := false // protected by s.mutex
+	go func() {
+		write()
+		s.mutex.Lock()
+		if abandoned {
+			s.stuck--
+		}
+		close(done)
+		s.mutex.Unlock()
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		s.mutex.Lock()
+		select {
+		case <-done:
+			// Completed just now, after all
+		default:
+			abandoned = true
+			s.stuck++
+			s.dropped++
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// DroppedCounts returns how many log entries were not written to an output,
+// because a write to it didn't complete within the write timeout, by the name
+// of the output, for example:
+//
+//	{"stream": 0, "file": 12, "collector": 0, "syslog": 0, "tee": 0}
+func DroppedCounts() map[string]uint64 {
+	counts := make(map[string]uint64, numSinks)
+	for sink := range sinkStates {
+		s := &sinkStates[sink]
+		s.mutex.Lock()
+		counts[sinkNames[sink]] = s.dropped
+		s.mutex.Unlock()
+	}
+	return counts
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2016 Pani Networks
+// All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package rlog
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseWriteTimeouts checks the interpretation of RLOG_WRITE_TIMEOUT.
+func TestParseWriteTimeouts(t *testing.T) {
+	timeouts := parseWriteTimeouts("200ms, file=2s,bogus=1s,tee=x")
+	expected := [numSinks]time.Duration{
+		sinkStream:    200 * time.Millisecond,
+		sinkFile:      2 * time.Second,
+		sinkCollector: 200 * time.Millisecond,
+		sinkSyslog:    200 * time.Millisecond,
+		sinkTee:       200 * time.Millisecond,
+	}
+	if timeouts != expected {
+		t.Errorf("Incorrect write timeouts: %v", timeouts)
+	}
+	if timeouts := parseWriteTimeouts(""); timeouts != [numSinks]time.Duration{} {
+		t.Errorf("Incorrect write timeouts: %v", timeouts)
+	}
+}
+
+// TestWriteTimeout checks that writes to a blocking output are abandoned,
+// while the other outputs keep working.
+func TestWriteTimeout(t *testing.T) {
+	conf := setup()
+	defer cleanup()
+
+	w := blockingWriter{release: make(chan struct{})}
+	streamOutput = w
+	defer func() { streamOutput = nil }()
+	conf.writeTimeout = "stream=50ms"
+	initialize(conf, true)
+
+	before := DroppedCounts()
+	start := time.Now()
+	Info("Test Info 1")
+	if d := time.Since(start); d < 50*time.Millisecond || d > time.Second {
+		t.Errorf("Write was abandoned after %s", d)
+	}
+	// The stream is stuck, so this is dropped right away
+	start = time.Now()
+	WithWriteTimeout(time.Second).Info("Test Info 2")
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("Write to stuck output took %s", d)
+	}
+	after := DroppedCounts()
+	if n := after["stream"] - before["stream"]; n != 2 {
+		t.Errorf("Incorrect number of dropped entries: %d", n)
+	}
+	if after["file"] != before["file"] {
+		t.Error("Entries for the logfile should not be dropped")
+	}
+
+	close(w.release)
+	for i := 0; i < 100 && sinkStates[sinkStream].isStuck(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	streamOutput = nil
+	initialize(conf, true)
+	Info("Test Info 3")
+
+	checkLines := []string{
+		"INFO     : Test Info 1",
+		"INFO     : Test Info 2",
+		"INFO     : Test Info 3",
+	}
+	fileMatch(t, checkLines, "")
+}
+
+// isStuck returns whether an abandoned write to the output is still in
+// progress.
+func (s *sinkState) isStuck() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.stuck > 0
+}